@@ -0,0 +1,114 @@
+// Package ast declares the types that represent the syntax tree of a Liquid template.
+//
+// Template.AST returns the syntax tree of a parsed template. Walk, Inspect, and
+// Rewrite traverse it. Engine.CompileAST compiles a tree, for example one that
+// has been rewritten, into a new template. A node's String method returns
+// its Liquid source.
+//
+// Unlike the other subpackages, this package is part of the stable API.
+package ast
+
+// A Node is a node of a template or expression syntax tree.
+//
+// The template nodes are *Seq, *Text, *Object, *Tag, *Block, *Raw, *Trim,
+// and *Invalid. The nodes of tag arguments implement Stmt, and the
+// expression nodes implement Expr.
+type Node interface {
+	String() string
+	node()
+}
+
+// SourceLoc is a node's source location.
+type SourceLoc struct {
+	Pathname string
+	LineNo   int
+}
+
+// Seq is a sequence of nodes. It is the root of a template.
+type Seq struct {
+	Children []Node
+}
+
+// Text is a span of text outside of objects and tags. It renders verbatim.
+type Text struct {
+	Loc    SourceLoc
+	Source string
+}
+
+// Object is an object such as {{ product.title | upcase }}.
+type Object struct {
+	Loc  SourceLoc
+	Expr Expr
+}
+
+// Tag is a tag such as {% assign x = 1 %}, that is not part of a block.
+//
+// Args is the source of the tag arguments. Stmt is their parse, if the tag
+// is one of the standard tags whose arguments are an expression or a
+// statement, such as assign, cycle, or echo, and nil otherwise. Walk and
+// Rewrite reach the expressions in Stmt. Where Stmt is set, the String
+// method and Engine.CompileAST use it instead of Args, so that a change to
+// it takes effect.
+type Tag struct {
+	Loc  SourceLoc
+	Name string
+	Args string
+	Stmt Stmt
+}
+
+// Block is a block such as {% if x %}…{% else %}…{% endif %}.
+//
+// Body holds the nodes before the first clause. Each clause, such as the
+// {% else %} above, is a Block whose Body holds the nodes that follow it.
+// A block's end tag is implied by its name.
+//
+// Args and Stmt are the arguments of the block's tag, as for a Tag. Stmt is
+// set for the if, unless, case, for, and tablerow blocks, and for the elsif
+// and when clauses.
+type Block struct {
+	Loc     SourceLoc
+	Name    string
+	Args    string
+	Stmt    Stmt
+	Body    []Node
+	Clauses []*Block
+}
+
+// Raw is the content of a {% raw %}…{% endraw %} block.
+type Raw struct {
+	Source string
+}
+
+// TrimDirection is the direction of a Trim.
+type TrimDirection int
+
+const (
+	// TrimLeft is the "-" at the start of "{{-" or "{%-". It trims the whitespace before the delimiter.
+	TrimLeft TrimDirection = iota
+	// TrimRight is the "-" at the end of "-}}" or "-%}". It trims the whitespace after the delimiter.
+	TrimRight
+)
+
+// Trim is a whitespace control marker. It immediately precedes (TrimLeft) or
+// follows (TrimRight) the object or tag whose delimiter carries the marker.
+// The markers of a block's end tag are at the end of its last body, and after
+// the block.
+type Trim struct {
+	Direction TrimDirection
+}
+
+// Invalid is an object that failed to parse. It is only present in templates
+// that were parsed in the lax and warn error modes.
+type Invalid struct {
+	Loc    SourceLoc
+	Source string
+}
+
+func (*Seq) node()     {}
+func (*Text) node()    {}
+func (*Object) node()  {}
+func (*Tag) node()     {}
+func (*Block) node()   {}
+func (*Raw) node()     {}
+func (*Trim) node()    {}
+func (*Invalid) node() {}
@@ -0,0 +1,137 @@
+package ast
+
+// An Expr is a node of an expression syntax tree.
+//
+// The expression nodes are *Literal, *Variable, *Property, *Index, *Range,
+// *Filter, *Call, and *BinaryOp.
+type Expr interface {
+	Node
+	expr()
+}
+
+// Literal is a literal value: a number, string, boolean, or nil.
+type Literal struct {
+	Value any
+}
+
+// Variable is a reference to a variable, e.g. "product".
+type Variable struct {
+	Name string
+}
+
+// Property is a property access, e.g. "product.title".
+type Property struct {
+	Object Expr
+	Name   string
+}
+
+// Index is an index expression, e.g. "products[0]" or "product['title']".
+type Index struct {
+	Object Expr
+	Index  Expr
+}
+
+// Range is a range expression, e.g. "(1..5)".
+type Range struct {
+	Start, End Expr
+}
+
+// Filter is a filter application, e.g. "title | truncate: 10".
+type Filter struct {
+	Input       Expr
+	Name        string
+	Args        []Expr
+	KeywordArgs []KeywordArg
+}
+
+// Call is a call to a named function, such as a macro, e.g.
+// "price(product.price, currency: 'EUR')".
+type Call struct {
+	Name        string
+	Args        []Expr
+	KeywordArgs []KeywordArg
+}
+
+// KeywordArg is a named filter or call argument, e.g. "allow_false: true".
+type KeywordArg struct {
+	Name  string
+	Value Expr
+}
+
+// BinaryOp is a comparison or logical operation. Op is one of
+// "==", "!=", "<", ">", "<=", ">=", "contains", "and", or "or".
+type BinaryOp struct {
+	Op          string
+	Left, Right Expr
+}
+
+func (*Literal) node()  {}
+func (*Variable) node() {}
+func (*Property) node() {}
+func (*Index) node()    {}
+func (*Range) node()    {}
+func (*Filter) node()   {}
+func (*Call) node()     {}
+func (*BinaryOp) node() {}
+
+func (*Literal) expr()  {}
+func (*Variable) expr() {}
+func (*Property) expr() {}
+func (*Index) expr()    {}
+func (*Range) expr()    {}
+func (*Filter) expr()   {}
+func (*Call) expr()     {}
+func (*BinaryOp) expr() {}
+
+// CopyExpr returns a deep copy of an expression syntax tree, so that the copy
+// can be modified without affecting the original. It returns nil for nil.
+func CopyExpr(n Expr) Expr {
+	switch n := n.(type) {
+	case *Literal:
+		c := *n
+		return &c
+	case *Variable:
+		c := *n
+		return &c
+	case *Property:
+		return &Property{Object: CopyExpr(n.Object), Name: n.Name}
+	case *Index:
+		return &Index{Object: CopyExpr(n.Object), Index: CopyExpr(n.Index)}
+	case *Range:
+		return &Range{Start: CopyExpr(n.Start), End: CopyExpr(n.End)}
+	case *Filter:
+		return &Filter{Input: CopyExpr(n.Input), Name: n.Name, Args: copyExprs(n.Args), KeywordArgs: copyKeywordArgs(n.KeywordArgs)}
+	case *Call:
+		return &Call{Name: n.Name, Args: copyExprs(n.Args), KeywordArgs: copyKeywordArgs(n.KeywordArgs)}
+	case *BinaryOp:
+		return &BinaryOp{Op: n.Op, Left: CopyExpr(n.Left), Right: CopyExpr(n.Right)}
+	default:
+		return n
+	}
+}
+
+func copyExprs(exprs []Expr) []Expr {
+	if exprs == nil {
+		return nil
+	}
+
+	out := make([]Expr, len(exprs))
+	for i, e := range exprs {
+		out[i] = CopyExpr(e)
+	}
+
+	return out
+}
+
+func copyKeywordArgs(kws []KeywordArg) []KeywordArg {
+	if kws == nil {
+		return nil
+	}
+
+	out := make([]KeywordArg, len(kws))
+	for i, kw := range kws {
+		out[i] = KeywordArg{Name: kw.Name, Value: CopyExpr(kw.Value)}
+	}
+
+	return out
+}
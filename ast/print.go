@@ -0,0 +1,253 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The String methods of the template nodes print Liquid source with the
+// default delimiters, one space inside each delimiter, and the tag arguments
+// as written, or printed from their Stmt if it is set. Comments are not part
+// of the tree, and are not printed.
+
+func (n *Seq) String() string     { return format(n) }
+func (n *Text) String() string    { return format(n) }
+func (n *Object) String() string  { return format(n) }
+func (n *Tag) String() string     { return format(n) }
+func (n *Block) String() string   { return format(n) }
+func (n *Raw) String() string     { return format(n) }
+func (n *Trim) String() string    { return format(n) }
+func (n *Invalid) String() string { return format(n) }
+
+// TagArgs returns the source of the tag arguments: the source of Stmt if it
+// is set, and otherwise Args.
+func (n *Tag) TagArgs() string { return tagArgs(n.Args, n.Stmt) }
+
+// TagArgs returns the source of the arguments of the block's tag, as for a
+// Tag.
+func (n *Block) TagArgs() string { return tagArgs(n.Args, n.Stmt) }
+
+func tagArgs(args string, stmt Stmt) string {
+	if stmt != nil {
+		return stmt.String()
+	}
+
+	return args
+}
+
+func format(n Node) string {
+	var p printer
+	p.node(n)
+	p.flush()
+
+	return p.b.String()
+}
+
+// printer prints template nodes. A Trim node modifies the delimiter of an
+// adjacent node, so the printer defers each closing delimiter until it has
+// seen the next node.
+type printer struct {
+	b        strings.Builder
+	close    string // the pending closing delimiter
+	trimLeft bool   // whether the next opening delimiter is trimmed
+}
+
+func (p *printer) flush() {
+	if p.close != "" {
+		p.b.WriteString(" " + p.close)
+		p.close = ""
+	}
+}
+
+func (p *printer) open(delim, close string) {
+	p.flush()
+	p.b.WriteString(delim)
+
+	if p.trimLeft {
+		p.b.WriteByte('-')
+		p.trimLeft = false
+	}
+
+	p.close = close
+}
+
+func (p *printer) tag(name, args string) {
+	p.open("{%", "%}")
+	p.b.WriteString(" " + name)
+
+	if args != "" {
+		p.b.WriteString(" " + args)
+	}
+}
+
+func (p *printer) nodes(nodes []Node) {
+	for _, n := range nodes {
+		p.node(n)
+	}
+}
+
+func (p *printer) node(n Node) {
+	switch n := n.(type) {
+	case *Seq:
+		p.nodes(n.Children)
+	case *Text:
+		p.flush()
+		p.b.WriteString(n.Source)
+	case *Object:
+		p.open("{{", "}}")
+
+		if n.Expr != nil {
+			p.b.WriteString(" " + n.Expr.String())
+		}
+	case *Tag:
+		p.tag(n.Name, n.TagArgs())
+	case *Block:
+		p.tag(n.Name, n.TagArgs())
+		p.nodes(n.Body)
+
+		for _, clause := range n.Clauses {
+			p.tag(clause.Name, clause.TagArgs())
+			p.nodes(clause.Body)
+		}
+
+		p.tag("end"+n.Name, "")
+	case *Raw:
+		p.tag("raw", "")
+		p.flush()
+		p.b.WriteString(n.Source)
+		p.tag("endraw", "")
+	case *Trim:
+		switch {
+		case n.Direction == TrimLeft:
+			p.flush()
+			p.trimLeft = true
+		case p.close != "":
+			p.b.WriteString(" -" + p.close)
+			p.close = ""
+		}
+	case *Invalid:
+		p.flush()
+		p.b.WriteString(n.Source)
+	case Stmt, Expr:
+		p.flush()
+		p.b.WriteString(n.String())
+	}
+}
+
+// Expression precedence levels, from loosest to tightest.
+const (
+	condLevel     = iota // and, or
+	relLevel             // comparisons
+	filteredLevel        // filter applications
+	primaryLevel         // literals, variables, properties, indices, and ranges
+)
+
+func level(e Expr) int {
+	switch e := e.(type) {
+	case *BinaryOp:
+		if e.Op == "and" || e.Op == "or" {
+			return condLevel
+		}
+
+		return relLevel
+	case *Filter:
+		return filteredLevel
+	default:
+		return primaryLevel
+	}
+}
+
+// operand prints e, parenthesized if it binds more loosely than minLevel.
+func operand(e Expr, minLevel int) string {
+	if level(e) < minLevel {
+		return "(" + e.String() + ")"
+	}
+
+	return e.String()
+}
+
+func (n *Literal) String() string {
+	switch v := n.Value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return quote(v)
+	case float64:
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+
+		return s
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func quote(s string) string {
+	return `"` + stringEscaper.Replace(s) + `"`
+}
+
+func (n *Variable) String() string { return n.Name }
+
+func (n *Property) String() string {
+	return operand(n.Object, primaryLevel) + "." + n.Name
+}
+
+func (n *Index) String() string {
+	return operand(n.Object, primaryLevel) + "[" + operand(n.Index, primaryLevel) + "]"
+}
+
+func (n *Range) String() string {
+	return "(" + operand(n.Start, primaryLevel) + ".." + operand(n.End, primaryLevel) + ")"
+}
+
+func (n *Filter) String() string {
+	var b strings.Builder
+	b.WriteString(operand(n.Input, filteredLevel))
+	b.WriteString(" | " + n.Name)
+
+	sep := ": "
+	for _, arg := range n.Args {
+		b.WriteString(sep + operand(arg, primaryLevel))
+		sep = ", "
+	}
+
+	for _, kw := range n.KeywordArgs {
+		b.WriteString(sep + kw.Name + ": " + operand(kw.Value, primaryLevel))
+		sep = ", "
+	}
+
+	return b.String()
+}
+
+func (n *Call) String() string {
+	var b strings.Builder
+	b.WriteString(n.Name + "(")
+
+	sep := ""
+	for _, arg := range n.Args {
+		b.WriteString(sep + operand(arg, primaryLevel))
+		sep = ", "
+	}
+
+	for _, kw := range n.KeywordArgs {
+		b.WriteString(sep + kw.Name + ": " + operand(kw.Value, primaryLevel))
+		sep = ", "
+	}
+
+	b.WriteString(")")
+
+	return b.String()
+}
+
+func (n *BinaryOp) String() string {
+	if n.Op == "and" || n.Op == "or" {
+		return operand(n.Left, condLevel) + " " + n.Op + " " + operand(n.Right, relLevel)
+	}
+
+	return operand(n.Left, primaryLevel) + " " + n.Op + " " + operand(n.Right, primaryLevel)
+}
@@ -0,0 +1,75 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpr_String(t *testing.T) {
+	a, b, c := &Variable{Name: "a"}, &Variable{Name: "b"}, &Variable{Name: "c"}
+	tests := []struct {
+		expr     Expr
+		expected string
+	}{
+		{&Literal{}, "nil"},
+		{&Literal{Value: true}, "true"},
+		{&Literal{Value: 12}, "12"},
+		{&Literal{Value: 2.0}, "2.0"},
+		{&Literal{Value: 2.5}, "2.5"},
+		{&Literal{Value: "it's \"quoted\"\n"}, `"it's \"quoted\"\n"`},
+		{&Index{Object: a, Index: &Literal{Value: "key"}}, `a["key"]`},
+		{&Range{Start: &Literal{Value: 1}, End: &Property{Object: a, Name: "size"}}, "(1..a.size)"},
+		{&Filter{Input: a, Name: "f", Args: []Expr{b}, KeywordArgs: []KeywordArg{{Name: "k", Value: c}}}, "a | f: b, k: c"},
+		{&BinaryOp{Op: "and", Left: &BinaryOp{Op: "or", Left: a, Right: b}, Right: c}, "a or b and c"},
+		{&BinaryOp{Op: "and", Left: a, Right: &BinaryOp{Op: "or", Left: b, Right: c}}, "a and (b or c)"},
+		{&BinaryOp{Op: "==", Left: &Filter{Input: a, Name: "f"}, Right: b}, "(a | f) == b"},
+		{&Filter{Input: &BinaryOp{Op: "==", Left: a, Right: b}, Name: "f"}, "(a == b) | f"},
+		{&Property{Object: &Filter{Input: a, Name: "f"}, Name: "size"}, "(a | f).size"},
+		{&Call{Name: "f"}, "f()"},
+		{&Call{Name: "f", Args: []Expr{a, &Filter{Input: b, Name: "g"}}, KeywordArgs: []KeywordArg{{Name: "k", Value: c}}}, "f(a, (b | g), k: c)"},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, test.expr.String())
+	}
+}
+
+func TestStmt_String(t *testing.T) {
+	a, b := &Variable{Name: "a"}, &Variable{Name: "b"}
+	tests := []struct {
+		stmt     Stmt
+		expected string
+	}{
+		{&ExprStmt{Expr: &BinaryOp{Op: "or", Left: a, Right: b}}, "a or b"},
+		{&AssignStmt{Path: []string{"page", "title"}, Value: &Filter{Input: a, Name: "f"}}, "page.title = a | f"},
+		{&LoopStmt{Variable: "i", Collection: &Filter{Input: a, Name: "f"}}, "i in a | f"},
+		{&LoopStmt{Variable: "i", Collection: a, Reversed: true, Limit: b, Cols: &Literal{Value: 2}}, "i in a reversed limit: b cols: 2"},
+		{&LoopStmt{Variable: "i", Collection: a, OffsetContinue: true}, "i in a offset: continue"},
+		{&CycleStmt{Values: []string{"odd", "even"}}, `"odd", "even"`},
+		{&CycleStmt{Group: "g", Values: []string{"it's"}}, `"g": "it's"`},
+		{&WhenStmt{Exprs: []Expr{&Literal{Value: 1}, &BinaryOp{Op: "==", Left: a, Right: b}}}, "1, (a == b)"},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, test.stmt.String())
+	}
+
+	require.Equal(t, "{% if a %}{% endif %}", (&Block{Name: "if", Args: "x", Stmt: &ExprStmt{Expr: a}}).String())
+}
+
+func TestNode_String(t *testing.T) {
+	obj := func(name string) *Object { return &Object{Expr: &Variable{Name: name}} }
+	root := &Seq{Children: []Node{
+		&Trim{Direction: TrimLeft}, obj("a"), &Trim{Direction: TrimRight},
+		&Text{Source: " x "},
+		&Block{Name: "for", Args: "i in (1..3)", Body: []Node{
+			&Trim{Direction: TrimRight}, obj("i"), &Trim{Direction: TrimLeft},
+		}},
+		&Trim{Direction: TrimRight},
+		&Tag{Name: "break"},
+		&Raw{Source: "{{ raw }}"},
+		&Invalid{Source: "{{ a | }}"},
+	}}
+	require.Equal(t, "{{- a -}} x {% for i in (1..3) -%}{{ i }}{%- endfor -%}{% break %}{% raw %}{{ raw }}{% endraw %}{{ a | }}", root.String())
+}
@@ -0,0 +1,113 @@
+package ast
+
+import "strings"
+
+// A Stmt is the parse of the arguments of a standard tag whose arguments are
+// an expression or a statement, such as {% if %} or {% assign %}. Its String
+// method returns the arguments as Liquid source.
+//
+// The statement nodes are *ExprStmt, *AssignStmt, *LoopStmt, *CycleStmt, and
+// *WhenStmt.
+type Stmt interface {
+	Node
+	stmt()
+}
+
+// ExprStmt is the arguments of a tag whose argument is an expression: if,
+// elsif, unless, case, and echo.
+type ExprStmt struct {
+	Expr Expr
+}
+
+// AssignStmt is the arguments of an assign tag, e.g. "page.title = title".
+type AssignStmt struct {
+	Path  []string // the variable, followed by the properties that are assigned
+	Value Expr
+}
+
+// LoopStmt is the arguments of a for or tablerow tag, e.g.
+// "p in products limit: 2 reversed". Limit, Offset, and Cols are nil if they
+// are absent. OffsetContinue is set by "offset: continue".
+type LoopStmt struct {
+	Variable                 string
+	Collection               Expr
+	Limit, Offset, Cols      Expr
+	Reversed, OffsetContinue bool
+}
+
+// CycleStmt is the arguments of a cycle tag, e.g. "'group': 'odd', 'even'".
+// Group is empty if the tag has no group name.
+type CycleStmt struct {
+	Group  string
+	Values []string
+}
+
+// WhenStmt is the arguments of a when clause, e.g. "1, 2".
+type WhenStmt struct {
+	Exprs []Expr
+}
+
+func (*ExprStmt) node()   {}
+func (*AssignStmt) node() {}
+func (*LoopStmt) node()   {}
+func (*CycleStmt) node()  {}
+func (*WhenStmt) node()   {}
+
+func (*ExprStmt) stmt()   {}
+func (*AssignStmt) stmt() {}
+func (*LoopStmt) stmt()   {}
+func (*CycleStmt) stmt()  {}
+func (*WhenStmt) stmt()   {}
+
+func (n *ExprStmt) String() string { return n.Expr.String() }
+
+func (n *AssignStmt) String() string {
+	return strings.Join(n.Path, ".") + " = " + n.Value.String()
+}
+
+func (n *LoopStmt) String() string {
+	var b strings.Builder
+	b.WriteString(n.Variable + " in " + operand(n.Collection, filteredLevel))
+
+	if n.Reversed {
+		b.WriteString(" reversed")
+	}
+
+	for _, mod := range []struct {
+		name string
+		expr Expr
+	}{{"limit", n.Limit}, {"offset", n.Offset}, {"cols", n.Cols}} {
+		if mod.expr != nil {
+			b.WriteString(" " + mod.name + ": " + operand(mod.expr, primaryLevel))
+		}
+	}
+
+	if n.OffsetContinue {
+		b.WriteString(" offset: continue")
+	}
+
+	return b.String()
+}
+
+func (n *CycleStmt) String() string {
+	values := make([]string, len(n.Values))
+	for i, v := range n.Values {
+		values[i] = quote(v)
+	}
+
+	s := strings.Join(values, ", ")
+	if n.Group != "" {
+		s = quote(n.Group) + ": " + s
+	}
+
+	return s
+}
+
+func (n *WhenStmt) String() string {
+	exprs := make([]string, len(n.Exprs))
+	for i, e := range n.Exprs {
+		exprs[i] = operand(e, primaryLevel)
+	}
+
+	return strings.Join(exprs, ", ")
+}
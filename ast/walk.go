@@ -0,0 +1,244 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses a syntax tree in depth-first order. It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the non-nil children of node, followed by a call of w.Visit(nil).
+//
+// Walk descends from an Object into its expression, and from a Tag or Block
+// into its Stmt, if it is set. It does not descend into the Args of other
+// tags, since their syntax depends on the tag.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Seq:
+		walkList(v, n.Children)
+	case *Object:
+		if n.Expr != nil {
+			Walk(v, n.Expr)
+		}
+	case *Tag:
+		if n.Stmt != nil {
+			Walk(v, n.Stmt)
+		}
+	case *Block:
+		if n.Stmt != nil {
+			Walk(v, n.Stmt)
+		}
+
+		walkList(v, n.Body)
+
+		for _, clause := range n.Clauses {
+			Walk(v, clause)
+		}
+	case *ExprStmt:
+		Walk(v, n.Expr)
+	case *AssignStmt:
+		Walk(v, n.Value)
+	case *LoopStmt:
+		Walk(v, n.Collection)
+
+		for _, mod := range []Expr{n.Limit, n.Offset, n.Cols} {
+			if mod != nil {
+				Walk(v, mod)
+			}
+		}
+	case *WhenStmt:
+		walkList(v, n.Exprs)
+	case *Property:
+		Walk(v, n.Object)
+	case *Index:
+		Walk(v, n.Object)
+		Walk(v, n.Index)
+	case *Range:
+		Walk(v, n.Start)
+		Walk(v, n.End)
+	case *Filter:
+		Walk(v, n.Input)
+
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+
+		for _, kw := range n.KeywordArgs {
+			Walk(v, kw.Value)
+		}
+	case *Call:
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+
+		for _, kw := range n.KeywordArgs {
+			Walk(v, kw.Value)
+		}
+	case *BinaryOp:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	}
+
+	v.Visit(nil)
+}
+
+func walkList[N Node](v Visitor, nodes []N) {
+	for _, n := range nodes {
+		Walk(v, n)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverses a syntax tree in depth-first order. It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a call
+// of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite traverses a syntax tree in depth-first order, and replaces each node
+// by the result of calling fn on it, after its children have been replaced.
+// It modifies the tree in place, and returns the replacement for node.
+//
+// fn can return its argument unchanged. Where a node is an element of a list
+// (the children of a Seq, the body of a Block, the expressions of a WhenStmt,
+// or the arguments of a Filter or Call), fn can return nil to remove it.
+// Otherwise the replacement must have the same kind as the original: an Expr
+// for an Expr, a Stmt for a Stmt, and a *Block for a clause.
+//
+// Like Walk, Rewrite reaches the expressions of objects, such as
+// {{ product.title }}, and of the Stmt of a tag or block, such as the
+// condition of {% if product %} or the value of {% assign x = product %}.
+// The arguments of other tags are source text, that fn sees only as the Args
+// of a Tag or Block.
+func Rewrite(node Node, fn func(Node) Node) Node {
+	switch n := node.(type) {
+	case *Seq:
+		n.Children = rewriteList(n.Children, fn)
+	case *Object:
+		if n.Expr != nil {
+			n.Expr = rewriteExpr(n.Expr, fn)
+		}
+	case *Tag:
+		if n.Stmt != nil {
+			n.Stmt = rewriteStmt(n.Stmt, fn)
+		}
+	case *Block:
+		if n.Stmt != nil {
+			n.Stmt = rewriteStmt(n.Stmt, fn)
+		}
+
+		n.Body = rewriteList(n.Body, fn)
+		for i, clause := range n.Clauses {
+			r, ok := Rewrite(clause, fn).(*Block)
+			if !ok {
+				panic(fmt.Errorf("ast.Rewrite: clause %s was replaced by a non-block", clause))
+			}
+
+			n.Clauses[i] = r
+		}
+	case *ExprStmt:
+		n.Expr = rewriteExpr(n.Expr, fn)
+	case *AssignStmt:
+		n.Value = rewriteExpr(n.Value, fn)
+	case *LoopStmt:
+		n.Collection = rewriteExpr(n.Collection, fn)
+		n.Limit = rewriteOptionalExpr(n.Limit, fn)
+		n.Offset = rewriteOptionalExpr(n.Offset, fn)
+		n.Cols = rewriteOptionalExpr(n.Cols, fn)
+	case *WhenStmt:
+		n.Exprs = rewriteList(n.Exprs, fn)
+	case *Property:
+		n.Object = rewriteExpr(n.Object, fn)
+	case *Index:
+		n.Object = rewriteExpr(n.Object, fn)
+		n.Index = rewriteExpr(n.Index, fn)
+	case *Range:
+		n.Start = rewriteExpr(n.Start, fn)
+		n.End = rewriteExpr(n.End, fn)
+	case *Filter:
+		n.Input = rewriteExpr(n.Input, fn)
+		n.Args = rewriteList(n.Args, fn)
+
+		for i, kw := range n.KeywordArgs {
+			n.KeywordArgs[i].Value = rewriteExpr(kw.Value, fn)
+		}
+	case *Call:
+		n.Args = rewriteList(n.Args, fn)
+
+		for i, kw := range n.KeywordArgs {
+			n.KeywordArgs[i].Value = rewriteExpr(kw.Value, fn)
+		}
+	case *BinaryOp:
+		n.Left = rewriteExpr(n.Left, fn)
+		n.Right = rewriteExpr(n.Right, fn)
+	}
+
+	return fn(node)
+}
+
+func rewriteExpr(e Expr, fn func(Node) Node) Expr {
+	r, ok := Rewrite(e, fn).(Expr)
+	if !ok {
+		panic(fmt.Errorf("ast.Rewrite: expression %s was replaced by a non-expression", e))
+	}
+
+	return r
+}
+
+// rewriteOptionalExpr rewrites e, which can be nil.
+func rewriteOptionalExpr(e Expr, fn func(Node) Node) Expr {
+	if e == nil {
+		return nil
+	}
+
+	return rewriteExpr(e, fn)
+}
+
+func rewriteStmt(s Stmt, fn func(Node) Node) Stmt {
+	r, ok := Rewrite(s, fn).(Stmt)
+	if !ok {
+		panic(fmt.Errorf("ast.Rewrite: tag arguments %s were replaced by a non-statement", s))
+	}
+
+	return r
+}
+
+func rewriteList[N Node](nodes []N, fn func(Node) Node) []N {
+	out := nodes[:0]
+
+	for _, n := range nodes {
+		r := Rewrite(n, fn)
+		if r == nil {
+			continue
+		}
+
+		rn, ok := r.(N)
+		if !ok {
+			panic(fmt.Errorf("ast.Rewrite: %s was replaced by a node of type %T", n, r))
+		}
+
+		out = append(out, rn)
+	}
+
+	return out
+}
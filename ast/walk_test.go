@@ -0,0 +1,134 @@
+package ast
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// {% if a %}{{ b.c | f: d }}{% else %}x{% endif %}
+func testTree() *Seq {
+	return &Seq{Children: []Node{
+		&Block{Name: "if", Args: "a", Body: []Node{
+			&Object{Expr: &Filter{
+				Input: &Property{Object: &Variable{Name: "b"}, Name: "c"},
+				Name:  "f",
+				Args:  []Expr{&Variable{Name: "d"}},
+			}},
+		}, Clauses: []*Block{
+			{Name: "else", Body: []Node{&Text{Source: "x"}}},
+		}},
+	}}
+}
+
+func TestInspect(t *testing.T) {
+	var visited []string
+	Inspect(testTree(), func(n Node) bool {
+		if n != nil {
+			visited = append(visited, fmt.Sprintf("%T", n))
+		}
+
+		return true
+	})
+	require.Equal(t, []string{
+		"*ast.Seq", "*ast.Block", "*ast.Object", "*ast.Filter", "*ast.Property",
+		"*ast.Variable", "*ast.Variable", "*ast.Block", "*ast.Text",
+	}, visited)
+
+	visited = nil
+	Inspect(testTree(), func(n Node) bool {
+		if n != nil {
+			visited = append(visited, fmt.Sprintf("%T", n))
+		}
+
+		_, isObject := n.(*Object)
+
+		return !isObject
+	})
+	require.Equal(t, []string{"*ast.Seq", "*ast.Block", "*ast.Object", "*ast.Block", "*ast.Text"}, visited)
+}
+
+func TestRewrite(t *testing.T) {
+	root := Rewrite(testTree(), func(n Node) Node {
+		switch n := n.(type) {
+		case *Variable:
+			if n.Name == "b" {
+				return &Variable{Name: "renamed"}
+			}
+		case *Text:
+			return nil
+		}
+
+		return n
+	})
+	require.Equal(t, "{% if a %}{{ renamed.c | f: d }}{% else %}{% endif %}", root.String())
+
+	require.Panics(t, func() {
+		Rewrite(testTree(), func(n Node) Node {
+			if _, ok := n.(*Variable); ok {
+				return &Text{}
+			}
+
+			return n
+		})
+	})
+}
+
+func TestRewrite_stmt(t *testing.T) {
+	a, b := &Variable{Name: "a"}, &Variable{Name: "b"}
+	root := &Seq{Children: []Node{
+		&Tag{Name: "assign", Args: "x = a", Stmt: &AssignStmt{Path: []string{"x"}, Value: a}},
+		&Block{Name: "for", Args: "i in a limit: b", Stmt: &LoopStmt{Variable: "i", Collection: a, Limit: b}},
+		&Tag{Name: "cycle", Args: "'x', 'y'", Stmt: &CycleStmt{Values: []string{"x", "y"}}},
+	}}
+
+	var visited []string
+	Inspect(root, func(n Node) bool {
+		if v, ok := n.(*Variable); ok {
+			visited = append(visited, v.Name)
+		}
+
+		return true
+	})
+	require.Equal(t, []string{"a", "a", "b"}, visited)
+
+	Rewrite(root, func(n Node) Node {
+		switch n := n.(type) {
+		case *Variable:
+			return &Variable{Name: n.Name + "2"}
+		case *CycleStmt:
+			n.Group = "g"
+		}
+
+		return n
+	})
+	require.Equal(t, `{% assign x = a2 %}{% for i in a2 limit: b2 %}{% endfor %}{% cycle "g": "x", "y" %}`, root.String())
+
+	require.Panics(t, func() {
+		Rewrite(root, func(n Node) Node {
+			if _, ok := n.(*AssignStmt); ok {
+				return a
+			}
+
+			return n
+		})
+	})
+}
+
+func TestCopyExpr(t *testing.T) {
+	expr := testTree().Children[0].(*Block).Body[0].(*Object).Expr
+	c := CopyExpr(expr)
+	require.Equal(t, expr, c)
+
+	Rewrite(&Object{Expr: c}, func(n Node) Node {
+		if v, ok := n.(*Variable); ok {
+			v.Name = "x"
+		}
+
+		return n
+	})
+	require.Equal(t, "x.c | f: x", c.String())
+	require.Equal(t, "b.c | f: d", expr.String())
+	require.Nil(t, CopyExpr(nil))
+}
@@ -6,9 +6,15 @@
 //
 //	echo '{{ "Hello " | append: "World" }}' | liquid
 //	liquid source.tpl
+//
+// The fmt subcommand formats templates, in the manner of gofmt:
+//
+//	liquid fmt source.tpl
+//	liquid fmt -w templates/*.html
 package main
 
 import (
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
@@ -33,12 +39,18 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		formatMain(os.Args[2:])
+		return
+	}
+
 	var err error
 
 	cmdLine := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
 	cmdLine.Usage = func() {
-		fmt.Fprintf(stderr, "usage: %s [OPTIONS] [FILE]\n", cmdLine.Name()) //nolint:errcheck
-		fmt.Fprint(stderr, "\nOPTIONS\n")                                   //nolint:errcheck
+		fmt.Fprintf(stderr, "usage: %s [OPTIONS] [FILE]\n", cmdLine.Name())        //nolint:errcheck
+		fmt.Fprintf(stderr, "       %s fmt [-l] [-w] [FILE...]\n", cmdLine.Name()) //nolint:errcheck
+		fmt.Fprint(stderr, "\nOPTIONS\n")                                          //nolint:errcheck
 		cmdLine.PrintDefaults()
 	}
 
@@ -113,3 +125,98 @@ func render() error {
 
 	return err
 }
+
+// formatMain implements the fmt subcommand. It writes the formatted source of
+// each file, or of stdin if there are no files, to stdout.
+func formatMain(args []string) {
+	cmdLine := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	cmdLine.SetOutput(stderr)
+	cmdLine.Usage = func() {
+		fmt.Fprintf(stderr, "usage: %s fmt [-l] [-w] [FILE...]\n", os.Args[0]) //nolint:errcheck
+		fmt.Fprint(stderr, "\nOPTIONS\n")                                      //nolint:errcheck
+		cmdLine.PrintDefaults()
+	}
+
+	var list, write bool
+	cmdLine.BoolVar(&list, "l", false, "list files whose formatting differs from liquid fmt's")
+	cmdLine.BoolVar(&write, "w", false, "write the result to the source file instead of stdout")
+
+	if err := cmdLine.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			exit(0)
+			return
+		}
+		fmt.Fprintln(stderr, err) //nolint:errcheck
+		exit(1)
+		return
+	}
+
+	files := cmdLine.Args()
+	if len(files) == 0 {
+		if write {
+			fmt.Fprintln(stderr, "cannot use -w with standard input") //nolint:errcheck
+			exit(1)
+			return
+		}
+
+		if err := formatFile("<standard input>", stdin, list, false); err != nil {
+			fmt.Fprintln(stderr, err) //nolint:errcheck
+			exit(1)
+		}
+
+		return
+	}
+
+	status := 0
+
+	for _, name := range files {
+		if err := formatPath(name, list, write); err != nil {
+			fmt.Fprintln(stderr, err) //nolint:errcheck
+			status = 1
+		}
+	}
+
+	if status != 0 {
+		exit(status)
+	}
+}
+
+func formatPath(name string, list, write bool) error {
+	f, err := os.Open(filepath.Clean(name)) //nolint:gosec // CLI tool intentionally opens user-specified files
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+
+	return formatFile(name, f, list, write)
+}
+
+func formatFile(name string, r io.Reader, list, write bool) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	out, err := liquid.Format(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	changed := !bytes.Equal(src, out)
+	if list && changed {
+		if _, err := fmt.Fprintln(stdout, name); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case write:
+		if changed {
+			return os.WriteFile(filepath.Clean(name), out, 0o644) //nolint:gosec // the file already exists
+		}
+	case !list:
+		_, err = stdout.Write(out)
+	}
+
+	return err
+}
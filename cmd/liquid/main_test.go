@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -137,3 +138,64 @@ func TestMain(t *testing.T) {
 	require.Contains(t, buf.String(), "too many")
 	require.Equal(t, 1, exitCode)
 }
+
+func TestMain_fmt(t *testing.T) {
+	oldArgs := os.Args
+
+	defer func() {
+		os.Args = oldArgs
+		stderr = os.Stderr
+		stdout = os.Stdout
+		stdin = os.Stdin
+		exit = os.Exit
+	}()
+
+	exitCode := 0
+	exit = func(n int) { exitCode = n }
+
+	// stdin
+	buf := &bytes.Buffer{}
+	stdin = bytes.NewBufferString("{%if x%}\n{{x|upcase}}\n{%endif%}\n")
+	stdout = buf
+	os.Args = []string{"liquid", "fmt"}
+
+	main()
+	require.Equal(t, 0, exitCode)
+	require.Equal(t, "{% if x %}\n{{ x | upcase }}\n{% endif %}\n", buf.String())
+
+	// -l and -w
+	path := filepath.Join(t.TempDir(), "page.liquid")
+	require.NoError(t, os.WriteFile(path, []byte("{{x}}\n"), 0o600))
+
+	buf = &bytes.Buffer{}
+	stdout = buf
+	os.Args = []string{"liquid", "fmt", "-l", path}
+
+	main()
+	require.Equal(t, path+"\n", buf.String())
+
+	os.Args = []string{"liquid", "fmt", "-w", path}
+
+	main()
+	require.Equal(t, 0, exitCode)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "{{ x }}\n", string(data))
+
+	buf = &bytes.Buffer{}
+	stdout = buf
+	os.Args = []string{"liquid", "fmt", "-l", path}
+
+	main()
+	require.Empty(t, buf.String())
+
+	// syntax error
+	buf = &bytes.Buffer{}
+	stderr = buf
+	stdin = bytes.NewBufferString("{% if x %}")
+	os.Args = []string{"liquid", "fmt"}
+
+	main()
+	require.Equal(t, 1, exitCode)
+	require.Contains(t, buf.String(), "unterminated")
+}
@@ -0,0 +1,136 @@
+package liquid
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/osteele/liquid/ast"
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+	"github.com/osteele/liquid/render"
+)
+
+// compiledFormatVersion identifies the format that Precompile writes.
+// LoadCompiled rejects other versions, since the syntax trees can change
+// between releases.
+const compiledFormatVersion = 1
+
+// compiledTemplates is the data that Precompile writes.
+type compiledTemplates struct {
+	Version   int
+	Templates []compiledTemplate
+	Parses    []expressions.ParsedSource // the expressions and statements in the templates' tags
+}
+
+type compiledTemplate struct {
+	Path string
+	Root ast.Node
+}
+
+func init() {
+	for _, n := range []ast.Node{
+		&ast.Seq{}, &ast.Text{}, &ast.Object{}, &ast.Tag{}, &ast.Block{}, &ast.Raw{}, &ast.Trim{}, &ast.Invalid{},
+		&ast.Literal{}, &ast.Variable{}, &ast.Property{}, &ast.Index{}, &ast.Range{}, &ast.Filter{}, &ast.Call{}, &ast.BinaryOp{},
+	} {
+		gob.RegisterName("liquid/ast."+reflect.TypeOf(n).Elem().Name(), n)
+	}
+}
+
+// Precompile parses templates, keyed by path, and writes them to w in a
+// compiled form, that LoadCompiled reads without parsing their source. The
+// paths are used as for ParseTemplateAndCache. It returns the first parse
+// error.
+//
+// The compiled form holds the syntax trees of the templates, and of the
+// expressions in their tags, encoded with encoding/gob. It can only be read
+// by the same version of this package.
+func (e *Engine) Precompile(w io.Writer, templates map[string][]byte) error {
+	out := compiledTemplates{Version: compiledFormatVersion}
+
+	// Compile with a copy of the configuration whose parse table records the
+	// expressions and statements that the tags parse.
+	cfg := e.config().Clone()
+	cfg.ParseTable = expressions.NewRecordingParseTable()
+
+	for _, path := range slices.Sorted(maps.Keys(templates)) {
+		root, _, err := cfg.CompileWithWarnings(string(templates[path]), parser.SourceLoc{Pathname: path, LineNo: 1})
+		if err != nil {
+			return err
+		}
+
+		out.Templates = append(out.Templates, compiledTemplate{Path: path, Root: withoutTagStmts(render.ToAST(root))})
+	}
+
+	out.Parses = cfg.ParseTable.Parses()
+
+	return gob.NewEncoder(w).Encode(out)
+}
+
+// withoutTagStmts clears the Stmt of the tags and blocks in root, so that
+// LoadCompiled compiles their arguments as written, which are the sources
+// that the parse table holds.
+func withoutTagStmts(root ast.Node) ast.Node {
+	ast.Inspect(root, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Tag:
+			n.Stmt = nil
+		case *ast.Block:
+			n.Stmt = nil
+		}
+
+		return true
+	})
+
+	return root
+}
+
+// LoadCompiled reads templates that Precompile wrote, and compiles them with
+// the engine's configuration. It returns them by path. Like
+// ParseTemplateAndCache, it also makes them available to the include and
+// render tags, unless the template store has a file with the same path.
+//
+// The syntax trees of the expressions in the templates' tags are added to the
+// engine's parse table, so that compiling the templates, and other templates
+// that the engine compiles with the same expressions, doesn't parse them.
+// Only load compiled templates from a trusted source.
+func (e *Engine) LoadCompiled(r io.Reader) (map[string]*Template, error) {
+	var in compiledTemplates
+	if err := gob.NewDecoder(r).Decode(&in); err != nil {
+		return nil, fmt.Errorf("reading compiled templates: %w", err)
+	}
+
+	if in.Version != compiledFormatVersion {
+		return nil, fmt.Errorf("compiled templates have format version %d; expected %d", in.Version, compiledFormatVersion)
+	}
+
+	cfg := e.config().Clone()
+	cfg.ParseTable = cfg.ParseTable.With(in.Parses)
+
+	templates := make(map[string]*Template, len(in.Templates))
+	for _, ct := range in.Templates {
+		root, warnings, err := cfg.CompileAST(ct.Root)
+		if err != nil {
+			return nil, err
+		}
+
+		templates[ct.Path] = newTemplateFromRoot(e.cfg, &cfg, root, warnings)
+	}
+
+	e.configure(func(cfg *render.Config) {
+		cfg.ParseTable = cfg.ParseTable.With(in.Parses)
+
+		if cfg.Compiled == nil {
+			cfg.Compiled = make(map[string]render.Node, len(templates))
+		}
+
+		for path, t := range templates {
+			cfg.Compiled[path] = t.root
+		}
+	})
+
+	return templates, nil
+}
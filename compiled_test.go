@@ -0,0 +1,122 @@
+package liquid
+
+import (
+	"bytes"
+	"encoding/gob"
+	"maps"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/ast"
+)
+
+var compiledTemplateSources = map[string][]byte{
+	"page.html": []byte(`{% assign title = page.title | upcase -%}
+<h1>{{ title }}</h1>
+{%- for item in items limit: 2 reversed %}
+  {% cycle "odd", "even" %}: {% render "card.html", name: item %}
+{%- endfor %}
+{% case items.size %}{% when 1, 2 %}few{% else %}many{% endcase %}
+{% capture c %}{{ 1 | plus: 2 }}{% endcapture %}{{ c }}{% raw %}{{ raw }}{% endraw %}
+{% liquid
+  if page.title contains "o"
+    echo "o"
+  endif
+%}`),
+	"card.html": []byte(`<b>{{ name }}</b>{% tablerow i in (1..2) cols: 2 %}{{ i }}{% endtablerow %}`),
+}
+
+func TestEngine_LoadCompiled(t *testing.T) {
+	bindings := Bindings{"page": map[string]any{"title": "Home"}, "items": []string{"a", "b", "c"}}
+
+	engine := NewEngine()
+	engine.RegisterFS(fstest.MapFS{"card.html": {Data: compiledTemplateSources["card.html"]}})
+	expected, err := engine.ParseAndRenderString(string(compiledTemplateSources["page.html"]), bindings)
+	require.NoError(t, err)
+
+	sources := maps.Clone(compiledTemplateSources)
+	sources["broken.html"] = []byte("\n{{ x | undefined_filter }}")
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, NewEngine().Precompile(buf, sources))
+
+	// The loaded templates render as the source does, and the render tag
+	// finds the compiled partial.
+	loader := NewEngine()
+	loader.RegisterFS(fstest.MapFS{})
+	templates, lerr := loader.LoadCompiled(buf)
+	require.NoError(t, lerr)
+	require.Len(t, templates, 3)
+
+	out, err := templates["page.html"].RenderString(bindings)
+	require.NoError(t, err)
+	require.Equal(t, expected, out)
+
+	// Errors keep their source locations.
+	_, err = templates["broken.html"].RenderString(Bindings{})
+	require.Error(t, err)
+	require.Equal(t, "broken.html", err.Path())
+	require.Equal(t, 2, err.LineNumber())
+
+	// A file in the template store takes precedence over a compiled partial.
+	loader.RegisterFS(fstest.MapFS{"card.html": {Data: []byte(`card`)}})
+	out, err = loader.ParseAndRenderString(`{% include "card.html" %}`, Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "card", out)
+}
+
+func TestEngine_Precompile_errors(t *testing.T) {
+	engine := NewEngine()
+
+	err := engine.Precompile(new(bytes.Buffer), map[string][]byte{"bad.html": []byte("\n{% if %}")})
+	require.Error(t, err)
+
+	se, ok := err.(SourceError)
+	require.True(t, ok)
+	require.Equal(t, "bad.html", se.Path())
+	require.Equal(t, 2, se.LineNumber())
+
+	_, err = engine.LoadCompiled(bytes.NewBufferString("not compiled"))
+	require.Error(t, err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, gob.NewEncoder(buf).Encode(compiledTemplates{Version: compiledFormatVersion + 1}))
+	_, err = engine.LoadCompiled(buf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "format version")
+}
+
+func TestEngine_LoadCompiled_parses(t *testing.T) {
+	source := `{% echo 1 | plus: 1 %}`
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, NewEngine().Precompile(buf, map[string][]byte{"page.html": []byte(source)}))
+
+	var in compiledTemplates
+	require.NoError(t, gob.NewDecoder(buf).Decode(&in))
+	require.Len(t, in.Parses, 1)
+	require.Equal(t, `1 | plus: 1`, in.Parses[0].Source)
+
+	// The loading engine uses the recorded parse, here replaced by another
+	// tree, instead of parsing the source. Other engines don't.
+	in.Parses[0].Expr = &ast.Literal{Value: 3}
+	require.NoError(t, gob.NewEncoder(buf).Encode(in))
+
+	loader := NewEngine()
+	templates, err := loader.LoadCompiled(buf)
+	require.NoError(t, err)
+
+	out, err := templates["page.html"].RenderString(Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "3", out)
+
+	out, err = loader.ParseAndRenderString(source, Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "3", out)
+
+	out, err = NewEngine().ParseAndRenderString(source, Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "2", out)
+}
@@ -2,42 +2,109 @@ package liquid
 
 import (
 	"io"
+	"io/fs"
+	"sync"
+	"sync/atomic"
 
+	"github.com/osteele/liquid/ast"
 	"github.com/osteele/liquid/filters"
+	"github.com/osteele/liquid/parser"
 	"github.com/osteele/liquid/render"
 	"github.com/osteele/liquid/tags"
 )
 
 // An Engine parses template source into renderable text.
 //
-// An engine can be configured with additional filters and tags.
-type Engine struct{ cfg render.Config }
+// An engine can be configured with additional filters and tags. It is safe to
+// configure an engine while other goroutines use it to parse and render
+// templates. Each change is made to a copy of the configuration, which then
+// replaces it; a parse or render uses the configuration that was current when
+// it began.
+type Engine struct {
+	mu  sync.Mutex // serializes configuration changes
+	cfg *atomic.Pointer[render.Config]
+}
 
 // NewEngine returns a new Engine.
 func NewEngine() *Engine {
-	e := Engine{render.NewConfig()}
-	filters.AddStandardFilters(&e.cfg)
-	tags.AddStandardTags(&e.cfg)
+	cfg := render.NewConfig()
+	filters.AddStandardFilters(&cfg)
+	tags.AddStandardTags(&cfg)
 
-	return &e
+	return newEngine(cfg)
 }
 
 // NewBasicEngine returns a new Engine without the standard filters or tags.
 func NewBasicEngine() *Engine {
-	return &Engine{render.NewConfig()}
+	return newEngine(render.NewConfig())
+}
+
+func newEngine(cfg render.Config) *Engine {
+	if cfg.Sources == nil {
+		cfg.Sources = render.NewSourceCache()
+	}
+
+	e := Engine{cfg: &atomic.Pointer[render.Config]{}}
+	e.cfg.Store(&cfg)
+
+	return &e
+}
+
+// Clone returns a new engine with a copy of e's configuration: its tags,
+// filters, delimiters, modes, limits, template store, and the templates cached
+// by ParseTemplateAndCache. Later changes to either engine don't affect the
+// other. If e has a partial cache, the clone has a new, empty one of the same
+// size, since the clone may compile partials differently.
+func (e *Engine) Clone() *Engine {
+	cfg := e.config().Clone()
+	cfg.Sources = cfg.Sources.Clone()
+
+	if pc := cfg.PartialCache; pc != nil {
+		cfg.SetPartialCache(render.NewPartialCache(pc.MaxEntries()))
+	}
+
+	return newEngine(cfg)
+}
+
+// config returns the current configuration. Callers must not modify it.
+func (e *Engine) config() *render.Config {
+	return e.cfg.Load()
+}
+
+// configure applies fn to a copy of the current configuration, and then makes
+// the copy current.
+func (e *Engine) configure(fn func(*render.Config)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cfg := e.config().Clone()
+	fn(&cfg)
+	e.cfg.Store(&cfg)
+}
+
+// configureCompiler is configure for a change that affects how templates
+// compile. It also resets the partial cache, so that renders that use the
+// new configuration don't use partials that were compiled with an old one.
+func (e *Engine) configureCompiler(fn func(*render.Config)) {
+	e.configure(func(cfg *render.Config) {
+		fn(cfg)
+		cfg.ResetPartialCache()
+	})
 }
 
 // RegisterBlock defines a block e.g. {% tag %}…{% endtag %}.
 func (e *Engine) RegisterBlock(name string, td Renderer) {
-	e.cfg.AddBlock(name).Renderer(func(w io.Writer, ctx render.Context) error {
-		s, err := td(ctx)
-		if err != nil {
-			return err
-		}
+	e.configureCompiler(func(cfg *render.Config) {
+		cfg.AddBlock(name).Renderer(func(w io.Writer, ctx render.Context) error {
+			s, err := td(ctx)
+			if err != nil {
+				return err
+			}
 
-		_, err = io.WriteString(w, s)
+			_, err = io.WriteString(w, s)
 
-		return err
+			return err
+		})
 	})
 }
 
@@ -46,13 +113,18 @@ func (e *Engine) RegisterBlock(name string, td Renderer) {
 // A filter is a function that takes at least one input, and returns one or two outputs.
 // If it returns two outputs, the second must have type error.
 //
+// A filter can also take the render's environment as its first parameter,
+// before its input. A filter whose first parameter is a render.Context, an
+// expressions.Context, or a context.Context receives the current one, and can
+// read the bindings, the registers, or the cancellation context.
+//
 // Examples:
 //
 // * https://github.com/osteele/liquid/blob/main/filters/standard_filters.go
 //
 // * https://github.com/osteele/gojekyll/blob/master/filters/filters.go
 func (e *Engine) RegisterFilter(name string, fn any) {
-	e.cfg.AddFilter(name, fn)
+	e.configure(func(cfg *render.Config) { cfg.AddFilter(name, fn) })
 }
 
 // RegisterTag defines a tag e.g. {% tag %}.
@@ -61,46 +133,114 @@ func (e *Engine) RegisterFilter(name string, fn any) {
 func (e *Engine) RegisterTag(name string, td Renderer) {
 	// For simplicity, don't expose the two stage parsing/rendering process to clients.
 	// Client tags do everything at runtime.
-	e.cfg.AddTag(name, func(_ string) (func(io.Writer, render.Context) error, error) {
-		return func(w io.Writer, ctx render.Context) error {
-			s, err := td(ctx)
-			if err != nil {
-				return err
-			}
+	e.configureCompiler(func(cfg *render.Config) {
+		cfg.AddTag(name, func(_ string) (func(io.Writer, render.Context) error, error) {
+			return func(w io.Writer, ctx render.Context) error {
+				s, err := td(ctx)
+				if err != nil {
+					return err
+				}
 
-			_, err = io.WriteString(w, s)
+				_, err = io.WriteString(w, s)
 
-			return err
-		}, nil
+				return err
+			}, nil
+		})
 	})
 }
 
 func (e *Engine) RegisterTemplateStore(templateStore render.TemplateStore) {
-	e.cfg.TemplateStore = templateStore
+	e.configureCompiler(func(cfg *render.Config) { cfg.TemplateStore = templateStore })
+}
+
+// RegisterFS sets the file system that the {% include %} and {% render %} tags
+// read templates from; for example, an embed.FS. Template paths are relative to
+// the root of fsys, and to the directory of the including template's source
+// path, as set by ParseTemplateLocation. Use fs.Sub to serve a subdirectory.
+func (e *Engine) RegisterFS(fsys fs.FS) {
+	e.RegisterTemplateStore(&render.FSTemplateStore{FS: fsys})
 }
 
 // StrictVariables causes the renderer to error when the template contains an undefined variable.
 func (e *Engine) StrictVariables() {
-	e.cfg.StrictVariables = true
+	e.configure(func(cfg *render.Config) { cfg.StrictVariables = true })
 }
 
 // LaxFilters causes the renderer to silently pass through the input value
 // when the template contains an undefined filter, matching Shopify Liquid behavior.
 // By default, undefined filters cause an error.
 func (e *Engine) LaxFilters() {
-	e.cfg.LaxFilters = true
+	e.configure(func(cfg *render.Config) { cfg.LaxFilters = true })
+}
+
+// ErrorMode sets how the engine responds to syntax and render errors, in the
+// manner of Shopify Liquid's error modes.
+//
+// In Strict mode, the default, parsing and rendering stop at the first error.
+// In Lax mode, an object or tag that fails to parse renders as nothing, and a
+// render error is written into the output as "Liquid error: …" text. Warn
+// mode behaves like Lax mode, and also collects the syntax errors in the
+// template's Warnings. Errors in block structure, such as an unterminated
+// {% if %}, are reported in every mode.
+func (e *Engine) ErrorMode(mode ErrorMode) {
+	e.configureCompiler(func(cfg *render.Config) { cfg.ErrorMode = mode })
 }
 
 // EnableJekyllExtensions enables Jekyll-specific extensions to Liquid.
 // This includes support for dot notation in assign tags (e.g., {% assign page.canonical_url = value %}).
+// It also gives the include tag Jekyll's file names and key=value parameters,
+// which the partial reads as include.key, and adds the include_relative tag.
 // Note: This is not part of the Shopify Liquid standard but is used in Jekyll and Gojekyll.
 func (e *Engine) EnableJekyllExtensions() {
-	e.cfg.JekyllExtensions = true
+	e.configureCompiler(func(cfg *render.Config) {
+		cfg.JekyllExtensions = true
+
+		if _, ok := cfg.FindTagDefinition("include_relative"); !ok {
+			tags.AddJekyllTags(cfg)
+		}
+	})
+}
+
+// EnableTemplateInheritance defines the layout, extends, and block tags.
+//
+// A template that executes {% layout "base.html" %} renders the named
+// template, which is loaded from the template store, in its place. Its
+// {% block name %}…{% endblock %} blocks replace the blocks of the same name in
+// the layout, and {{ block.super }} within a block renders the block that it
+// replaces. Layouts can themselves have layouts. The output of a template with
+// a layout, apart from its blocks, is discarded from the layout tag on, so
+// the tag should come first.
+//
+// It also defines the content_for and yield tags. {% content_for "head" %}…
+// {% endcontent_for %} appends its output to a named region instead of
+// writing it, and {% yield "head" %} writes the region. Template.RenderWithResult
+// returns the regions to the host.
+func (e *Engine) EnableTemplateInheritance() {
+	e.configureCompiler(func(cfg *render.Config) {
+		if _, ok := cfg.FindTagDefinition("layout"); !ok {
+			tags.AddInheritanceTags(cfg)
+		}
+	})
+}
+
+// EnableMacros defines the macro block and the call tag. A template defines a
+// macro with {% macro price(amount, currency='USD') %}…{% endmacro %}, and
+// calls it later on with {% call price(product.price) %} or
+// {{ price(product.price, currency: 'EUR') }}. The body of a macro sees only
+// its parameters, as a partial that the render tag renders does. A call to a
+// macro that the template hasn't defined, or with arguments that don't match
+// its parameters, is a parse error.
+func (e *Engine) EnableMacros() {
+	e.configureCompiler(func(cfg *render.Config) {
+		if _, ok := cfg.FindTagDefinition("call"); !ok {
+			tags.AddMacroTags(cfg)
+		}
+	})
 }
 
 // ParseTemplate creates a new Template using the engine configuration.
 func (e *Engine) ParseTemplate(source []byte) (*Template, SourceError) {
-	return newTemplate(&e.cfg, source, "", 0)
+	return newTemplate(e.cfg, source, "", 0)
 }
 
 // ParseString creates a new Template using the engine configuration.
@@ -114,7 +254,36 @@ func (e *Engine) ParseString(source string) (*Template, SourceError) {
 // The path and line number are used for error reporting.
 // The path is also the reference for relative pathnames in the {% include %} tag.
 func (e *Engine) ParseTemplateLocation(source []byte, path string, line int) (*Template, SourceError) {
-	return newTemplate(&e.cfg, source, path, line)
+	return newTemplate(e.cfg, source, path, line)
+}
+
+// CompileAST creates a new Template from a syntax tree, such as one that
+// Template.AST returned and that has since been modified. The tree is checked
+// against the engine's tags, as ParseTemplate checks source text. Source
+// locations in errors come from the Loc fields of the nodes.
+func (e *Engine) CompileAST(root ast.Node) (*Template, SourceError) {
+	cfg := e.config()
+
+	node, warnings, err := cfg.CompileAST(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTemplateFromRoot(e.cfg, cfg, node, warnings), nil
+}
+
+// Format returns template source in canonical form. It normalizes the spacing
+// inside objects and tags, using the engine's tags and delimiters. Text, trim
+// markers, and the bodies of raw and comment blocks are kept as written, so
+// the rendered output doesn't change. It returns an error for an object or
+// tag argument that doesn't parse. See parser.Config.Format for the details.
+func (e *Engine) Format(source []byte) ([]byte, SourceError) {
+	out, err := e.config().Format(string(source), parser.SourceLoc{LineNo: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(out), nil
 }
 
 // ParseAndRender parses and then renders the template.
@@ -158,7 +327,7 @@ func (e *Engine) Delims(objectLeft, objectRight, tagLeft, tagRight string) *Engi
 			delims[i] = defaults[i]
 		}
 	}
-	e.cfg.Delims = delims
+	e.configureCompiler(func(cfg *render.Config) { cfg.Delims = delims })
 	return e
 }
 
@@ -175,7 +344,9 @@ func (e *Engine) ParseTemplateAndCache(source []byte, path string, line int) (*T
 		return t, err
 	}
 
-	e.cfg.Cache[path] = source
+	// The sources are shared by the engine's configurations, so adding one
+	// doesn't copy the configuration.
+	e.config().Sources.Set(path, source)
 
 	return t, err
 }
@@ -183,9 +354,17 @@ func (e *Engine) ParseTemplateAndCache(source []byte, path string, line int) (*T
 // SetAutoEscapeReplacer enables auto-escape functionality where the output of expression blocks ({{ ... }}) is
 // passed though a render.Replacer during rendering, unless it's been marked as safe by applying the 'safe' filter.
 // This filter is automatically registered when this method is called. The filter must be applied last.
-// A replacer is provided for escaping HTML (see render.HtmlEscaper).
+// A replacer is provided for escaping HTML (see render.HtmlEscaper). render.HtmlContextEscaper instead
+// escapes each object for its HTML context: text, attribute, URL, script, or style.
 func (e *Engine) SetAutoEscapeReplacer(replacer render.Replacer) {
-	e.cfg.SetAutoEscapeReplacer(replacer)
+	e.configure(func(cfg *render.Config) { cfg.SetAutoEscapeReplacer(replacer) })
+}
+
+// SetLimits bounds the output size, loop iterations, range sizes, and include
+// nesting of each render. A render that exceeds a limit returns a SourceError
+// whose cause is a LimitError.
+func (e *Engine) SetLimits(limits Limits) {
+	e.configure(func(cfg *render.Config) { cfg.Limits = limits })
 }
 
 // UnregisterTag removes the named tag definition from the engine's configuration.
@@ -193,5 +372,18 @@ func (e *Engine) SetAutoEscapeReplacer(replacer render.Replacer) {
 // parsing or rendering operations. The call is idempotent — unregistering a tag
 // that is not registered is a no-op.
 func (e *Engine) UnregisterTag(name string) {
-	e.cfg.UnregisterTag(name)
+	e.configureCompiler(func(cfg *render.Config) { cfg.UnregisterTag(name) })
+}
+
+// SetPartialCache shares a cache of compiled partials across the renders of
+// the engine's templates, so that the include and render tags needn't
+// recompile a partial for each render. A nil cache turns caching off.
+//
+// The engine clears the cache when a change to its tags, delimiters, error
+// mode, or template store affects how partials compile. Call
+// PartialCache.Invalidate or PartialCache.Clear when a template changes in a
+// store that doesn't implement TemplateVersioner and that the cache therefore
+// reads on each use.
+func (e *Engine) SetPartialCache(cache *PartialCache) {
+	e.configure(func(cfg *render.Config) { cfg.SetPartialCache(cache) })
 }
@@ -2,14 +2,19 @@ package liquid
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/osteele/liquid/expressions"
 	"github.com/osteele/liquid/render"
 
 	"github.com/stretchr/testify/require"
@@ -172,6 +177,15 @@ func TestEngine_ParseTemplateAndCache(t *testing.T) {
 	result, err := eng.ParseAndRender(templateB, Bindings{})
 	require.NoError(t, err)
 	require.Equal(t, "Foo, Bar", string(result))
+
+	// Caching a template doesn't copy the configuration.
+	cfg := eng.config()
+	_, err = eng.ParseTemplateAndCache([]byte("Baz"), "template_a.html", 1)
+	require.NoError(t, err)
+	require.Same(t, cfg, eng.config())
+	result, err = eng.ParseAndRender(templateB, Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "Baz, Bar", string(result))
 }
 
 type MockTemplateStore struct{}
@@ -195,6 +209,261 @@ func Test_template_store(t *testing.T) {
 	require.Equal(t, "Message Text: filename from: template.liquid.", out)
 }
 
+func TestEngine_RegisterFS(t *testing.T) {
+	engine := NewEngine()
+	engine.RegisterFS(fstest.MapFS{
+		"header.liquid":              {Data: []byte("<h1>{{ title }}</h1>")},
+		"pages/index.liquid":         {Data: []byte(`{% render "partials/card.liquid", name: "a" %}`)},
+		"pages/partials/card.liquid": {Data: []byte(`[{{ name }}]`)},
+		"pages/escape.liquid":        {Data: []byte(`{% include "../header.liquid" %}`)},
+	})
+
+	out, err := engine.ParseAndRenderString(`{% include "header.liquid" %}`, Bindings{"title": "Home"})
+	require.NoError(t, err)
+	require.Equal(t, "<h1>Home</h1>", out)
+
+	tpl, err := engine.ParseTemplateLocation([]byte(`{% include "index.liquid" %}`), "pages/page.liquid", 1)
+	require.NoError(t, err)
+	out, err = tpl.RenderString(Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "[a]", out)
+
+	tpl, err = engine.ParseTemplateLocation([]byte(`{% include "escape.liquid" %}`), "pages/page.liquid", 1)
+	require.NoError(t, err)
+	_, err = tpl.RenderString(Bindings{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "escapes its source directory")
+
+	_, err = engine.ParseAndRenderString(`{% include "missing.liquid" %}`, Bindings{})
+	require.Error(t, err)
+}
+
+func TestEngine_EnableTemplateInheritance(t *testing.T) {
+	engine := NewEngine()
+	engine.RegisterFS(fstest.MapFS{
+		"layouts/base.liquid": {Data: []byte("<h1>{% block title %}Site{% endblock %}</h1>\n{% block content %}{% endblock %}")},
+		"pages/page.liquid":   {Data: []byte("{% layout \"../layouts/base.liquid\" %}")},
+	})
+	engine.EnableTemplateInheritance()
+	engine.EnableTemplateInheritance()
+
+	tpl, err := engine.ParseTemplateLocation([]byte(`{% layout "layouts/base.liquid" %}{% block title %}{{ block.super }}: {{ title }}{% endblock %}`), "page.liquid", 1)
+	require.NoError(t, err)
+	out, err := tpl.RenderString(Bindings{"title": "Home"})
+	require.NoError(t, err)
+	require.Equal(t, "<h1>Site: Home</h1>\n", out)
+
+	// An error in a block is reported at its location in the child template.
+	tpl, err = engine.ParseTemplateLocation([]byte("{% layout \"layouts/base.liquid\" %}\n{% block content %}{{ x | nofilter }}{% endblock %}"), "page.liquid", 1)
+	require.NoError(t, err)
+	_, err = tpl.RenderString(Bindings{})
+	require.Error(t, err)
+	require.Equal(t, "page.liquid", err.Path())
+	require.Equal(t, 2, err.LineNumber())
+
+	tpl, err = engine.ParseString("{% layout \"layouts/base.liquid\" %}\n{% block content %}{{ x | nofilter }}{% endblock %}")
+	require.NoError(t, err)
+	_, err = tpl.RenderString(Bindings{})
+	require.Error(t, err)
+	require.Empty(t, err.Path())
+
+	_, err = engine.ParseAndRenderString(`{% include "pages/page.liquid" %}`, Bindings{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "escapes its source directory")
+}
+
+func TestEngine_EnableMacros(t *testing.T) {
+	engine := NewEngine()
+
+	_, err := engine.ParseString(`{% macro price(amount) %}{% endmacro %}`)
+	require.Error(t, err)
+
+	engine.EnableMacros()
+	engine.EnableMacros()
+
+	src := `{% macro price(amount, currency='USD') %}{{ amount | times: 2 }} {{ currency }}{% endmacro %}` +
+		`{% for p in products %}{% call price(p.amount) %}; {{ price(p.amount, currency: 'EUR') }}{% endfor %}`
+	out, err := engine.ParseAndRenderString(src, Bindings{"products": []map[string]any{{"amount": 5}}})
+	require.NoError(t, err)
+	require.Equal(t, "10 USD; 10 EUR", out)
+
+	_, err = engine.ParseString("{% macro price(amount) %}{% endmacro %}\n{{ price() }}")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `missing argument "amount"`)
+}
+
+func TestEngine_EnableJekyllExtensions_include(t *testing.T) {
+	engine := NewEngine()
+	engine.RegisterFS(fstest.MapFS{
+		"card.html":          {Data: []byte(`<h2>{{ include.title }}</h2>`)},
+		"posts/sidebar.html": {Data: []byte(`{% include card.html title=include.heading %}`)},
+	})
+	engine.EnableJekyllExtensions()
+
+	tpl, err := engine.ParseTemplateLocation([]byte(`{% include_relative sidebar.html heading=page.title %}`), "posts/post.html", 1)
+	require.NoError(t, err)
+
+	out, err := tpl.RenderString(Bindings{"page": Bindings{"title": "Hello"}})
+	require.NoError(t, err)
+	require.Equal(t, "<h2>Hello</h2>", out)
+}
+
+func TestEngine_SetPartialCache(t *testing.T) {
+	fsys := fstest.MapFS{
+		"card.liquid": {Data: []byte("[{{ name }}]"), ModTime: time.Unix(1, 0)},
+	}
+	cache := NewPartialCache(10)
+	engine := NewEngine()
+	engine.RegisterFS(fsys)
+	engine.SetPartialCache(cache)
+
+	tpl, err := engine.ParseString(`{% render "card.liquid", name: name %}`)
+	require.NoError(t, err)
+	out, err := tpl.RenderString(Bindings{"name": "a"})
+	require.NoError(t, err)
+	require.Equal(t, "[a]", out)
+	require.Equal(t, 1, cache.Len())
+
+	// The modification time is unchanged, so the cached partial is used.
+	fsys["card.liquid"].Data = []byte("<{{ name }}>")
+	out, err = tpl.RenderString(Bindings{"name": "b"})
+	require.NoError(t, err)
+	require.Equal(t, "[b]", out)
+
+	cache.Invalidate("card.liquid")
+	out, err = tpl.RenderString(Bindings{"name": "c"})
+	require.NoError(t, err)
+	require.Equal(t, "<c>", out)
+
+	// Changing the engine's tags clears the cache.
+	engine.RegisterTag("custom", func(render.Context) (string, error) { return "", nil })
+	require.Equal(t, 0, cache.Len())
+}
+
+func TestEngine_concurrentConfiguration(t *testing.T) {
+	engine := NewEngine()
+	tpl, err := engine.ParseString(`{% include "cached.liquid" %}{{ "a" | upcase }}`)
+	require.NoError(t, err)
+	_, err = engine.ParseTemplateAndCache([]byte(`{{ 1 }}`), "cached.liquid", 1)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				name := fmt.Sprintf("f%d_%d", i, j)
+				engine.RegisterFilter(name, strings.ToUpper)
+				engine.RegisterTag(name, func(render.Context) (string, error) { return "", nil })
+				engine.UnregisterTag(name)
+				_, err := engine.ParseTemplateAndCache([]byte(`{{ 1 }}`), name, 1)
+				require.NoError(t, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				out, err := tpl.RenderString(Bindings{})
+				require.NoError(t, err)
+				require.Equal(t, "1A", out)
+
+				out, err = engine.ParseAndRenderString(`{% assign x = "b" %}{{ x | upcase }}`, Bindings{})
+				require.NoError(t, err)
+				require.Equal(t, "B", out)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Templates use the engine's configuration as of the start of each render.
+	engine.RegisterFilter("late", func(s string) string { return s + "!" })
+	out, err := tpl.RenderString(Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "1A", out)
+	out, err = engine.ParseAndRenderString(`{{ "x" | late }}`, Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "x!", out)
+}
+
+func TestEngine_Clone(t *testing.T) {
+	base := NewEngine()
+	base.RegisterFilter("shout", func(s string) string { return strings.ToUpper(s) + "!" })
+	base.SetPartialCache(NewPartialCache(5))
+	_, err := base.ParseTemplateAndCache([]byte(`{{ x | shout }}`), "partial", 1)
+	require.NoError(t, err)
+
+	email := base.Clone()
+	email.SetAutoEscapeReplacer(render.HtmlEscaper)
+	email.RegisterFilter("whisper", strings.ToLower)
+	email.RegisterTag("sig", func(render.Context) (string, error) { return "--", nil })
+	strict := base.Clone()
+	strict.StrictVariables()
+	base.RegisterFilter("late", strings.TrimSpace)
+
+	bindings := Bindings{"x": "<b>"}
+	out, err := email.ParseAndRenderString(`{% include "partial" %}{{ x }}{{ "A" | whisper }}{% sig %}`, bindings)
+	require.NoError(t, err)
+	require.Equal(t, "&lt;B&gt;!&lt;b&gt;a--", out)
+
+	out, err = base.ParseAndRenderString(`{% include "partial" %}{{ x }}`, bindings)
+	require.NoError(t, err)
+	require.Equal(t, "<B>!<b>", out)
+
+	_, err = base.ParseAndRenderString(`{{ "A" | whisper }}`, bindings)
+	require.Error(t, err)
+	_, err = base.ParseAndRenderString(`{% sig %}`, bindings)
+	require.Error(t, err)
+	_, err = email.ParseAndRenderString(`{{ " a " | late }}`, bindings)
+	require.Error(t, err)
+
+	_, err = strict.ParseAndRenderString(`{{ missing }}`, bindings)
+	require.Error(t, err)
+	_, err = base.ParseAndRenderString(`{{ missing }}`, bindings)
+	require.NoError(t, err)
+
+	require.NotSame(t, base.config().PartialCache, email.config().PartialCache)
+	require.Equal(t, 5, email.config().PartialCache.MaxEntries())
+}
+
+func TestEngine_registers(t *testing.T) {
+	engine := NewEngine()
+	engine.RegisterFilter("asset_url", func(ctx context.Context, path string) string {
+		return fmt.Sprintf("https://%s/%s", RegistersFromContext(ctx)["cdn"], path)
+	})
+	engine.RegisterTag("visit", func(ctx render.Context) (string, error) {
+		ctx.(render.RegistersProvider).Registers()["visited"] = true
+		return "", nil
+	})
+
+	tpl, err := engine.ParseString(`{% visit %}{{ "a.css" | asset_url }} {{ cdn }}`)
+	require.NoError(t, err)
+
+	registers := map[string]any{"cdn": "cdn.example.com"}
+	out, err := tpl.RenderContext(WithRegisters(context.Background(), registers), Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "https://cdn.example.com/a.css ", string(out))
+	require.Equal(t, true, registers["visited"])
+}
+
+func TestEngine_RegisterFilter_context(t *testing.T) {
+	translations := map[string]map[string]string{"fr": {"hello": "bonjour"}}
+	engine := NewEngine()
+	engine.RegisterFilter("t", func(ctx render.Context, key string) string {
+		locale, _ := ctx.Get("locale").(string)
+		return translations[locale][key]
+	})
+	engine.RegisterFilter("prefix", func(ctx expressions.Context, s string, sep string) string {
+		return fmt.Sprint(ctx.Get("prefix")) + sep + s
+	})
+
+	out, err := engine.ParseAndRenderString(
+		`{{ "hello" | t | prefix: "-" }}{% assign locale = "en" %}{{ "hello" | t }}`,
+		Bindings{"locale": "fr", "prefix": ">"})
+	require.NoError(t, err)
+	require.Equal(t, ">-bonjour", out)
+}
+
 func TestEngine_LaxFilters(t *testing.T) {
 	// Default: undefined filters cause an error
 	engine := NewEngine()
@@ -215,6 +484,125 @@ func TestEngine_LaxFilters(t *testing.T) {
 	require.Equal(t, "HELLO", out)
 }
 
+func TestEngine_ErrorMode(t *testing.T) {
+	source := "a{{ x | }}b{% undefined_tag %}c{{ 1 | undefined_filter }}d{% for i in (1..3) %}{% if i == 2 %}{% break %}{% endif %}{{ i }}{% endfor %}"
+
+	engine := NewEngine()
+	_, err := engine.ParseString(source)
+	require.Error(t, err)
+
+	engine.ErrorMode(Lax)
+	tpl, err := engine.ParseString(source)
+	require.NoError(t, err)
+	require.Empty(t, tpl.Warnings())
+	out, err := tpl.RenderString(emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, `abcLiquid error: undefined filter "undefined_filter"d1`, out)
+
+	engine.ErrorMode(Warn)
+	tpl, err = engine.ParseTemplateLocation([]byte(source), "page.html", 1)
+	require.NoError(t, err)
+	require.Len(t, tpl.Warnings(), 2)
+	require.Contains(t, tpl.Warnings()[0].Error(), "syntax error")
+	require.Equal(t, "page.html", tpl.Warnings()[0].Path())
+	require.Contains(t, tpl.Warnings()[1].Error(), `undefined tag "undefined_tag"`)
+	out, err = tpl.RenderString(emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, `abcLiquid error (line 1): undefined filter "undefined_filter"d1`, out)
+
+	// block structure errors are not recoverable
+	_, err = engine.ParseString(`{% if true %}`)
+	require.Error(t, err)
+}
+
+func TestEngine_ErrorMode_filters(t *testing.T) {
+	tests := []struct{ in, expected string }{
+		{`{{ x | }}`, "abc"},
+		{`{{ x | upcase | }}`, "ABC"},
+		{`{{ x | upcase | 123 | downcase }}`, "ABC"},
+		{`{{ x | append: 'a|b' | f: ) }}`, "abca|b"},
+		{`{{ | upcase }}`, ""},
+	}
+
+	engine := NewEngine()
+	engine.ErrorMode(Lax)
+
+	for _, test := range tests {
+		out, err := engine.ParseAndRenderString(test.in, Bindings{"x": "abc"})
+		require.NoErrorf(t, err, test.in)
+		require.Equalf(t, test.expected, out, test.in)
+	}
+
+	engine.ErrorMode(Warn)
+	tpl, err := engine.ParseString(`{{ x | upcase | }}`)
+	require.NoError(t, err)
+	require.Len(t, tpl.Warnings(), 1)
+	out, err := tpl.RenderString(Bindings{"x": "abc"})
+	require.NoError(t, err)
+	require.Equal(t, "ABC", out)
+}
+
+func TestEngine_SetLimits(t *testing.T) {
+	var limitErr LimitError
+
+	engine := NewEngine()
+	engine.SetLimits(Limits{MaxOutputBytes: 10})
+	out, err := engine.ParseAndRenderString(`{{ "0123456789" }}`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", out)
+	_, err = engine.ParseAndRenderString(`{{ "0123456789" }}{{ "x" }}`, emptyBindings)
+	require.Error(t, err)
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, "output size", limitErr.Limit)
+
+	engine = NewEngine()
+	engine.SetLimits(Limits{MaxIterations: 5})
+	_, err = engine.ParseAndRenderString(`{% for i in (1..3) %}{% tablerow j in (1..2) %}{% endtablerow %}{% endfor %}`, emptyBindings)
+	require.Error(t, err)
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, "loop iteration", limitErr.Limit)
+	require.Contains(t, err.Error(), "{% tablerow")
+
+	engine = NewEngine()
+	engine.SetLimits(Limits{MaxRangeSize: 100})
+	_, err = engine.ParseAndRenderString(`{% for i in (1..100) %}{% endfor %}`, emptyBindings)
+	require.NoError(t, err)
+	_, err = engine.ParseAndRenderString(`{% for i in (1..101) %}{% endfor %}`, emptyBindings)
+	require.Error(t, err)
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, "range size", limitErr.Limit)
+	require.Contains(t, err.Error(), "{% for i in (1..101) %}")
+
+	// limit errors are not rendered inline in lax mode
+	engine.ErrorMode(Lax)
+	_, err = engine.ParseAndRenderString(`{{ (1..101) | join }}`, emptyBindings)
+	require.Error(t, err)
+	require.True(t, errors.As(err, &limitErr))
+}
+
+func TestEngine_recursivePartials(t *testing.T) {
+	var limitErr LimitError
+
+	for _, tag := range []string{"include", "render"} {
+		t.Run(tag, func(t *testing.T) {
+			engine := NewEngine()
+			_, err := engine.ParseTemplateAndCache([]byte(`x{% `+tag+` 'self' %}`), "self", 1)
+			require.NoError(t, err)
+			_, err = engine.ParseAndRenderString(`{% `+tag+` 'self' %}`, emptyBindings)
+			require.Error(t, err)
+			require.True(t, errors.As(err, &limitErr))
+			require.Equal(t, "include depth", limitErr.Limit)
+			require.Equal(t, render.DefaultMaxIncludeDepth, limitErr.Max)
+			require.Equal(t, "self", err.Path())
+
+			engine.SetLimits(Limits{MaxIncludeDepth: 3})
+			_, err = engine.ParseAndRenderString(`{% `+tag+` 'self' %}`, emptyBindings)
+			require.True(t, errors.As(err, &limitErr))
+			require.Equal(t, 3, limitErr.Max)
+		})
+	}
+}
+
 func TestEngine_Delims(t *testing.T) {
 	engine := NewEngine()
 	engine.Delims("<%=", "%>", "<%", "%>")
@@ -329,3 +717,52 @@ func TestEngine_UnregisterTag(t *testing.T) {
 	_, err = engine.ParseAndRenderString(source, emptyBindings)
 	require.Error(t, err)
 }
+
+func TestEngine_Format(t *testing.T) {
+	engine := NewEngine()
+	src := "{%case x%}\n{%when 1,2%}\n<pre>\n  one\n</pre>\n{%else%}\n  {%-for i in (1..3)-%}\n{{i|times:2}}\n{%-endfor-%}\n{%endcase%}\n{%liquid assign y=x|plus:1\n  echo y%}"
+	expected := "{% case x %}\n{% when 1, 2 %}\n<pre>\n  one\n</pre>\n{% else %}\n  {%- for i in (1..3) -%}\n{{ i | times: 2 }}\n{%- endfor -%}\n{% endcase %}\n{% liquid\n  assign y = x | plus: 1\n  echo y\n%}"
+
+	out, err := engine.Format([]byte(src))
+	require.NoError(t, err)
+	require.Equal(t, expected, string(out))
+
+	// formatting keeps the rendered output
+	for _, x := range []int{1, 3} {
+		before, err := engine.ParseAndRenderString(src, Bindings{"x": x})
+		require.NoError(t, err)
+		after, err := engine.ParseAndRenderString(string(out), Bindings{"x": x})
+		require.NoError(t, err)
+		require.Equal(t, before, after)
+	}
+
+	_, err = engine.Format([]byte("{% if x %}"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unterminated")
+
+	out, err = engine.Delims("((", "))", "(%", "%)").Format([]byte("(%if x%)((x))(%endif%)"))
+	require.NoError(t, err)
+	require.Equal(t, "(% if x %)(( x ))(% endif %)", string(out))
+
+	out, fmtErr := Format([]byte("{{x}}"))
+	require.NoError(t, fmtErr)
+	require.Equal(t, "{{ x }}", string(out))
+
+	out, fmtErr = Format([]byte("{% endif %}"))
+	require.Error(t, fmtErr)
+	require.Nil(t, out)
+}
+
+func TestEngine_liquidTag(t *testing.T) {
+	engine := NewEngine()
+	engine.SetAutoEscapeReplacer(render.HtmlEscaper)
+
+	src := "{%- liquid\n  assign items = list | split: ','\n  for item in items\n    echo item | upcase\n  endfor\n  increment n\n-%} {% increment n %}{% echo html %}"
+	out, err := engine.ParseAndRenderString(src, map[string]any{"list": "a,b", "html": "<b>"})
+	require.NoError(t, err)
+	require.Equal(t, "AB01&lt;b&gt;", out)
+
+	_, err = engine.ParseTemplateLocation([]byte("\n{% liquid\n  assign a = 1\n  if a\n    undefined_tag\n  endif\n%}"), "page.html", 1)
+	require.Error(t, err)
+	require.Equal(t, 5, err.LineNumber())
+}
@@ -0,0 +1,143 @@
+package expressions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/osteele/liquid/ast"
+)
+
+// A VariablePath is a reference to a variable, followed by the properties and
+// indices that are read from it. For example, the expression
+// "product.variants[0].title" reads the path {"product", "variants", 0, "title"}.
+//
+// The first element is the variable name. Subsequent elements are strings
+// (property names and string-literal indices) or ints (integer-literal
+// indices). A path ends before the first index that is not a literal; the
+// variables in that index are reported as separate paths.
+type VariablePath []any
+
+var identifierRe = regexp.MustCompile(`^[a-zA-Z_][\w-]*\??$`)
+
+// Name returns the variable name, or "" if the path is empty.
+func (p VariablePath) Name() string {
+	if len(p) == 0 {
+		return ""
+	}
+
+	name, _ := p[0].(string)
+
+	return name
+}
+
+// String returns the path in Liquid syntax, e.g. "product.variants[0].title".
+func (p VariablePath) String() string {
+	var b strings.Builder
+
+	for i, elt := range p {
+		switch elt := elt.(type) {
+		case string:
+			switch {
+			case i == 0:
+				b.WriteString(elt)
+			case identifierRe.MatchString(elt):
+				b.WriteByte('.')
+				b.WriteString(elt)
+			default:
+				fmt.Fprintf(&b, "[%q]", elt)
+			}
+		default:
+			fmt.Fprintf(&b, "[%v]", elt)
+		}
+	}
+
+	return b.String()
+}
+
+// Analyze returns the variable paths that an expression reads and the names
+// of the filters that it applies, in the order in which they appear in the
+// source. It returns nil slices for an expression without a syntax tree;
+// see SyntaxTree.
+func Analyze(expr Expression) (variables []VariablePath, filters []string) {
+	n := SyntaxTree(expr)
+	if n == nil {
+		return nil, nil
+	}
+
+	var a analyzer
+	a.visit(n)
+
+	return a.variables, a.filters
+}
+
+type analyzer struct {
+	variables []VariablePath
+	filters   []string
+}
+
+func (a *analyzer) visit(n ast.Expr) {
+	switch n := n.(type) {
+	case *ast.Variable, *ast.Property, *ast.Index:
+		if path := a.path(n); path != nil {
+			a.variables = append(a.variables, path)
+		}
+	case *ast.Range:
+		a.visit(n.Start)
+		a.visit(n.End)
+	case *ast.Filter:
+		a.visit(n.Input)
+		a.filters = append(a.filters, n.Name)
+
+		for _, arg := range n.Args {
+			a.visit(arg)
+		}
+
+		for _, kw := range n.KeywordArgs {
+			a.visit(kw.Value)
+		}
+	case *ast.Call:
+		for _, arg := range n.Args {
+			a.visit(arg)
+		}
+
+		for _, kw := range n.KeywordArgs {
+			a.visit(kw.Value)
+		}
+	case *ast.BinaryOp:
+		a.visit(n.Left)
+		a.visit(n.Right)
+	}
+}
+
+// path returns the variable path that n reads, or nil if n does not read a
+// path that starts at a variable. Variables that are read by n but are not
+// part of the returned path are recorded by the analyzer.
+func (a *analyzer) path(n ast.Expr) VariablePath {
+	switch n := n.(type) {
+	case *ast.Variable:
+		return VariablePath{n.Name}
+	case *ast.Property:
+		if path := a.path(n.Object); path != nil {
+			return append(path, n.Name)
+		}
+	case *ast.Index:
+		path := a.path(n.Object)
+		if path != nil {
+			if lit, ok := n.Index.(*ast.Literal); ok {
+				switch key := lit.Value.(type) {
+				case string, int:
+					return append(path, key)
+				}
+			}
+
+			a.variables = append(a.variables, path)
+		}
+
+		a.visit(n.Index)
+	default:
+		a.visit(n)
+	}
+
+	return nil
+}
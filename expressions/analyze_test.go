@@ -0,0 +1,101 @@
+package expressions
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/ast"
+)
+
+var analyzeTests = []struct {
+	in        string
+	variables []string
+	filters   []string
+}{
+	{`12`, nil, nil},
+	{`x`, []string{"x"}, nil},
+	{`product.title`, []string{"product.title"}, nil},
+	{`product.variants[0].title`, []string{"product.variants[0].title"}, nil},
+	{`hash["b"].c`, []string{"hash.b.c"}, nil},
+	{`hash["a b"]`, []string{`hash["a b"]`}, nil},
+	{`array[i].name`, []string{"array", "i"}, nil},
+	{`array[page.index]`, []string{"array", "page.index"}, nil},
+	{`(1..range.end)`, []string{"range.end"}, nil},
+	{`a == b.c and d`, []string{"a", "b.c", "d"}, nil},
+	{`title | upcase`, []string{"title"}, []string{"upcase"}},
+	{`title | truncate: n, ellipsis: e | append: "!"`, []string{"title", "n", "e"}, []string{"truncate", "append"}},
+	{`"abc" | size`, nil, []string{"size"}},
+	{`price(p.amount, currency: (c | upcase))`, []string{"p.amount", "c"}, []string{"upcase"}},
+}
+
+func TestAnalyze(t *testing.T) {
+	for i, test := range analyzeTests {
+		t.Run(fmt.Sprint(i+1), func(t *testing.T) {
+			expr, err := Parse(test.in)
+			require.NoErrorf(t, err, test.in)
+
+			variables, filters := Analyze(expr)
+			var paths []string
+			for _, v := range variables {
+				paths = append(paths, v.String())
+			}
+			require.Equalf(t, test.variables, paths, test.in)
+			require.Equalf(t, test.filters, filters, test.in)
+		})
+	}
+
+	variables, filters := Analyze(Constant(1))
+	require.Nil(t, variables)
+	require.Nil(t, filters)
+}
+
+func TestAnalyze_statements(t *testing.T) {
+	stmt, err := ParseStatement(LoopStatementSelector, "item in products limit: n reversed")
+	require.NoError(t, err)
+
+	variables, _ := Analyze(stmt.Loop.Expr)
+	require.Equal(t, []VariablePath{{"products"}}, variables)
+	variables, _ = Analyze(stmt.Limit)
+	require.Equal(t, []VariablePath{{"n"}}, variables)
+}
+
+func TestVariablePath(t *testing.T) {
+	path := VariablePath{"product", "variants", 0, "title"}
+	require.Equal(t, "product", path.Name())
+	require.Equal(t, "product.variants[0].title", path.String())
+	require.Equal(t, "", VariablePath{}.Name())
+}
+
+func TestSyntaxTree_String(t *testing.T) {
+	for _, test := range evaluatorTests {
+		expr, err := Parse(test.in)
+		require.NoErrorf(t, err, test.in)
+
+		// The printed tree parses to the same tree.
+		src := SyntaxTree(expr).String()
+		reparsed, err := Parse(src)
+		require.NoErrorf(t, err, "%s → %s", test.in, src)
+		require.Equalf(t, SyntaxTree(expr), SyntaxTree(reparsed), "%s → %s", test.in, src)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	expr, err := Parse(`a | plus: 1`)
+	require.NoError(t, err)
+
+	tree := SyntaxTree(expr).(*ast.Filter)
+	tree.Input = &ast.Variable{Name: "b"}
+	compiled, err := Compile(tree)
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.AddFilter("plus", func(a, b int) int { return a + b })
+	value, err := compiled.Evaluate(NewContext(map[string]any{"a": 1, "b": 10}, cfg))
+	require.NoError(t, err)
+	require.Equal(t, 11, value)
+
+	_, err = Compile(&ast.BinaryOp{Op: "xor", Left: tree, Right: tree})
+	require.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package expressions
+
+import (
+	"fmt"
+
+	"github.com/osteele/liquid/ast"
+	"github.com/osteele/liquid/values"
+)
+
+// SyntaxTree returns the syntax tree of an expression that was created by
+// Parse or ParseStatement. It returns nil for other expressions, such as
+// those created by Constant and Not.
+func SyntaxTree(expr Expression) ast.Expr {
+	if e, ok := expr.(*expression); ok {
+		return e.node
+	}
+
+	return nil
+}
+
+// Compile compiles an expression syntax tree, such as one that has been
+// modified after it was returned by SyntaxTree, into an Expression.
+func Compile(n ast.Expr) (Expression, error) {
+	ev, err := compile(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return &expression{evaluator: ev, node: n}, nil
+}
+
+// newExpression compiles a syntax tree that the parser produced. The parser
+// recovers the SyntaxError that it panics with for an invalid tree.
+func newExpression(n ast.Expr) *expression {
+	ev, err := compile(n)
+	if err != nil {
+		panic(err)
+	}
+
+	return &expression{evaluator: ev, node: n}
+}
+
+func compile(n ast.Expr) (evaluator, error) {
+	switch n := n.(type) {
+	case *ast.Literal:
+		return &literalExpr{values.ValueOf(n.Value)}, nil
+	case *ast.Variable:
+		return &variableExpr{n.Name}, nil
+	case *ast.Property:
+		object, err := compile(n.Object)
+		if err != nil {
+			return nil, err
+		}
+
+		return &propertyExpr{object, values.ValueOf(n.Name)}, nil
+	case *ast.Index:
+		object, index, err := compilePair(n.Object, n.Index)
+		if err != nil {
+			return nil, err
+		}
+
+		return &indexExpr{object, index}, nil
+	case *ast.Range:
+		start, end, err := compilePair(n.Start, n.End)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rangeExpr{start, end}, nil
+	case *ast.Filter:
+		return compileFilter(n)
+	case *ast.Call:
+		return compileCall(n)
+	case *ast.BinaryOp:
+		return compileBinaryOp(n)
+	default:
+		return nil, SyntaxError(fmt.Sprintf("unexpected expression node %T", n))
+	}
+}
+
+func compilePair(a, b ast.Expr) (evaluator, evaluator, error) {
+	ea, err := compile(a)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	eb, err := compile(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ea, eb, nil
+}
+
+func compileArgs(args []ast.Expr, kwargs []ast.KeywordArg) ([]evaluator, []keywordArg, error) {
+	var (
+		positional []evaluator
+		keyword    []keywordArg
+	)
+
+	for _, arg := range args {
+		ev, err := compile(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		positional = append(positional, ev)
+	}
+
+	for _, kw := range kwargs {
+		ev, err := compile(kw.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keyword = append(keyword, keywordArg{kw.Name, ev})
+	}
+
+	return positional, keyword, nil
+}
+
+func compileFilter(n *ast.Filter) (evaluator, error) {
+	input, err := compile(n.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	positional, keyword, err := compileArgs(n.Args, n.KeywordArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var args *filterArgs
+	if len(positional) > 0 || len(keyword) > 0 {
+		args = &filterArgs{positional, keyword}
+	}
+
+	return &filterExpr{input, n.Name, args}, nil
+}
+
+func compileCall(n *ast.Call) (evaluator, error) {
+	positional, keyword, err := compileArgs(n.Args, n.KeywordArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &callExpr{n.Name, positional, keyword}, nil
+}
+
+func compileBinaryOp(n *ast.BinaryOp) (evaluator, error) {
+	left, right, err := compilePair(n.Left, n.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Op {
+	case "and", "or":
+		return &logicalExpr{n.Op == "or", left, right}, nil
+	}
+
+	op, ok := compareOps[n.Op]
+	if !ok {
+		return nil, SyntaxError("undefined operator " + n.Op)
+	}
+
+	return &compareExpr{op, left, right}, nil
+}
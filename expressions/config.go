@@ -1,13 +1,27 @@
 package expressions
 
+import "maps"
+
 // Config holds configuration information for expression interpretation.
 type Config struct {
 	filters         map[string]any
 	LaxFilters      bool
 	StrictVariables bool
+	// MaxRangeSize limits the number of elements in a range literal such as (1..n).
+	// Zero means no limit.
+	MaxRangeSize int
 }
 
 // NewConfig creates a new Config.
 func NewConfig() Config {
 	return Config{}
 }
+
+// Clone returns a copy of the configuration whose filters can be changed
+// without affecting c.
+func (c *Config) Clone() Config {
+	clone := *c
+	clone.filters = maps.Clone(c.filters)
+
+	return clone
+}
@@ -1,10 +1,15 @@
 package expressions
 
-import "github.com/osteele/liquid/values"
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/osteele/liquid/values"
+)
 
 // Context is the expression evaluation context. It maps variables names to values.
 type Context interface {
-	ApplyFilter(string, valueFn, *filterArgs) (any, error)
+	ApplyFilter(string, evaluator, *filterArgs) (any, error)
 	// Clone returns a copy with a new variable binding map
 	// (so that copy.Set does effect the source context.)
 	Clone() Context
@@ -12,15 +17,72 @@ type Context interface {
 	Set(string, any)
 }
 
+// FilterArguments supplies the leading argument of the filters that take an
+// environment, such as a context.Context, before their input. A filter whose
+// first parameter has type t receives the value that FilterArgument returns
+// for t, if it returns true.
+type FilterArguments interface {
+	FilterArgument(t reflect.Type) (any, bool)
+}
+
+// A Caller calls the functions, such as macros, that expressions call by
+// name; for example "price(product.price, currency: 'EUR')".
+type Caller interface {
+	CallFunction(name string, args []any, kwargs map[string]any) (any, error)
+}
+
+// Counters holds the increment and decrement counters. A counter is the
+// value of a variable that neither the bindings nor the scope defines.
+type Counters interface {
+	Counter(name string) (int, bool)
+}
+
 type context struct {
 	Config
 
-	bindings map[string]any
+	bindings   map[string]any
+	filterArgs FilterArguments
+	scope      values.Value // looked up for variables that bindings doesn't define
+	caller     Caller
+	counters   Counters // looked up for variables that bindings and scope don't define
 }
 
 // NewContext makes a new expression evaluation context.
 func NewContext(vars map[string]any, cfg Config) Context {
-	return &context{cfg, vars}
+	return &context{Config: cfg, bindings: vars}
+}
+
+// SetFilterArguments sets the source of the environment arguments of
+// filters.
+func (ctx *context) SetFilterArguments(args FilterArguments) {
+	ctx.filterArgs = args
+}
+
+// SetScope sets a map or struct value whose properties are the variables that
+// the bindings don't define. The bindings overlay the scope, so Set doesn't
+// modify it.
+func (ctx *context) SetScope(scope values.Value) {
+	ctx.scope = scope
+}
+
+// SetCounters sets the counters that are looked up for the variables that
+// the bindings and the scope don't define.
+func (ctx *context) SetCounters(counters Counters) {
+	ctx.counters = counters
+}
+
+// SetCaller sets the implementation of the functions that expressions call.
+func (ctx *context) SetCaller(caller Caller) {
+	ctx.caller = caller
+}
+
+// CallFunction calls a function with the caller that SetCaller set.
+func (ctx *context) CallFunction(name string, args []any, kwargs map[string]any) (any, error) {
+	if ctx.caller == nil {
+		return nil, InterpreterError(fmt.Sprintf("undefined function %q", name))
+	}
+
+	return ctx.caller.CallFunction(name, args, kwargs)
 }
 
 func (ctx *context) Clone() Context {
@@ -29,17 +91,38 @@ func (ctx *context) Clone() Context {
 		bindings[k] = v
 	}
 
-	return &context{ctx.Config, bindings}
+	return &context{ctx.Config, bindings, ctx.filterArgs, ctx.scope, ctx.caller, ctx.counters}
 }
 
 // Get looks up a variable value in the expression context.
 func (ctx *context) Get(name string) any {
+	value, err := ctx.lookup(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return value
+}
+
+// lookup is Get, with an error for an undefined variable when variables are
+// strict.
+func (ctx *context) lookup(name string) (any, error) {
 	value, ok := ctx.bindings[name]
+	if !ok && ctx.scope != nil {
+		value, ok = values.Property(ctx.scope, name)
+	}
+
+	if !ok && ctx.counters != nil {
+		if n, isCounter := ctx.counters.Counter(name); isCounter {
+			value, ok = n, true
+		}
+	}
+
 	if !ok && ctx.Config.StrictVariables {
-		panic(InterpreterError("undefined variable"))
+		return nil, InterpreterError("undefined variable")
 	}
 
-	return values.ToLiquid(value)
+	return values.ToLiquid(value), nil
 }
 
 // Set sets a variable value in the expression context.
@@ -55,3 +138,16 @@ func strictVariables(ctx Context) bool {
 	configured, ok := ctx.(interface{ StrictVariables() bool })
 	return ok && configured.StrictVariables()
 }
+
+func (ctx *context) MaxRangeSize() int {
+	return ctx.Config.MaxRangeSize
+}
+
+func maxRangeSize(ctx Context) int {
+	configured, ok := ctx.(interface{ MaxRangeSize() int })
+	if !ok {
+		return 0
+	}
+
+	return configured.MaxRangeSize()
+}
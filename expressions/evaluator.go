@@ -0,0 +1,282 @@
+package expressions
+
+import (
+	"fmt"
+
+	"github.com/osteele/liquid/values"
+)
+
+// An evaluator is a node of a compiled expression. The nodes mirror the
+// syntax tree, with the work that doesn't depend on the context, such as
+// wrapping a literal in a Value, done at compile time.
+//
+// Eval returns the error that stops the evaluation, instead of panicking,
+// so that a failed expression costs no more than one that succeeds.
+type evaluator interface {
+	eval(ctx Context) (values.Value, error)
+}
+
+type literalExpr struct {
+	val values.Value
+}
+
+func (e *literalExpr) eval(Context) (values.Value, error) {
+	return e.val, nil
+}
+
+type variableExpr struct {
+	name string
+}
+
+func (e *variableExpr) eval(ctx Context) (values.Value, error) {
+	// The package's context reports an undefined variable as an error. Other
+	// implementations of Context can only panic.
+	if c, ok := ctx.(*context); ok {
+		value, err := c.lookup(e.name)
+		if err != nil {
+			return nil, err
+		}
+
+		return values.ValueOf(value), nil
+	}
+
+	return values.ValueOf(ctx.Get(e.name)), nil
+}
+
+type propertyExpr struct {
+	object evaluator
+	name   values.Value
+}
+
+func (e *propertyExpr) eval(ctx Context) (values.Value, error) {
+	object, err := e.object.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkDefined(ctx, object.PropertyValue(e.name))
+}
+
+type indexExpr struct {
+	object, index evaluator
+}
+
+func (e *indexExpr) eval(ctx Context) (values.Value, error) {
+	index, err := e.index.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := e.object.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkDefined(ctx, object.IndexValue(index))
+}
+
+// checkDefined returns an error for an undefined property or element, if the
+// context has strict variables.
+func checkDefined(ctx Context, value values.Value) (values.Value, error) {
+	if values.IsUndefined(value) && strictVariables(ctx) {
+		return nil, InterpreterError("undefined variable")
+	}
+
+	return value, nil
+}
+
+type rangeExpr struct {
+	start, end evaluator
+}
+
+func (e *rangeExpr) eval(ctx Context) (values.Value, error) {
+	a, err := evalInt(ctx, e.start)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := evalInt(ctx, e.end)
+	if err != nil {
+		return nil, err
+	}
+
+	r := values.NewRange(a, b)
+	if limit := maxRangeSize(ctx); limit > 0 && r.Len() > limit {
+		return nil, LimitError{"range size", limit}
+	}
+
+	return values.ValueOf(r), nil
+}
+
+// evalInt evaluates a range bound, which must be an int, as for Value.Int.
+func evalInt(ctx Context, e evaluator) (int, error) {
+	v, err := e.eval(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n := v.Interface().(type) {
+	case int:
+		return n, nil
+	default:
+		return 0, values.TypeError(fmt.Sprintf("can't convert %T(%v) to type int", n, n))
+	}
+}
+
+// filterArgs holds both positional and keyword arguments for a filter.
+type filterArgs struct {
+	positional []evaluator
+	keyword    []keywordArg
+}
+
+// keywordArg represents a named argument (e.g., allow_false: true).
+type keywordArg struct {
+	name string
+	val  evaluator
+}
+
+type filterExpr struct {
+	input evaluator
+	name  string
+	args  *filterArgs
+}
+
+func (e *filterExpr) eval(ctx Context) (values.Value, error) {
+	result, err := ctx.ApplyFilter(e.name, e.input, e.args)
+	if err != nil {
+		return nil, err
+	}
+
+	return values.ValueOf(result), nil
+}
+
+type callExpr struct {
+	name   string
+	args   []evaluator
+	kwargs []keywordArg
+}
+
+func (e *callExpr) eval(ctx Context) (values.Value, error) {
+	caller, ok := ctx.(Caller)
+	if !ok {
+		return nil, InterpreterError(fmt.Sprintf("undefined function %q", e.name))
+	}
+
+	positional := make([]any, len(e.args))
+	for i, arg := range e.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		positional[i] = v.Interface()
+	}
+
+	var keyword map[string]any
+	if len(e.kwargs) > 0 {
+		keyword = make(map[string]any, len(e.kwargs))
+		for _, kw := range e.kwargs {
+			v, err := kw.val.eval(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			keyword[kw.name] = v.Interface()
+		}
+	}
+
+	// The error is returned as is, so that an error that has a source
+	// location, such as one from the body of a macro, keeps it.
+	value, err := caller.CallFunction(e.name, positional, keyword)
+	if err != nil {
+		return nil, err
+	}
+
+	return values.ValueOf(value), nil
+}
+
+// A compareOp is a binary operator that evaluates both of its operands.
+type compareOp int
+
+const (
+	opEqual compareOp = iota
+	opNotEqual
+	opGreater
+	opLess
+	opGreaterOrEqual
+	opLessOrEqual
+	opContains
+)
+
+var compareOps = map[string]compareOp{
+	"==":       opEqual,
+	"!=":       opNotEqual,
+	">":        opGreater,
+	"<":        opLess,
+	">=":       opGreaterOrEqual,
+	"<=":       opLessOrEqual,
+	"contains": opContains,
+}
+
+type compareExpr struct {
+	op          compareOp
+	left, right evaluator
+}
+
+func (e *compareExpr) eval(ctx Context) (values.Value, error) {
+	a, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result bool
+
+	switch e.op {
+	case opEqual:
+		result = a.Equal(b)
+	case opNotEqual:
+		result = !a.Equal(b)
+	case opGreater:
+		result = b.Less(a)
+	case opLess:
+		result = a.Less(b)
+	case opGreaterOrEqual:
+		result = b.Less(a) || a.Equal(b)
+	case opLessOrEqual:
+		result = a.Less(b) || a.Equal(b)
+	case opContains:
+		result = a.Contains(b)
+	}
+
+	return values.ValueOf(result), nil
+}
+
+// A logicalExpr is an "and" or "or". The right operand is only evaluated if
+// the left one doesn't determine the result.
+type logicalExpr struct {
+	or          bool
+	left, right evaluator
+}
+
+func (e *logicalExpr) eval(ctx Context) (values.Value, error) {
+	a, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Test() == e.or {
+		return values.ValueOf(e.or), nil
+	}
+
+	b, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return values.ValueOf(b.Test()), nil
+}
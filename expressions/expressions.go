@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"runtime/debug"
 
+	"github.com/osteele/liquid/ast"
 	"github.com/osteele/liquid/values"
 )
 
@@ -44,10 +45,14 @@ func (c closure) Evaluate() (any, error) {
 }
 
 type expression struct {
-	evaluator func(Context) values.Value
+	evaluator evaluator
+	node      ast.Expr
 }
 
 func (e expression) Evaluate(ctx Context) (out any, err error) {
+	// The evaluator returns its errors. This recovers the errors that
+	// filters, the values package, drops, and implementations of Context
+	// panic with.
 	defer func() {
 		if r := recover(); r != nil {
 			switch e := r.(type) {
@@ -59,6 +64,8 @@ func (e expression) Evaluate(ctx Context) (out any, err error) {
 				err = e
 			case FilterError:
 				err = e
+			case LimitError:
+				err = e
 			case error:
 				panic(&rethrownError{e, debug.Stack()})
 			default:
@@ -67,7 +74,12 @@ func (e expression) Evaluate(ctx Context) (out any, err error) {
 		}
 	}()
 
-	return e.evaluator(ctx).Interface(), nil
+	v, err := e.evaluator.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Interface(), nil
 }
 
 // rethrownError is for use in a re-thrown error from panic recovery.
@@ -2,7 +2,7 @@
 package expressions
 import (
 	"fmt"
-	"github.com/osteele/liquid/values"
+	"github.com/osteele/liquid/ast"
 )
 
 func init() {
@@ -15,7 +15,7 @@ func init() {
 %union {
    name     string
    val      any
-   f        func(Context) values.Value
+   node     ast.Expr
    s        string
    ss       []string
    exprs    []Expression
@@ -23,10 +23,12 @@ func init() {
    cyclefn  func(string) Cycle
    loop     Loop
    loopmods loopModifiers
-   filter_params *filterArgs
+   filter_params *ast.Filter
+   call     *ast.Call
 }
-%type<f> expr rel filtered cond
+%type<node> expr rel filtered cond
 %type<filter_params> filter_params
+%type<call> call_args
 %type<exprs> exprs expr2
 %type<cycle> cycle
 %type<cyclefn> cycle2
@@ -49,7 +51,7 @@ start:
 	if len(path) == 1 {
 		variable = path[0]
 	}
-	yylex.(*lexer).Assignment = Assignment{Variable: variable, Path: path, ValueFn: &expression{$4}}
+	yylex.(*lexer).Assignment = Assignment{Variable: variable, Path: path, ValueFn: newExpression($4)}
 }
 | CYCLE cycle ';' { yylex.(*lexer).Cycle = $2 }
 | LOOP loop ';'   { yylex.(*lexer).Loop = $2 }
@@ -80,11 +82,11 @@ cycle3:
 | ',' string cycle3 { $$ = append([]string{$2}, $3...) }
 ;
 
-exprs: expr expr2 { $$ = append([]Expression{&expression{$1}}, $2...) } ;
+exprs: expr expr2 { $$ = append([]Expression{newExpression($1)}, $2...) } ;
 expr2:
   /* empty */    { $$ = []Expression{} }
-| ',' expr expr2 { $$ = append([]Expression{&expression{$2}}, $3...) }
-| OR expr expr2  { $$ = append([]Expression{&expression{$2}}, $3...) }
+| ',' expr expr2 { $$ = append([]Expression{newExpression($2)}, $3...) }
+| OR expr expr2  { $$ = append([]Expression{newExpression($2)}, $3...) }
 ;
 
 string: LITERAL {
@@ -97,7 +99,7 @@ string: LITERAL {
 
 loop: IDENTIFIER IN filtered loop_modifiers {
 	name, expr, mods := $1, $3, $4
-	$$ = Loop{mods, name, &expression{expr}}
+	$$ = Loop{mods, name, newExpression(expr)}
 }
 ;
 
@@ -114,11 +116,15 @@ loop_modifiers: /* empty */ { $$ = loopModifiers{} }
 | loop_modifiers KEYWORD expr {
     switch $2 {
 	case "cols":
-		$1.Cols = &expression{$3}
+		$1.Cols = newExpression($3)
 	case "limit":
-		$1.Limit = &expression{$3}
+		$1.Limit = newExpression($3)
 	case "offset":
-		$1.Offset = &expression{$3}
+		if v, ok := $3.(*ast.Variable); ok && v.Name == "continue" {
+			$1.Offset, $1.OffsetContinue = nil, true
+		} else {
+			$1.Offset, $1.OffsetContinue = newExpression($3), false
+		}
 	default:
 		panic(SyntaxError(fmt.Sprintf("undefined loop modifier %q", $2)))
 	}
@@ -127,86 +133,51 @@ loop_modifiers: /* empty */ { $$ = loopModifiers{} }
 ;
 
 expr:
-  LITERAL { val := values.ValueOf($1); $$ = func(Context) values.Value { return val } }
-| IDENTIFIER { name := $1; $$ = func(ctx Context) values.Value { return values.ValueOf(ctx.Get(name)) } }
-| expr PROPERTY { $$ = makeObjectPropertyExpr($1, $2) }
-| expr '[' expr ']' { $$ = makeIndexExpr($1, $3) }
-| '(' expr DOTDOT expr ')' { $$ = makeRangeExpr($2, $4) }
+  LITERAL { $$ = &ast.Literal{Value: $1} }
+| IDENTIFIER { $$ = &ast.Variable{Name: $1} }
+| IDENTIFIER '(' ')' { $$ = &ast.Call{Name: $1} }
+| IDENTIFIER '(' call_args ')' { $3.Name = $1; $$ = $3 }
+| expr PROPERTY { $$ = &ast.Property{Object: $1, Name: $2} }
+| expr '[' expr ']' { $$ = &ast.Index{Object: $1, Index: $3} }
+| '(' expr DOTDOT expr ')' { $$ = &ast.Range{Start: $2, End: $4} }
 | '(' cond ')' { $$ = $2 }
 ;
 
 filtered:
   expr
-| filtered '|' IDENTIFIER { $$ = makeFilter($1, $3, nil) }
-| filtered '|' KEYWORD filter_params { $$ = makeFilter($1, $3, $4) }
+| filtered '|' IDENTIFIER { $$ = &ast.Filter{Input: $1, Name: $3} }
+| filtered '|' KEYWORD filter_params { $4.Input, $4.Name = $1, $3; $$ = $4 }
 ;
 
 filter_params:
-  expr { $$ = &filterArgs{positional: []valueFn{$1}} }
+  expr { $$ = &ast.Filter{Args: []ast.Expr{$1}} }
 | filter_params ',' expr
-  { $1.positional = append($1.positional, $3); $$ = $1 }
+  { $1.Args = append($1.Args, $3); $$ = $1 }
 | filter_params ',' KEYWORD expr
-  { $1.keyword = append($1.keyword, keywordArg{$3, $4}); $$ = $1 }
+  { $1.KeywordArgs = append($1.KeywordArgs, ast.KeywordArg{Name: $3, Value: $4}); $$ = $1 }
+
+call_args:
+  expr { $$ = &ast.Call{Args: []ast.Expr{$1}} }
+| KEYWORD expr { $$ = &ast.Call{KeywordArgs: []ast.KeywordArg{{Name: $1, Value: $2}}} }
+| call_args ',' expr
+  { $1.Args = append($1.Args, $3); $$ = $1 }
+| call_args ',' KEYWORD expr
+  { $1.KeywordArgs = append($1.KeywordArgs, ast.KeywordArg{Name: $3, Value: $4}); $$ = $1 }
+;
 
 rel:
   filtered
-| expr EQ expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(a.Equal(b))
-	}
-}
-| expr NEQ expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(!a.Equal(b))
-	}
-}
-| expr '>' expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(b.Less(a))
-	}
-}
-| expr '<' expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(a.Less(b))
-	}
-}
-| expr GE expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(b.Less(a) || a.Equal(b))
-	}
-}
-| expr LE expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(a.Less(b) || a.Equal(b))
-	}
-}
-| expr CONTAINS expr { $$ = makeContainsExpr($1, $3) }
+| expr EQ expr { $$ = &ast.BinaryOp{Op: "==", Left: $1, Right: $3} }
+| expr NEQ expr { $$ = &ast.BinaryOp{Op: "!=", Left: $1, Right: $3} }
+| expr '>' expr { $$ = &ast.BinaryOp{Op: ">", Left: $1, Right: $3} }
+| expr '<' expr { $$ = &ast.BinaryOp{Op: "<", Left: $1, Right: $3} }
+| expr GE expr { $$ = &ast.BinaryOp{Op: ">=", Left: $1, Right: $3} }
+| expr LE expr { $$ = &ast.BinaryOp{Op: "<=", Left: $1, Right: $3} }
+| expr CONTAINS expr { $$ = &ast.BinaryOp{Op: "contains", Left: $1, Right: $3} }
 ;
 
 cond:
   rel
-| cond AND rel {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		return values.ValueOf(fa(ctx).Test() && fb(ctx).Test())
-	}
-}
-| cond OR rel {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		return values.ValueOf(fa(ctx).Test() || fb(ctx).Test())
-	}
-}
+| cond AND rel { $$ = &ast.BinaryOp{Op: "and", Left: $1, Right: $3} }
+| cond OR rel { $$ = &ast.BinaryOp{Op: "or", Left: $1, Right: $3} }
 ;
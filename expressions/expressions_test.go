@@ -157,6 +157,77 @@ func TestEvaluateString(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestEvaluateString_errors(t *testing.T) {
+	cfg := NewConfig()
+	cfg.AddFilter("error", func(input any) (string, error) { return "", errors.New("test error") })
+	cfg.AddFilter("length", strings.Count)
+	cfg.StrictVariables = true
+	cfg.MaxRangeSize = 10
+	ctx := NewContext(map[string]any{"n": 100, "s": "x", "hash": map[string]any{"a": 1}, "array": []int{1}}, cfg)
+
+	tests := []struct {
+		in       string
+		expected error
+	}{
+		{`1 | undefined_filter`, UndefinedFilter("undefined_filter")},
+		{`1 | error | length`, FilterError{FilterName: "error", Err: errors.New("test error")}},
+		{`(1..n)`, LimitError{"range size", 10}},
+		{`(1..s)`, values.TypeError("can't convert string(x) to type int")},
+		{`missing`, InterpreterError("undefined variable")},
+		{`hash.missing`, InterpreterError("undefined variable")},
+		{`array[1]`, InterpreterError("undefined variable")},
+		{`true and hash.missing`, InterpreterError("undefined variable")},
+		{`hash.missing | length`, InterpreterError("undefined variable")},
+	}
+	for _, test := range tests {
+		_, err := EvaluateString(test.in, ctx)
+		require.Equalf(t, test.expected, err, test.in)
+	}
+
+	// The right operand of a logical operator is evaluated only as needed.
+	for _, in := range []string{`false and missing`, `true or missing`} {
+		_, err := EvaluateString(in, ctx)
+		require.NoErrorf(t, err, in)
+	}
+}
+
+type testCaller func(name string, args []any, kwargs map[string]any) (any, error)
+
+func (f testCaller) CallFunction(name string, args []any, kwargs map[string]any) (any, error) {
+	return f(name, args, kwargs)
+}
+
+func TestEvaluateString_call(t *testing.T) {
+	ctx := NewContext(map[string]any{"x": 1}, NewConfig())
+
+	_, err := EvaluateString("f(x)", ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `undefined function "f"`)
+
+	ctx.(*context).SetCaller(testCaller(func(name string, args []any, kwargs map[string]any) (any, error) {
+		if name == "fail" {
+			return nil, errors.New("test error")
+		}
+
+		return fmt.Sprint(name, args, kwargs), nil
+	}))
+
+	tests := []struct{ in, expected string }{
+		{`f()`, `f[] map[]`},
+		{`f(x, "a", k: x, j: 2)`, `f[1 a] map[j:2 k:1]`},
+		{`f(g(x)).size`, `19`},
+		{`f(x) == "f[1] map[]"`, `true`},
+	}
+	for _, test := range tests {
+		val, err := EvaluateString(test.in, ctx)
+		require.NoErrorf(t, err, test.in)
+		require.Equalf(t, test.expected, fmt.Sprint(val), test.in)
+	}
+
+	_, err = EvaluateString("fail()", ctx)
+	require.EqualError(t, err, "test error")
+}
+
 func TestClosure(t *testing.T) {
 	cfg := NewConfig()
 	ctx := NewContext(map[string]any{"x": 1}, cfg)
@@ -172,3 +243,32 @@ func TestClosure(t *testing.T) {
 	require.Equal(t, 1, x1)
 	require.Equal(t, 2, x2)
 }
+
+func BenchmarkEvaluate(b *testing.B) {
+	tests := []struct{ name, source string }{
+		{"Literal", `1`},
+		{"Property", `product.variants[0].price`},
+		{"Filter", `product.variants[0].price | money`},
+		{"Comparison", `product.variants[0].price > 500 and product.title contains "o"`},
+	}
+
+	cfg := NewConfig()
+	cfg.AddFilter("money", func(cents int) string { return fmt.Sprintf("$%d.%02d", cents/100, cents%100) })
+	ctx := NewContext(map[string]any{
+		"product": map[string]any{"title": "Shoe", "variants": []map[string]any{{"price": 1250}}},
+	}, cfg)
+
+	for _, test := range tests {
+		expr, err := Parse(test.source)
+		require.NoError(b, err)
+
+		b.Run(test.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if _, err := expr.Evaluate(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -31,7 +31,16 @@ func (e FilterError) Error() string {
 	return fmt.Sprintf("error applying filter %q (%q)", e.FilterName, e.Err)
 }
 
-type valueFn func(Context) values.Value
+// A LimitError is the error when an evaluation or render exceeds a configured
+// resource limit.
+type LimitError struct {
+	Limit string // the name of the limit, e.g. "range size"
+	Max   int
+}
+
+func (e LimitError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded", e.Limit, e.Max)
+}
 
 func (c *Config) ensureMapIsCreated() {
 	if c.filters == nil {
@@ -40,6 +49,10 @@ func (c *Config) ensureMapIsCreated() {
 }
 
 // AddFilter adds a filter to the filter dictionary.
+//
+// A filter whose first parameter is a Context, followed by at least one
+// more, receives the evaluation context before its input. The FilterArguments
+// of the context can supply other such arguments.
 func (c *Config) AddFilter(name string, fn any) {
 	rf := reflect.ValueOf(fn)
 	switch {
@@ -72,47 +85,92 @@ func (c *Config) AddSafeFilter() {
 }
 
 var (
-	closureType   = reflect.TypeOf(closure{})
-	interfaceType = reflect.TypeOf([]any{}).Elem()
+	closureType          = reflect.TypeOf(closure{})
+	contextInterfaceType = reflect.TypeOf((*Context)(nil)).Elem()
+	interfaceType        = reflect.TypeOf([]any{}).Elem()
 )
 
 func isClosureInterfaceType(t reflect.Type) bool {
 	return closureType.ConvertibleTo(t) && !interfaceType.ConvertibleTo(t)
 }
 
-func (ctx *context) ApplyFilter(name string, receiver valueFn, params *filterArgs) (any, error) {
+// ApplyFilter applies the named filter to the value of receiver, with the
+// values of params. An error from the filter is returned as a FilterError.
+func (ctx *context) ApplyFilter(name string, receiver evaluator, params *filterArgs) (any, error) {
 	filter, ok := ctx.filters[name]
 	if !ok {
 		if !ctx.LaxFilters {
-			panic(UndefinedFilter(name))
+			return nil, UndefinedFilter(name)
 		}
-		return receiver(ctx).Interface(), nil
+
+		v, err := receiver.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return v.Interface(), nil
 	}
 
 	fr := reflect.ValueOf(filter)
+	ft := fr.Type()
 	// Use stack-allocated array to avoid heap allocation for filters with ≤4 args.
 	var argsBuf [4]any
-	args := argsBuf[:1]
-	args[0] = receiver(ctx).Interface()
+	args := argsBuf[:0]
+
+	// An environment argument, such as the Context, precedes the input.
+	if ft.NumIn() > 1 {
+		switch in := ft.In(0); {
+		case in == contextInterfaceType:
+			args = append(args, Context(ctx))
+		case ctx.filterArgs != nil:
+			if arg, ok := ctx.filterArgs.FilterArgument(in); ok {
+				args = append(args, arg)
+			}
+		}
+	}
+
+	envArgs := len(args)
+
+	input, err := receiver.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, input.Interface())
 
 	if params != nil {
 		for i, param := range params.positional {
-			if i+1 < fr.Type().NumIn() && isClosureInterfaceType(fr.Type().In(i+1)) {
-				expr, err := Parse(param(ctx).Interface().(string))
+			v, err := param.eval(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			if n := envArgs + i + 1; n < ft.NumIn() && isClosureInterfaceType(ft.In(n)) {
+				source, ok := v.Interface().(string)
+				if !ok {
+					return nil, FilterError{FilterName: name, Err: fmt.Errorf("argument %d must be an expression string", i+1)}
+				}
+
+				expr, err := Parse(source)
 				if err != nil {
-					panic(err)
+					return nil, err
 				}
 
 				args = append(args, closure{expr, ctx})
 			} else {
-				args = append(args, param(ctx).Interface())
+				args = append(args, v.Interface())
 			}
 		}
 
 		if len(params.keyword) > 0 {
 			kwargs := make(map[string]any, len(params.keyword))
 			for _, kw := range params.keyword {
-				kwargs[kw.name] = kw.val(ctx).Interface()
+				v, err := kw.val.eval(ctx)
+				if err != nil {
+					return nil, err
+				}
+
+				kwargs[kw.name] = v.Interface()
 			}
 			args = append(args, kwargs)
 		}
@@ -121,10 +179,10 @@ func (ctx *context) ApplyFilter(name string, receiver valueFn, params *filterArg
 	out, err := values.Call(fr, args)
 	if err != nil {
 		if e, ok := err.(*values.CallParityError); ok {
-			err = &values.CallParityError{NumArgs: e.NumArgs - 1, NumParams: e.NumParams - 1}
+			err = &values.CallParityError{NumArgs: e.NumArgs - 1 - envArgs, NumParams: e.NumParams - 1 - envArgs}
 		}
 
-		return nil, err
+		return nil, FilterError{FilterName: name, Err: err}
 	}
 
 	switch out := out.(type) {
@@ -2,6 +2,7 @@ package expressions
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -24,8 +25,8 @@ func TestContext_AddFilter(t *testing.T) {
 
 func TestContext_runFilter(t *testing.T) {
 	cfg := NewConfig()
-	constant := func(value any) valueFn {
-		return func(Context) values.Value { return values.ValueOf(value) }
+	constant := func(value any) evaluator {
+		return &literalExpr{values.ValueOf(value)}
 	}
 	receiver := constant("self")
 
@@ -43,7 +44,7 @@ func TestContext_runFilter(t *testing.T) {
 		return fmt.Sprintf("(%s, %s)", a, b)
 	})
 	ctx = NewContext(map[string]any{"x": 10}, cfg)
-	out, err = ctx.ApplyFilter("with_arg", receiver, &filterArgs{positional: []valueFn{constant("arg")}})
+	out, err = ctx.ApplyFilter("with_arg", receiver, &filterArgs{positional: []evaluator{constant("arg")}})
 	require.NoError(t, err)
 	require.Equal(t, "(self, arg)", out)
 
@@ -51,7 +52,7 @@ func TestContext_runFilter(t *testing.T) {
 	// TODO error return
 
 	// extra argument
-	_, err = ctx.ApplyFilter("with_arg", receiver, &filterArgs{positional: []valueFn{constant(1), constant(2)}})
+	_, err = ctx.ApplyFilter("with_arg", receiver, &filterArgs{positional: []evaluator{constant(1), constant(2)}})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "wrong number of arguments")
 	require.Contains(t, err.Error(), "given 2")
@@ -70,15 +71,20 @@ func TestContext_runFilter(t *testing.T) {
 		return fmt.Sprintf("(%v, %v)", a, value), nil
 	})
 	ctx = NewContext(map[string]any{"x": 10}, cfg)
-	out, err = ctx.ApplyFilter("closure", receiver, &filterArgs{positional: []valueFn{constant("x |add: y")}})
+	out, err = ctx.ApplyFilter("closure", receiver, &filterArgs{positional: []evaluator{constant("x |add: y")}})
 	require.NoError(t, err)
 	require.Equal(t, "(self, 11)", out)
+
+	for _, arg := range []any{"x |", 1} {
+		_, err = ctx.ApplyFilter("closure", receiver, &filterArgs{positional: []evaluator{constant(arg)}})
+		require.Error(t, err)
+	}
 }
 
 func TestContext_runFilter_keywordArgs(t *testing.T) {
 	cfg := NewConfig()
-	constant := func(value any) valueFn {
-		return func(Context) values.Value { return values.ValueOf(value) }
+	constant := func(value any) evaluator {
+		return &literalExpr{values.ValueOf(value)}
 	}
 	receiver := constant("self")
 
@@ -106,6 +112,61 @@ func TestContext_runFilter_keywordArgs(t *testing.T) {
 	require.Equal(t, "(self, option=true)", out)
 }
 
+type localizer interface{ Locale() string }
+
+type french struct{}
+
+func (french) Locale() string { return "fr" }
+
+type localeArgs struct{}
+
+func (localeArgs) FilterArgument(t reflect.Type) (any, bool) {
+	if t != reflect.TypeOf((*localizer)(nil)).Elem() {
+		return nil, false
+	}
+
+	return french{}, true
+}
+
+func TestContext_runFilter_filterArguments(t *testing.T) {
+	cfg := NewConfig()
+	receiver := &literalExpr{values.ValueOf("self")}
+	cfg.AddFilter("t", func(l localizer, s string, suffix string) string {
+		return fmt.Sprintf("%s:%s%s", l.Locale(), s, suffix)
+	})
+	cfg.AddFilter("plain", func(s string) string { return s })
+
+	ctx := NewContext(map[string]any{}, cfg)
+	ctx.(*context).SetFilterArguments(localeArgs{})
+
+	arg := &literalExpr{values.ValueOf("!")}
+	out, err := ctx.ApplyFilter("t", receiver, &filterArgs{positional: []evaluator{arg}})
+	require.NoError(t, err)
+	require.Equal(t, "fr:self!", out)
+
+	out, err = ctx.ApplyFilter("plain", receiver, nil)
+	require.NoError(t, err)
+	require.Equal(t, "self", out)
+
+	_, err = ctx.ApplyFilter("t", receiver, &filterArgs{positional: []evaluator{arg, arg}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "given 2")
+	require.Contains(t, err.Error(), "expected 1")
+}
+
+func TestContext_runFilter_context(t *testing.T) {
+	cfg := NewConfig()
+	receiver := &literalExpr{values.ValueOf("self")}
+	cfg.AddFilter("t", func(ctx Context, s string) string {
+		return fmt.Sprintf("%s:%s", ctx.Get("locale"), s)
+	})
+
+	ctx := NewContext(map[string]any{"locale": "de"}, cfg)
+	out, err := ctx.ApplyFilter("t", receiver, nil)
+	require.NoError(t, err)
+	require.Equal(t, "de:self", out)
+}
+
 // TestAddSafeFilterNilMap verifies that AddSafeFilter doesn't panic
 // when called on a Config with nil filters map
 func TestAddSafeFilterNilMap(t *testing.T) {
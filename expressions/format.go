@@ -0,0 +1,71 @@
+package expressions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatSource returns the source of an expression or tag argument list with
+// canonical spacing: one space around operators, filter bars, and keyword
+// arguments; a space after each comma; and no space inside brackets and
+// parentheses, before a property, or around "..". Literals are kept as
+// written. The spacing around characters that the expression language
+// doesn't use is kept as written, so that the result of formatting the
+// arguments of a custom tag still has the same meaning.
+//
+// FormatSource doesn't check the syntax of source. It returns an error only
+// if source contains a malformed token, such as an invalid identifier.
+func FormatSource(source string) (out string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = "", SyntaxError(fmt.Sprint(r))
+		}
+	}()
+
+	var (
+		b        strings.Builder
+		lex      = newLexer([]byte(source))
+		prev     = 0  // the previous token
+		prevEnd  = -1 // the end of the previous token
+		sym      yySymType
+		operands = map[int]bool{LITERAL: true, IDENTIFIER: true, PROPERTY: true, ']': true, ')': true}
+	)
+
+	for {
+		tok := lex.Lex(&sym)
+		if tok == 0 {
+			break
+		}
+
+		if prevEnd >= 0 {
+			space := true
+
+			switch {
+			case !formatKnownTokens[prev] || !formatKnownTokens[tok]:
+				space = lex.ts > prevEnd
+			case tok == PROPERTY, tok == ']', tok == ')', tok == ',', tok == ':', tok == DOTDOT:
+				space = false
+			case prev == '[', prev == '(', prev == DOTDOT:
+				space = false
+			case tok == '[' && operands[prev], tok == '(' && prev == IDENTIFIER:
+				space = false
+			}
+
+			if space {
+				b.WriteByte(' ')
+			}
+		}
+
+		b.WriteString(lex.token())
+		prev, prevEnd = tok, lex.te
+	}
+
+	return b.String(), nil
+}
+
+// formatKnownTokens are the tokens whose spacing FormatSource normalizes.
+var formatKnownTokens = map[int]bool{
+	LITERAL: true, IDENTIFIER: true, KEYWORD: true, PROPERTY: true,
+	EQ: true, NEQ: true, GE: true, LE: true, IN: true, AND: true, OR: true, CONTAINS: true, DOTDOT: true,
+	'|': true, ',': true, '[': true, ']': true, '(': true, ')': true, '<': true, '>': true, '=': true, ':': true,
+}
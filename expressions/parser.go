@@ -9,7 +9,7 @@ import (
 	"fmt"
 	"sync"
 
-	"github.com/osteele/liquid/values"
+	"github.com/osteele/liquid/ast"
 )
 
 var yaccParserPool = sync.Pool{
@@ -22,7 +22,7 @@ type parseValue struct {
 	Loop
 	When
 
-	val func(Context) values.Value
+	val ast.Expr
 }
 
 // SyntaxError represents a syntax error. The yacc-generated compiler
@@ -38,7 +38,7 @@ func Parse(source string) (expr Expression, err error) {
 		return nil, err
 	}
 
-	return &expression{p.val}, nil
+	return newExpression(p.val), nil
 }
 
 func parse(source string) (p *parseValue, err error) {
@@ -54,6 +54,7 @@ func parse(source string) (p *parseValue, err error) {
 			}
 		}
 	}()
+
 	// FIXME hack to recognize EOF
 	lex := newLexer([]byte(source + ";"))
 
@@ -66,3 +66,26 @@ func TestParse_errors(t *testing.T) {
 		})
 	}
 }
+
+var formatSourceTests = []struct{ in, expected string }{
+	{`a`, `a`},
+	{`a|f:1,2`, `a | f: 1, 2`},
+	{`  a.b [ 0 ].c  `, `a.b[0].c`},
+	{`( 1 .. n )`, `(1..n)`},
+	{`a==b and c!=d or e contains "x  y"`, `a == b and c != d or e contains "x  y"`},
+	{`item in products limit:3 reversed`, `item in products limit: 3 reversed`},
+	{`x|f:key:1`, `x | f: key: 1`},
+	{`a=1`, `a = 1`},
+	{`"snippet" with x as y`, `"snippet" with x as y`},
+	{`price ( a.b , currency:"x" )`, `price(a.b, currency: "x")`},
+}
+
+func TestFormatSource(t *testing.T) {
+	for i, test := range formatSourceTests {
+		t.Run(fmt.Sprint(i+1), func(t *testing.T) {
+			out, err := FormatSource(test.in)
+			require.NoError(t, err, test.in)
+			require.Equal(t, test.expected, out, test.in)
+		})
+	}
+}
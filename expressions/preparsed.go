@@ -0,0 +1,231 @@
+package expressions
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/osteele/liquid/ast"
+)
+
+// A ParsedSource is the result of parsing an expression or statement source, as
+// syntax trees. Unlike an Expression, it holds only data, so that it can be
+// serialized with encoding/gob. A recording ParseTable collects the parses of
+// the sources that a compilation parses, and a ParseTable made from them
+// supplies them to a later compilation, which then doesn't need to parse those
+// sources.
+type ParsedSource struct {
+	// Source is the parsed source. The source of a statement starts with its
+	// selector, such as LoopStatementSelector.
+	Source string
+	// Expr is the expression, the value of an assignment, or the collection
+	// of a loop.
+	Expr ast.Expr
+	// Path is the variable path of an assignment.
+	Path []string
+	// Cycle is the parse of a cycle statement.
+	Cycle Cycle
+	// Variable and the fields that follow it are the parse of a loop.
+	Variable                 string
+	Limit, Offset, Cols      ast.Expr
+	Reversed, OffsetContinue bool
+	// When holds the expressions of a when clause.
+	When []ast.Expr
+}
+
+// A ParseTable holds the parses of sources. Its Parse and ParseStatement
+// methods return the compiled parse of a source in the table, instead of
+// parsing it. A recording table also adds the parses of the sources that it
+// parses. A ParseTable is safe for concurrent use. A nil *ParseTable parses
+// every source.
+//
+// A parse is a function of its source, so a table doesn't change the result
+// of any parse unless its parses are modified or come from a different
+// version of this package.
+type ParseTable struct {
+	recording bool
+
+	mu     sync.RWMutex
+	parses map[string]ParsedSource
+}
+
+// NewParseTable returns a table of parses, such as those that a recording
+// table returned in an earlier process.
+func NewParseTable(parses []ParsedSource) *ParseTable {
+	return (*ParseTable)(nil).With(parses)
+}
+
+// NewRecordingParseTable returns an empty table that adds the parses of the
+// sources that it parses.
+func NewRecordingParseTable() *ParseTable {
+	return &ParseTable{recording: true, parses: map[string]ParsedSource{}}
+}
+
+// With returns a new table, that isn't recording, with the parses of t and
+// parses.
+func (t *ParseTable) With(parses []ParsedSource) *ParseTable {
+	table := &ParseTable{parses: make(map[string]ParsedSource, t.len()+len(parses))}
+
+	if t != nil {
+		t.mu.RLock()
+		maps.Copy(table.parses, t.parses)
+		t.mu.RUnlock()
+	}
+
+	for _, p := range parses {
+		table.parses[p.Source] = p
+	}
+
+	return table
+}
+
+func (t *ParseTable) len() int {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return len(t.parses)
+}
+
+// Parses returns the parses in the table, sorted by source.
+func (t *ParseTable) Parses() []ParsedSource {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	parses := slices.Collect(maps.Values(t.parses))
+	sort.Slice(parses, func(i, j int) bool { return parses[i].Source < parses[j].Source })
+
+	return parses
+}
+
+// Parse is the same as the package's Parse, except that it uses the table.
+func (t *ParseTable) Parse(source string) (Expression, error) {
+	p, err := t.parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return newExpression(p.val), nil
+}
+
+// ParseStatement is the same as the package's ParseStatement, except that it
+// uses the table.
+func (t *ParseTable) ParseStatement(sel, source string) (*Statement, error) {
+	p, err := t.parse(sel + source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Statement{*p}, nil
+}
+
+func (t *ParseTable) parse(source string) (*parseValue, error) {
+	if t == nil {
+		return parse(source)
+	}
+
+	t.mu.RLock()
+	p, ok := t.parses[source]
+	t.mu.RUnlock()
+
+	if ok {
+		return p.parseValue(), nil
+	}
+
+	v, err := parse(source)
+	if err != nil || !t.recording {
+		return v, err
+	}
+
+	t.mu.Lock()
+	t.parses[source] = newParsedSource(source, v)
+	t.mu.Unlock()
+
+	return v, nil
+}
+
+// newParsedSource returns the data of the result of parsing source.
+func newParsedSource(source string, v *parseValue) ParsedSource {
+	p := ParsedSource{
+		Source:         source,
+		Expr:           v.val,
+		Path:           v.Assignment.Path,
+		Cycle:          v.Cycle,
+		Variable:       v.Loop.Variable,
+		Limit:          SyntaxTree(v.Loop.Limit),
+		Offset:         SyntaxTree(v.Loop.Offset),
+		Cols:           SyntaxTree(v.Loop.Cols),
+		Reversed:       v.Loop.Reversed,
+		OffsetContinue: v.Loop.OffsetContinue,
+	}
+
+	switch {
+	case v.Assignment.ValueFn != nil:
+		p.Expr = SyntaxTree(v.Assignment.ValueFn)
+	case v.Loop.Expr != nil:
+		p.Expr = SyntaxTree(v.Loop.Expr)
+	}
+
+	for _, expr := range v.When.Exprs {
+		p.When = append(p.When, SyntaxTree(expr))
+	}
+
+	return p
+}
+
+// parseValue compiles a copy of the parse, as though its source had just
+// been parsed.
+func (p ParsedSource) parseValue() *parseValue {
+	var v parseValue
+
+	switch {
+	case strings.HasPrefix(p.Source, AssignStatementSelector):
+		var variable string
+		if len(p.Path) == 1 {
+			variable = p.Path[0]
+		}
+
+		v.Assignment = Assignment{Variable: variable, Path: p.Path, ValueFn: compileParsed(p.Expr)}
+	case strings.HasPrefix(p.Source, CycleStatementSelector):
+		v.Cycle = p.Cycle
+	case strings.HasPrefix(p.Source, LoopStatementSelector):
+		v.Loop = Loop{
+			loopModifiers: loopModifiers{
+				Limit:          compileParsed(p.Limit),
+				Offset:         compileParsed(p.Offset),
+				Cols:           compileParsed(p.Cols),
+				Reversed:       p.Reversed,
+				OffsetContinue: p.OffsetContinue,
+			},
+			Variable: p.Variable,
+			Expr:     compileParsed(p.Expr),
+		}
+	case strings.HasPrefix(p.Source, WhenStatementSelector):
+		for _, expr := range p.When {
+			v.When.Exprs = append(v.When.Exprs, compileParsed(expr))
+		}
+	default:
+		v.val = ast.CopyExpr(p.Expr)
+	}
+
+	return &v
+}
+
+// compileParsed compiles a copy of a preloaded syntax tree. It returns nil
+// for a nil tree, as the parser leaves an absent loop modifier nil.
+func compileParsed(n ast.Expr) Expression {
+	if n == nil {
+		return nil
+	}
+
+	return newExpression(ast.CopyExpr(n))
+}
@@ -0,0 +1,91 @@
+package expressions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/ast"
+)
+
+func TestParseTable_recording(t *testing.T) {
+	table := NewRecordingParseTable()
+	_, err := table.Parse(`a | f: 1`)
+	require.NoError(t, err)
+	_, err = table.ParseStatement(LoopStatementSelector, `i in (1..n) limit: 2 reversed`)
+	require.NoError(t, err)
+	_, err = table.Parse(`a syntax error`)
+	require.Error(t, err)
+
+	parses := table.Parses()
+	require.Len(t, parses, 2)
+	require.Equal(t, LoopStatementSelector+`i in (1..n) limit: 2 reversed`, parses[0].Source)
+	require.Equal(t, `a | f: 1`, parses[1].Source)
+
+	// Each table records separately.
+	require.Empty(t, NewRecordingParseTable().Parses())
+
+	// A table made from the parses doesn't record.
+	copied := NewParseTable(parses)
+	_, err = copied.Parse(`b`)
+	require.NoError(t, err)
+	require.Equal(t, parses, copied.Parses())
+}
+
+func TestParsedSource_parseValue(t *testing.T) {
+	statements := []struct{ sel, source string }{
+		{AssignStatementSelector, `page.title = a | upcase`},
+		{CycleStatementSelector, `"g": "a", "b"`},
+		{LoopStatementSelector, `i in items limit: 2 offset: continue cols: 3 reversed`},
+		{LoopStatementSelector, `i in (1..n) offset: m`},
+		{WhenStatementSelector, `1, x or "y"`},
+	}
+	for _, test := range statements {
+		t.Run(test.sel+test.source, func(t *testing.T) {
+			expected, err := ParseStatement(test.sel, test.source)
+			require.NoError(t, err)
+
+			parsed := newParsedSource(test.sel+test.source, &expected.parseValue)
+			actual := parsed.parseValue()
+			require.Equal(t, newParsedSource(test.sel+test.source, actual), parsed)
+		})
+	}
+
+	expr, err := Parse(`f(a[0], k: b.c) == (1..2)`)
+	require.NoError(t, err)
+
+	parsed := newParsedSource(`src`, &parseValue{val: SyntaxTree(expr)})
+	actual := parsed.parseValue().val
+	require.Equal(t, SyntaxTree(expr), actual)
+
+	// The compiled parse has a copy of the tree.
+	actual.(*ast.BinaryOp).Op = "!="
+	require.Equal(t, "==", parsed.Expr.(*ast.BinaryOp).Op)
+}
+
+func TestParseTable(t *testing.T) {
+	source := `parse_table_test_source`
+	table := NewParseTable([]ParsedSource{{Source: source, Expr: &ast.Literal{Value: 42}}})
+
+	// The table's tree is used instead of parsing the source.
+	expr, err := table.Parse(source)
+	require.NoError(t, err)
+	value, err := expr.Evaluate(NewContext(map[string]any{}, NewConfig()))
+	require.NoError(t, err)
+	require.Equal(t, 42, value)
+
+	// The package's Parse parses the source.
+	expr, err = Parse(source)
+	require.NoError(t, err)
+	require.Equal(t, "parse_table_test_source", SyntaxTree(expr).String())
+
+	expr, err = table.With(nil).Parse(source)
+	require.NoError(t, err)
+	require.Equal(t, "42", SyntaxTree(expr).String())
+
+	var empty *ParseTable
+	expr, err = empty.With([]ParsedSource{{Source: `a`, Expr: &ast.Literal{Value: 1}}}).Parse(`a`)
+	require.NoError(t, err)
+	require.Equal(t, "1", SyntaxTree(expr).String())
+	require.Nil(t, empty.Parses())
+}
@@ -1,5 +1,7 @@
 package expressions
 
+import "github.com/osteele/liquid/ast"
+
 // These strings match lexer tokens.
 const (
 	AssignStatementSelector = "%assign "
@@ -40,6 +42,9 @@ type loopModifiers struct {
 	Offset   Expression
 	Cols     Expression
 	Reversed bool
+	// OffsetContinue is set by offset:continue, which starts the loop where
+	// the previous loop with the same forloop.name stopped.
+	OffsetContinue bool
 }
 
 // A When is a parse of a {% when %} clause
@@ -57,3 +62,39 @@ func ParseStatement(sel, source string) (*Statement, error) {
 
 	return &Statement{*p}, nil
 }
+
+// ParseSyntax parses the arguments of a tag, as the statement that sel
+// selects, or as an expression if sel is empty, and returns their syntax
+// tree. The tree is not shared with any compiled expression.
+func ParseSyntax(sel, source string) (ast.Stmt, error) {
+	p, err := parse(sel + source)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sel {
+	case AssignStatementSelector:
+		return &ast.AssignStmt{Path: p.Assignment.Path, Value: SyntaxTree(p.Assignment.ValueFn)}, nil
+	case CycleStatementSelector:
+		return &ast.CycleStmt{Group: p.Cycle.Group, Values: p.Cycle.Values}, nil
+	case LoopStatementSelector:
+		return &ast.LoopStmt{
+			Variable:       p.Loop.Variable,
+			Collection:     SyntaxTree(p.Loop.Expr),
+			Limit:          SyntaxTree(p.Loop.Limit),
+			Offset:         SyntaxTree(p.Loop.Offset),
+			Cols:           SyntaxTree(p.Loop.Cols),
+			Reversed:       p.Loop.Reversed,
+			OffsetContinue: p.Loop.OffsetContinue,
+		}, nil
+	case WhenStatementSelector:
+		stmt := &ast.WhenStmt{}
+		for _, expr := range p.When.Exprs {
+			stmt.Exprs = append(stmt.Exprs, SyntaxTree(expr))
+		}
+
+		return stmt, nil
+	default:
+		return &ast.ExprStmt{Expr: p.val}, nil
+	}
+}
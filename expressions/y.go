@@ -8,7 +8,7 @@ import __yyfmt__ "fmt"
 //line expressions.y:2
 import (
 	"fmt"
-	"github.com/osteele/liquid/values"
+	"github.com/osteele/liquid/ast"
 )
 
 func init() {
@@ -22,7 +22,7 @@ type yySymType struct {
 	yys           int
 	name          string
 	val           any
-	f             func(Context) values.Value
+	node          ast.Expr
 	s             string
 	ss            []string
 	exprs         []Expression
@@ -30,7 +30,8 @@ type yySymType struct {
 	cyclefn       func(string) Cycle
 	loop          Loop
 	loopmods      loopModifiers
-	filter_params *filterArgs
+	filter_params *ast.Filter
+	call          *ast.Call
 }
 
 const LITERAL = 57346
@@ -80,10 +81,10 @@ var yyToknames = [...]string{
 	"'='",
 	"':'",
 	"','",
-	"'['",
-	"']'",
 	"'('",
 	"')'",
+	"'['",
+	"']'",
 }
 
 var yyStatenames = [...]string{}
@@ -101,79 +102,88 @@ var yyExca = [...]int8{
 
 const yyPrivate = 57344
 
-const yyLast = 125
+const yyLast = 134
 
 var yyAct = [...]int8{
-	9, 50, 45, 19, 2, 8, 82, 24, 46, 10,
-	11, 87, 49, 35, 10, 11, 47, 36, 3, 4,
-	5, 6, 42, 14, 15, 26, 44, 46, 55, 56,
-	57, 58, 59, 60, 61, 62, 12, 26, 64, 10,
-	11, 12, 25, 48, 65, 7, 26, 27, 66, 69,
-	67, 41, 70, 71, 68, 73, 26, 52, 40, 27,
-	37, 38, 83, 22, 75, 17, 12, 51, 27, 77,
-	78, 20, 80, 81, 14, 15, 1, 39, 27, 74,
-	84, 85, 76, 86, 26, 79, 88, 21, 89, 28,
-	29, 32, 33, 53, 54, 16, 34, 63, 43, 18,
-	31, 30, 26, 14, 15, 23, 27, 28, 29, 32,
-	33, 13, 72, 0, 34, 0, 0, 0, 31, 30,
-	0, 0, 0, 0, 27,
+	9, 51, 46, 19, 2, 8, 81, 24, 80, 35,
+	14, 15, 90, 36, 10, 11, 26, 37, 3, 4,
+	5, 6, 26, 69, 26, 45, 47, 53, 56, 57,
+	58, 59, 60, 61, 62, 63, 66, 52, 26, 12,
+	27, 10, 11, 67, 47, 70, 27, 79, 27, 71,
+	74, 72, 50, 75, 76, 73, 78, 41, 10, 11,
+	97, 93, 27, 10, 11, 92, 12, 64, 82, 83,
+	10, 11, 14, 15, 85, 86, 40, 88, 89, 25,
+	84, 48, 91, 12, 14, 15, 43, 49, 12, 7,
+	42, 96, 13, 98, 26, 12, 99, 22, 100, 28,
+	29, 32, 33, 17, 38, 39, 34, 68, 26, 20,
+	31, 30, 1, 28, 29, 32, 33, 87, 27, 21,
+	34, 94, 95, 16, 31, 30, 54, 55, 44, 18,
+	23, 65, 27, 77,
 }
 
 var yyPact = [...]int16{
-	10, -1000, 86, 60, 67, 58, 35, -1000, 20, 95,
-	-1000, -1000, 35, -1000, 35, 35, 51, 44, -3, -1,
-	-1000, -9, 27, -13, 39, 88, -1000, 35, 35, 35,
-	35, 35, 35, 35, 35, 77, 6, -1000, -1000, 35,
-	-1000, -1000, -1000, -1000, 67, -1000, 67, -1000, 35, -1000,
-	-1000, 35, 35, -1000, 35, 49, 18, 18, 18, 18,
-	18, 18, 18, 35, -1000, 57, -20, -20, 20, 18,
-	39, 39, -22, 18, -1000, 30, -1000, -1000, -1000, 75,
-	-1000, -1000, 5, -1000, -1000, 35, 18, 35, 18, 18,
+	10, -1000, 67, 98, 105, 92, 66, -1000, 57, 101,
+	-1000, -20, 66, -1000, 66, 66, 50, 83, 61, -2,
+	-1000, 56, 71, 27, 9, 121, -1000, 66, 66, 66,
+	66, 66, 66, 66, 66, 37, 87, -7, -1000, -1000,
+	66, -1000, -1000, -1000, -1000, 105, -1000, 105, -1000, 66,
+	-1000, -1000, 66, 66, -1000, 66, 15, 17, 17, 17,
+	17, 17, 17, 17, -1000, -22, 17, 66, 66, -1000,
+	55, 16, 16, 57, 17, 9, 9, -16, 17, -1000,
+	-1000, 59, 17, 31, -1000, -1000, -1000, 116, -1000, -1000,
+	54, 17, 66, -1000, -1000, 66, 17, 66, 17, 17,
+	17,
 }
 
-var yyPgo = [...]int8{
-	0, 0, 45, 5, 4, 112, 105, 1, 99, 98,
-	2, 95, 87, 85, 3, 76,
+var yyPgo = [...]uint8{
+	0, 0, 89, 5, 4, 133, 131, 130, 1, 129,
+	128, 2, 123, 119, 117, 3, 112,
 }
 
 var yyR1 = [...]int8{
-	0, 15, 15, 15, 15, 15, 11, 11, 11, 8,
-	9, 9, 10, 10, 6, 7, 7, 7, 14, 12,
-	13, 13, 13, 1, 1, 1, 1, 1, 1, 3,
-	3, 3, 5, 5, 5, 2, 2, 2, 2, 2,
-	2, 2, 2, 4, 4, 4,
+	0, 16, 16, 16, 16, 16, 12, 12, 12, 9,
+	10, 10, 11, 11, 7, 8, 8, 8, 15, 13,
+	14, 14, 14, 1, 1, 1, 1, 1, 1, 1,
+	1, 3, 3, 3, 5, 5, 5, 6, 6, 6,
+	6, 2, 2, 2, 2, 2, 2, 2, 2, 4,
+	4, 4,
 }
 
 var yyR2 = [...]int8{
 	0, 2, 5, 3, 3, 3, 1, 2, 2, 2,
 	3, 1, 0, 3, 2, 0, 3, 3, 1, 4,
-	0, 2, 3, 1, 1, 2, 4, 5, 3, 1,
-	3, 4, 1, 3, 4, 1, 3, 3, 3, 3,
-	3, 3, 3, 1, 3, 3,
+	0, 2, 3, 1, 1, 3, 4, 2, 4, 5,
+	3, 1, 3, 4, 1, 3, 4, 1, 2, 3,
+	4, 1, 3, 3, 3, 3, 3, 3, 3, 1,
+	3, 3,
 }
 
 var yyChk = [...]int16{
-	-1000, -15, -4, 8, 9, 10, 11, -2, -3, -1,
-	4, 5, 31, 25, 17, 18, -11, 5, -8, -14,
-	4, -12, 5, -6, -1, 22, 7, 29, 12, 13,
-	24, 23, 14, 15, 19, -1, -4, -2, -2, 26,
-	7, 7, 25, -9, 27, -10, 28, 25, 16, 25,
-	-7, 28, 18, 5, 6, -1, -1, -1, -1, -1,
-	-1, -1, -1, 20, 32, -4, -14, -14, -3, -1,
-	-1, -1, -5, -1, 30, -1, 25, -10, -10, -13,
-	-7, -7, 28, 32, 5, 6, -1, 6, -1, -1,
+	-1000, -16, -4, 8, 9, 10, 11, -2, -3, -1,
+	4, 5, 29, 25, 17, 18, -12, 5, -9, -15,
+	4, -13, 5, -7, -1, 22, 7, 31, 12, 13,
+	24, 23, 14, 15, 19, 29, -1, -4, -2, -2,
+	26, 7, 7, 25, -10, 27, -11, 28, 25, 16,
+	25, -8, 28, 18, 5, 6, -1, -1, -1, -1,
+	-1, -1, -1, -1, 30, -6, -1, 6, 20, 30,
+	-4, -15, -15, -3, -1, -1, -1, -5, -1, 32,
+	30, 28, -1, -1, 25, -11, -11, -14, -8, -8,
+	28, -1, 6, 30, 5, 6, -1, 6, -1, -1,
+	-1,
 }
 
 var yyDef = [...]int8{
-	0, -2, 0, 0, 0, 0, 0, 43, 35, 29,
+	0, -2, 0, 0, 0, 0, 0, 49, 41, 31,
 	23, 24, 0, 1, 0, 0, 0, 6, 0, 12,
-	18, 0, 0, 0, 15, 0, 25, 0, 0, 0,
-	0, 0, 0, 0, 0, 29, 0, 44, 45, 0,
-	8, 7, 3, 9, 0, 11, 0, 4, 0, 5,
-	14, 0, 0, 30, 0, 0, 36, 37, 38, 39,
-	40, 41, 42, 0, 28, 0, 12, 12, 20, 29,
-	15, 15, 31, 32, 26, 0, 2, 10, 13, 19,
-	16, 17, 0, 27, 21, 0, 33, 0, 22, 34,
+	18, 0, 0, 0, 15, 0, 27, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 31, 0, 50, 51,
+	0, 8, 7, 3, 9, 0, 11, 0, 4, 0,
+	5, 14, 0, 0, 32, 0, 0, 42, 43, 44,
+	45, 46, 47, 48, 25, 0, 37, 0, 0, 30,
+	0, 12, 12, 20, 31, 15, 15, 33, 34, 28,
+	26, 0, 38, 0, 2, 10, 13, 19, 16, 17,
+	0, 39, 0, 29, 21, 0, 35, 0, 40, 22,
+	36,
 }
 
 var yyTok1 = [...]int8{
@@ -181,12 +191,12 @@ var yyTok1 = [...]int8{
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	31, 32, 3, 3, 28, 3, 21, 3, 3, 3,
+	29, 30, 3, 3, 28, 3, 21, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 27, 25,
 	23, 26, 24, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 29, 3, 30, 3, 3, 3, 3, 3, 3,
+	3, 31, 3, 32, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 22,
@@ -540,116 +550,116 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:45
+//line expressions.y:47
 		{
-			yylex.(*lexer).val = yyDollar[1].f
+			yylex.(*lexer).val = yyDollar[1].node
 		}
 	case 2:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line expressions.y:46
+//line expressions.y:48
 		{
 			path := yyDollar[2].ss
 			var variable string
 			if len(path) == 1 {
 				variable = path[0]
 			}
-			yylex.(*lexer).Assignment = Assignment{Variable: variable, Path: path, ValueFn: &expression{yyDollar[4].f}}
+			yylex.(*lexer).Assignment = Assignment{Variable: variable, Path: path, ValueFn: newExpression(yyDollar[4].node)}
 		}
 	case 3:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:54
+//line expressions.y:56
 		{
 			yylex.(*lexer).Cycle = yyDollar[2].cycle
 		}
 	case 4:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:55
+//line expressions.y:57
 		{
 			yylex.(*lexer).Loop = yyDollar[2].loop
 		}
 	case 5:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:56
+//line expressions.y:58
 		{
 			yylex.(*lexer).When = When{yyDollar[2].exprs}
 		}
 	case 6:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:60
+//line expressions.y:62
 		{
 			yyVAL.ss = []string{yyDollar[1].name}
 		}
 	case 7:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:61
+//line expressions.y:63
 		{
 			yyVAL.ss = []string{yyDollar[1].name, yyDollar[2].name}
 		}
 	case 8:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:62
+//line expressions.y:64
 		{
 			yyVAL.ss = append(yyDollar[1].ss, yyDollar[2].name)
 		}
 	case 9:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:65
+//line expressions.y:67
 		{
 			yyVAL.cycle = yyDollar[2].cyclefn(yyDollar[1].s)
 		}
 	case 10:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:68
+//line expressions.y:70
 		{
 			h, t := yyDollar[2].s, yyDollar[3].ss
 			yyVAL.cyclefn = func(g string) Cycle { return Cycle{g, append([]string{h}, t...)} }
 		}
 	case 11:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:72
+//line expressions.y:74
 		{
 			vals := yyDollar[1].ss
 			yyVAL.cyclefn = func(h string) Cycle { return Cycle{Values: append([]string{h}, vals...)} }
 		}
 	case 12:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line expressions.y:79
+//line expressions.y:81
 		{
 			yyVAL.ss = []string{}
 		}
 	case 13:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:80
+//line expressions.y:82
 		{
 			yyVAL.ss = append([]string{yyDollar[2].s}, yyDollar[3].ss...)
 		}
 	case 14:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:83
+//line expressions.y:85
 		{
-			yyVAL.exprs = append([]Expression{&expression{yyDollar[1].f}}, yyDollar[2].exprs...)
+			yyVAL.exprs = append([]Expression{newExpression(yyDollar[1].node)}, yyDollar[2].exprs...)
 		}
 	case 15:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line expressions.y:85
+//line expressions.y:87
 		{
 			yyVAL.exprs = []Expression{}
 		}
 	case 16:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:86
+//line expressions.y:88
 		{
-			yyVAL.exprs = append([]Expression{&expression{yyDollar[2].f}}, yyDollar[3].exprs...)
+			yyVAL.exprs = append([]Expression{newExpression(yyDollar[2].node)}, yyDollar[3].exprs...)
 		}
 	case 17:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:87
+//line expressions.y:89
 		{
-			yyVAL.exprs = append([]Expression{&expression{yyDollar[2].f}}, yyDollar[3].exprs...)
+			yyVAL.exprs = append([]Expression{newExpression(yyDollar[2].node)}, yyDollar[3].exprs...)
 		}
 	case 18:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:90
+//line expressions.y:92
 		{
 			s, ok := yyDollar[1].val.(string)
 			if !ok {
@@ -659,20 +669,20 @@ yydefault:
 		}
 	case 19:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line expressions.y:98
+//line expressions.y:100
 		{
-			name, expr, mods := yyDollar[1].name, yyDollar[3].f, yyDollar[4].loopmods
-			yyVAL.loop = Loop{mods, name, &expression{expr}}
+			name, expr, mods := yyDollar[1].name, yyDollar[3].node, yyDollar[4].loopmods
+			yyVAL.loop = Loop{mods, name, newExpression(expr)}
 		}
 	case 20:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line expressions.y:104
+//line expressions.y:106
 		{
 			yyVAL.loopmods = loopModifiers{}
 		}
 	case 21:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:105
+//line expressions.y:107
 		{
 			switch yyDollar[2].name {
 			case "reversed":
@@ -684,15 +694,19 @@ yydefault:
 		}
 	case 22:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:114
+//line expressions.y:116
 		{
 			switch yyDollar[2].name {
 			case "cols":
-				yyDollar[1].loopmods.Cols = &expression{yyDollar[3].f}
+				yyDollar[1].loopmods.Cols = newExpression(yyDollar[3].node)
 			case "limit":
-				yyDollar[1].loopmods.Limit = &expression{yyDollar[3].f}
+				yyDollar[1].loopmods.Limit = newExpression(yyDollar[3].node)
 			case "offset":
-				yyDollar[1].loopmods.Offset = &expression{yyDollar[3].f}
+				if v, ok := yyDollar[3].node.(*ast.Variable); ok && v.Name == "continue" {
+					yyDollar[1].loopmods.Offset, yyDollar[1].loopmods.OffsetContinue = nil, true
+				} else {
+					yyDollar[1].loopmods.Offset, yyDollar[1].loopmods.OffsetContinue = newExpression(yyDollar[3].node), false
+				}
 			default:
 				panic(SyntaxError(fmt.Sprintf("undefined loop modifier %q", yyDollar[2].name)))
 			}
@@ -700,157 +714,165 @@ yydefault:
 		}
 	case 23:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:130
+//line expressions.y:136
 		{
-			val := values.ValueOf(yyDollar[1].val)
-			yyVAL.f = func(Context) values.Value { return val }
+			yyVAL.node = &ast.Literal{Value: yyDollar[1].val}
 		}
 	case 24:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:131
+//line expressions.y:137
 		{
-			name := yyDollar[1].name
-			yyVAL.f = func(ctx Context) values.Value { return values.ValueOf(ctx.Get(name)) }
+			yyVAL.node = &ast.Variable{Name: yyDollar[1].name}
 		}
 	case 25:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:132
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:138
 		{
-			yyVAL.f = makeObjectPropertyExpr(yyDollar[1].f, yyDollar[2].name)
+			yyVAL.node = &ast.Call{Name: yyDollar[1].name}
 		}
 	case 26:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line expressions.y:133
+//line expressions.y:139
 		{
-			yyVAL.f = makeIndexExpr(yyDollar[1].f, yyDollar[3].f)
+			yyDollar[3].call.Name = yyDollar[1].name
+			yyVAL.node = yyDollar[3].call
 		}
 	case 27:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line expressions.y:134
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line expressions.y:140
 		{
-			yyVAL.f = makeRangeExpr(yyDollar[2].f, yyDollar[4].f)
+			yyVAL.node = &ast.Property{Object: yyDollar[1].node, Name: yyDollar[2].name}
 		}
 	case 28:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:135
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line expressions.y:141
 		{
-			yyVAL.f = yyDollar[2].f
+			yyVAL.node = &ast.Index{Object: yyDollar[1].node, Index: yyDollar[3].node}
+		}
+	case 29:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line expressions.y:142
+		{
+			yyVAL.node = &ast.Range{Start: yyDollar[2].node, End: yyDollar[4].node}
 		}
 	case 30:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:140
+//line expressions.y:143
 		{
-			yyVAL.f = makeFilter(yyDollar[1].f, yyDollar[3].name, nil)
+			yyVAL.node = yyDollar[2].node
 		}
-	case 31:
+	case 32:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:148
+		{
+			yyVAL.node = &ast.Filter{Input: yyDollar[1].node, Name: yyDollar[3].name}
+		}
+	case 33:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line expressions.y:141
+//line expressions.y:149
 		{
-			yyVAL.f = makeFilter(yyDollar[1].f, yyDollar[3].name, yyDollar[4].filter_params)
+			yyDollar[4].filter_params.Input, yyDollar[4].filter_params.Name = yyDollar[1].node, yyDollar[3].name
+			yyVAL.node = yyDollar[4].filter_params
 		}
-	case 32:
+	case 34:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:145
+//line expressions.y:153
 		{
-			yyVAL.filter_params = &filterArgs{positional: []valueFn{yyDollar[1].f}}
+			yyVAL.filter_params = &ast.Filter{Args: []ast.Expr{yyDollar[1].node}}
 		}
-	case 33:
+	case 35:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:147
+//line expressions.y:155
 		{
-			yyDollar[1].filter_params.positional = append(yyDollar[1].filter_params.positional, yyDollar[3].f)
+			yyDollar[1].filter_params.Args = append(yyDollar[1].filter_params.Args, yyDollar[3].node)
 			yyVAL.filter_params = yyDollar[1].filter_params
 		}
-	case 34:
+	case 36:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line expressions.y:149
+//line expressions.y:157
 		{
-			yyDollar[1].filter_params.keyword = append(yyDollar[1].filter_params.keyword, keywordArg{yyDollar[3].name, yyDollar[4].f})
+			yyDollar[1].filter_params.KeywordArgs = append(yyDollar[1].filter_params.KeywordArgs, ast.KeywordArg{Name: yyDollar[3].name, Value: yyDollar[4].node})
 			yyVAL.filter_params = yyDollar[1].filter_params
 		}
-	case 36:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:153
-		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(a.Equal(b))
-			}
-		}
 	case 37:
-		yyDollar = yyS[yypt-3 : yypt+1]
+		yyDollar = yyS[yypt-1 : yypt+1]
 //line expressions.y:160
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(!a.Equal(b))
-			}
+			yyVAL.call = &ast.Call{Args: []ast.Expr{yyDollar[1].node}}
 		}
 	case 38:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:167
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line expressions.y:161
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(b.Less(a))
-			}
+			yyVAL.call = &ast.Call{KeywordArgs: []ast.KeywordArg{{Name: yyDollar[1].name, Value: yyDollar[2].node}}}
 		}
 	case 39:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:174
+//line expressions.y:163
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(a.Less(b))
-			}
+			yyDollar[1].call.Args = append(yyDollar[1].call.Args, yyDollar[3].node)
+			yyVAL.call = yyDollar[1].call
 		}
 	case 40:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:181
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line expressions.y:165
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(b.Less(a) || a.Equal(b))
-			}
+			yyDollar[1].call.KeywordArgs = append(yyDollar[1].call.KeywordArgs, ast.KeywordArg{Name: yyDollar[3].name, Value: yyDollar[4].node})
+			yyVAL.call = yyDollar[1].call
 		}
-	case 41:
+	case 42:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:188
+//line expressions.y:170
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(a.Less(b) || a.Equal(b))
-			}
+			yyVAL.node = &ast.BinaryOp{Op: "==", Left: yyDollar[1].node, Right: yyDollar[3].node}
 		}
-	case 42:
+	case 43:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:195
+//line expressions.y:171
 		{
-			yyVAL.f = makeContainsExpr(yyDollar[1].f, yyDollar[3].f)
+			yyVAL.node = &ast.BinaryOp{Op: "!=", Left: yyDollar[1].node, Right: yyDollar[3].node}
 		}
 	case 44:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:200
+//line expressions.y:172
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				return values.ValueOf(fa(ctx).Test() && fb(ctx).Test())
-			}
+			yyVAL.node = &ast.BinaryOp{Op: ">", Left: yyDollar[1].node, Right: yyDollar[3].node}
 		}
 	case 45:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:206
+//line expressions.y:173
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				return values.ValueOf(fa(ctx).Test() || fb(ctx).Test())
-			}
+			yyVAL.node = &ast.BinaryOp{Op: "<", Left: yyDollar[1].node, Right: yyDollar[3].node}
+		}
+	case 46:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:174
+		{
+			yyVAL.node = &ast.BinaryOp{Op: ">=", Left: yyDollar[1].node, Right: yyDollar[3].node}
+		}
+	case 47:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:175
+		{
+			yyVAL.node = &ast.BinaryOp{Op: "<=", Left: yyDollar[1].node, Right: yyDollar[3].node}
+		}
+	case 48:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:176
+		{
+			yyVAL.node = &ast.BinaryOp{Op: "contains", Left: yyDollar[1].node, Right: yyDollar[3].node}
+		}
+	case 50:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:181
+		{
+			yyVAL.node = &ast.BinaryOp{Op: "and", Left: yyDollar[1].node, Right: yyDollar[3].node}
+		}
+	case 51:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:182
+		{
+			yyVAL.node = &ast.BinaryOp{Op: "or", Left: yyDollar[1].node, Right: yyDollar[3].node}
 		}
 	}
 	goto yystack /* stack new state and value */
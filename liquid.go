@@ -8,6 +8,10 @@ The liquid package itself is versioned in gopkg.in. Subpackages have no compatib
 package liquid
 
 import (
+	"context"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
 	"github.com/osteele/liquid/render"
 	"github.com/osteele/liquid/tags"
 )
@@ -33,8 +37,70 @@ type SourceError interface {
 	LineNumber() int
 }
 
+// ErrorMode determines how an Engine responds to syntax and render errors.
+// See Engine.ErrorMode.
+type ErrorMode = parser.ErrorMode
+
+// These are the error modes. They correspond to Shopify Liquid's :strict, :warn, and :lax modes.
+const (
+	Strict = parser.StrictMode
+	Warn   = parser.WarnMode
+	Lax    = parser.LaxMode
+)
+
+// Limits bounds the resources that a single render can use. See Engine.SetLimits.
+type Limits = render.Limits
+
+// A LimitError is the cause of the SourceError that a render returns when it exceeds one of its Limits.
+type LimitError = expressions.LimitError
+
+// Analysis is a static report of the variables, filters, and partials that a template uses.
+// See Template.Analyze.
+type Analysis = render.Analysis
+
+// VariablePath is a variable reference in an Analysis, e.g. product.variants[0].title.
+type VariablePath = expressions.VariablePath
+
+// PartialCache is a concurrency-safe cache of compiled partials. See Engine.SetPartialCache.
+type PartialCache = render.PartialCache
+
+// TemplateVersioner is a template store that reports template versions to a PartialCache.
+type TemplateVersioner = render.TemplateVersioner
+
+// NewPartialCache creates a PartialCache that holds at most maxEntries
+// partials, evicting the least recently used. Zero means no limit.
+func NewPartialCache(maxEntries int) *PartialCache {
+	return render.NewPartialCache(maxEntries)
+}
+
+// WithRegisters returns a copy of ctx that carries registers: the host
+// application's state for a render. Pass it to Template.RenderContext or
+// Template.FRenderContext. Custom tags read and write the registers with
+// render.Context.Registers, and filters whose first parameter is a
+// context.Context read them with RegistersFromContext. Templates can't see
+// them.
+func WithRegisters(ctx context.Context, registers map[string]any) context.Context {
+	return render.WithRegisters(ctx, registers)
+}
+
+// RegistersFromContext returns the registers that ctx carries, or nil.
+func RegistersFromContext(ctx context.Context) map[string]any {
+	return render.RegistersFromContext(ctx)
+}
+
 // IterationKeyedMap returns a map whose {% for %} tag iteration values are its keys, instead of [key, value] pairs.
 // Use this to create a Go map with the semantics of a Ruby struct drop.
 func IterationKeyedMap(m map[string]any) tags.IterationKeyedMap {
 	return m
 }
+
+// Format returns template source in canonical form, using the standard tags and
+// the default delimiters. See Engine.Format.
+func Format(source []byte) ([]byte, error) {
+	out, err := NewEngine().Format(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -40,6 +40,19 @@ type ASTObject struct {
 	Expr expressions.Expression
 }
 
+// ASTError is an object or tag that failed to parse. The parser produces it,
+// in place of failing, in the lax and warn error modes.
+//
+// Recovered is set for an object whose variable or value, followed by
+// zero or more of its filters, parses: for example {{ x | upcase | }}. It
+// holds the object without the filter that failed to parse and those after
+// it, which is what the object renders.
+type ASTError struct {
+	Token
+	Err       Error
+	Recovered *ASTObject
+}
+
 // ASTSeq is a sequence of nodes.
 type ASTSeq struct {
 	sourcelessNode
@@ -6,8 +6,37 @@ import "github.com/osteele/liquid/expressions"
 type Config struct {
 	expressions.Config
 
-	Grammar Grammar
-	Delims  []string
+	Grammar   Grammar
+	Delims    []string
+	ErrorMode ErrorMode
+	// ParseTable, if non-nil, supplies the parses of the expressions and
+	// statements in objects and tags, and records them if it is a recording
+	// table.
+	ParseTable *expressions.ParseTable
+}
+
+// ErrorMode determines how syntax and render errors are reported.
+type ErrorMode int
+
+const (
+	// StrictMode fails on the first syntax or render error. This is the default.
+	StrictMode ErrorMode = iota
+	// WarnMode recovers from errors the way LaxMode does, and also collects
+	// syntax errors so that they can be reported as warnings.
+	WarnMode
+	// LaxMode recovers from syntax errors in objects and tags, and renders
+	// render errors inline as "Liquid error: …" text.
+	LaxMode
+)
+
+// ParseExpression parses an expression, using the configuration's ParseTable.
+func (c *Config) ParseExpression(source string) (expressions.Expression, error) {
+	return c.ParseTable.Parse(source)
+}
+
+// ParseStatement parses a statement, using the configuration's ParseTable.
+func (c *Config) ParseStatement(sel, source string) (*expressions.Statement, error) {
+	return c.ParseTable.ParseStatement(sel, source)
 }
 
 // NewConfig creates a parser Config.
@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"strings"
+)
+
+// A CSTNode is a node of a concrete syntax tree. Unlike the AST, the
+// concrete syntax tree records every byte of the source: the text of each
+// object and tag, with its delimiters, whitespace, and trim markers, and the
+// bodies of comment and raw blocks. The String method of a node returns its
+// source text.
+//
+// The node types are *CSTSeq, *CSTText, *CSTMarkup, and *CSTBlock.
+type CSTNode interface {
+	String() string
+	writeTo(*strings.Builder)
+}
+
+// CSTSeq is a sequence of nodes. It is the root of a concrete syntax tree.
+type CSTSeq struct {
+	Children []CSTNode
+}
+
+// CSTText is a text span. Within a comment or raw block it is the verbatim
+// source of the block's body, which can include objects and tags.
+type CSTText struct {
+	Token
+}
+
+// CSTMarkup is an object, or a tag that is not a block, clause, or end tag.
+// Token.Source is the verbatim text, including the delimiters.
+type CSTMarkup struct {
+	Token
+
+	TrimLeft, TrimRight bool
+}
+
+// CSTBlock is a block, or a clause of a block. Start is its start or clause
+// tag, and End is its end tag. End is nil for a clause.
+type CSTBlock struct {
+	Start   *CSTMarkup
+	Body    []CSTNode
+	Clauses []*CSTBlock
+	End     *CSTMarkup
+}
+
+// IsVerbatim returns true if the block is a comment or raw block, whose body
+// is a single CSTText.
+func (b *CSTBlock) IsVerbatim() bool {
+	return b.Start.Name == "comment" || b.Start.Name == "raw"
+}
+
+func (n *CSTSeq) String() string    { return cstString(n) }
+func (n *CSTText) String() string   { return cstString(n) }
+func (n *CSTMarkup) String() string { return cstString(n) }
+func (n *CSTBlock) String() string  { return cstString(n) }
+
+func cstString(n CSTNode) string {
+	var b strings.Builder
+	n.writeTo(&b)
+
+	return b.String()
+}
+
+func (n *CSTSeq) writeTo(b *strings.Builder) {
+	for _, c := range n.Children {
+		c.writeTo(b)
+	}
+}
+
+func (n *CSTText) writeTo(b *strings.Builder)   { b.WriteString(n.Source) }
+func (n *CSTMarkup) writeTo(b *strings.Builder) { b.WriteString(n.Source) }
+
+func (n *CSTBlock) writeTo(b *strings.Builder) {
+	n.Start.writeTo(b)
+
+	for _, c := range n.Body {
+		c.writeTo(b)
+	}
+
+	for _, c := range n.Clauses {
+		c.writeTo(b)
+	}
+
+	if n.End != nil {
+		n.End.writeTo(b)
+	}
+}
+
+// ParseCST parses a source template into a concrete syntax tree. It uses the
+// grammar to match blocks with their clauses and end tags, as Parse does, but
+// it doesn't parse expressions, and it accepts tags that the grammar doesn't
+// define.
+func (c *Config) ParseCST(source string, loc SourceLoc) (*CSTSeq, Error) { //nolint: gocyclo
+	type frame struct {
+		syntax BlockSyntax
+		block  *CSTBlock
+		ap     *[]CSTNode
+	}
+
+	var (
+		g        = c.Grammar
+		root     = &CSTSeq{}
+		ap       = &root.Children
+		sd       BlockSyntax
+		bn       *CSTBlock
+		stack    []frame
+		last     *CSTMarkup // the most recent markup, for a following right trim
+		trimLeft bool       // whether the next markup has a left trim
+		vb       *CSTBlock  // the current comment or raw block
+		verbatim *CSTText   // the body of vb
+	)
+
+	for _, tok := range Scan(source, loc, c.Delims) {
+		switch tok.Type {
+		case TrimLeftTokenType:
+			trimLeft = true
+			continue
+		case TrimRightTokenType:
+			if last != nil {
+				last.TrimRight = true
+			}
+
+			continue
+		}
+
+		if verbatim != nil && (tok.Type != TagTokenType || tok.Name != "end"+vb.Start.Name) {
+			verbatim.Source += tok.Source
+			last, trimLeft = nil, false
+
+			continue
+		}
+
+		markup := &CSTMarkup{Token: tok, TrimLeft: trimLeft}
+		trimLeft = false
+
+		if verbatim != nil {
+			vb.End, last = markup, markup
+			vb, verbatim = nil, nil
+
+			continue
+		}
+
+		switch tok.Type {
+		case TextTokenType:
+			*ap = append(*ap, &CSTText{tok})
+			continue
+		case ObjTokenType:
+			*ap = append(*ap, markup)
+			last = markup
+
+			continue
+		}
+
+		last = markup
+
+		var (
+			cs      BlockSyntax
+			isBlock bool
+		)
+		if g != nil {
+			cs, isBlock = g.BlockSyntax(tok.Name)
+		}
+
+		switch {
+		case !isBlock:
+			*ap = append(*ap, markup)
+		case tok.Name == "comment" || tok.Name == "raw":
+			verbatim = &CSTText{Token{Type: TextTokenType, SourceLoc: tok.SourceLoc}}
+			vb = &CSTBlock{Start: markup, Body: []CSTNode{verbatim}}
+			*ap = append(*ap, vb)
+		case cs.RequiresParent() && (sd == nil || !cs.CanHaveParent(sd)):
+			suffix := ""
+			if sd != nil {
+				suffix = "; immediate parent is " + sd.TagName()
+			}
+
+			return nil, Errorf(tok, "%s not inside %s%s", tok.Name, strings.Join(cs.ParentTags(), " or "), suffix)
+		case cs.IsBlockStart():
+			stack = append(stack, frame{syntax: sd, block: bn, ap: ap})
+			sd, bn = cs, &CSTBlock{Start: markup}
+			*ap = append(*ap, bn)
+			ap = &bn.Body
+		case cs.IsClause():
+			n := &CSTBlock{Start: markup}
+			bn.Clauses = append(bn.Clauses, n)
+			ap = &n.Body
+		case cs.IsBlockEnd():
+			bn.End = markup
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			sd, bn, ap = f.syntax, f.block, f.ap
+		}
+	}
+
+	if vb != nil {
+		bn = vb
+	}
+
+	if bn != nil {
+		return nil, Errorf(bn.Start, "unterminated %q block", bn.Start.Name)
+	}
+
+	return root, nil
+}
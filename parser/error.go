@@ -29,8 +29,9 @@ func WrapError(err error, loc Locatable) Error {
 
 	if e, ok := err.(Error); ok {
 		// re-wrap the error, if the inner layer implemented the locatable interface
-		// but didn't actually provide any information
-		if e.Path() != "" || loc.SourceLocation().IsZero() {
+		// but didn't actually provide any information. An error with a line
+		// number but no path comes from a template without a path.
+		if e.Path() != "" || e.LineNumber() > 0 || loc.SourceLocation().IsZero() {
 			return e
 		}
 
@@ -0,0 +1,294 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/osteele/liquid/expressions"
+)
+
+// formatIndent is the indentation of the statements of a statements tag,
+// relative to the line that contains the tag, and of the statements in the
+// body of a block within a statements tag.
+const formatIndent = "  "
+
+// Format returns source in canonical form:
+//
+//   - Objects are written {{ expr }}, and tags {% name args %}, with their
+//     trim markers. Object expressions, and the arguments of the standard
+//     tags whose arguments are expressions, are spaced by
+//     expressions.FormatSource. Tag arguments that span lines are kept as
+//     written.
+//   - A statements tag, such as {% liquid %}, whose statements span lines
+//     is written with one statement on each line, indented by two spaces
+//     more than the line that contains the tag, and with its closing
+//     delimiter on a line of its own. Each statement is formatted as the
+//     tag it stands for, and the statements in the body of a block are
+//     indented by two more spaces.
+//   - Text, and the bodies of comment and raw blocks, are kept as written.
+//
+// Formatting doesn't change the rendered output, since it only changes the
+// whitespace within objects and tags. Format returns an error for an object,
+// or for the arguments of one of those standard tags, that doesn't parse,
+// instead of changing its meaning by respacing it.
+func (c *Config) Format(source string, loc SourceLoc) (string, Error) {
+	root, err := c.ParseCST(source, loc)
+	if err != nil {
+		return "", err
+	}
+
+	f := formatter{config: c, delims: normalizeDelims(c.Delims)}
+	f.nodes(root.Children)
+
+	if f.err != nil {
+		return "", f.err
+	}
+
+	return f.out.String(), nil
+}
+
+type formatter struct {
+	config *Config
+	delims []string
+	out    strings.Builder
+	err    Error // the first error
+}
+
+func (f *formatter) nodes(nodes []CSTNode) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *CSTText:
+			f.out.WriteString(n.Source)
+		case *CSTMarkup:
+			f.markup(n)
+		case *CSTBlock:
+			f.block(n)
+		}
+	}
+}
+
+func (f *formatter) block(n *CSTBlock) {
+	f.markup(n.Start)
+
+	if n.IsVerbatim() {
+		for _, c := range n.Body {
+			f.out.WriteString(c.(*CSTText).Source)
+		}
+	} else {
+		f.nodes(n.Body)
+	}
+
+	for _, clause := range n.Clauses {
+		f.markup(clause.Start)
+		f.nodes(clause.Body)
+	}
+
+	f.markup(n.End)
+}
+
+func (f *formatter) markup(m *CSTMarkup) {
+	var open, close, content string
+
+	if m.Type == ObjTokenType {
+		open, close = f.delims[0], f.delims[1]
+		content = strings.TrimSpace(m.Args)
+
+		if _, err := f.config.ParseExpression(content); err != nil {
+			f.fail(WrapError(err, m))
+		} else if s, err := expressions.FormatSource(content); err == nil {
+			content = s
+		}
+	} else {
+		open, close = f.delims[2], f.delims[3]
+		content = m.Name
+
+		if args := strings.TrimSpace(m.Args); args != "" {
+			if f.isStatementsTag(m.Name) {
+				content += f.formatStatements(m, args)
+			} else {
+				s, err := f.formatTagArgs(m.Name, args)
+				if err != nil {
+					f.fail(WrapError(err, m))
+				}
+
+				content += " " + s
+			}
+		}
+	}
+
+	f.out.WriteString(open)
+
+	if m.TrimLeft {
+		f.out.WriteByte('-')
+	}
+
+	f.out.WriteString(" " + content)
+
+	// a closing delimiter on a line of its own follows its indentation
+	if last := content[strings.LastIndexByte(content, '\n')+1:]; strings.TrimSpace(last) != "" {
+		f.out.WriteByte(' ')
+	}
+
+	if m.TrimRight {
+		f.out.WriteByte('-')
+	}
+
+	f.out.WriteString(close)
+}
+
+// fail records err, if it is the first error.
+func (f *formatter) fail(err Error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *formatter) isStatementsTag(name string) bool {
+	sg, ok := f.config.Grammar.(StatementsGrammar)
+	return ok && sg.IsStatementsTag(name)
+}
+
+// formatStatements formats the arguments of a statements tag. A single
+// statement stays on the tag's line; otherwise each statement is written on a
+// line of its own, and the result ends with the indentation of the tag's
+// line, for the closing delimiter.
+func (f *formatter) formatStatements(m *CSTMarkup, args string) string {
+	loc := m.SourceLoc
+	if i := strings.Index(m.Source, args); i > 0 {
+		loc.LineNo += strings.Count(m.Source[:i], "\n")
+	}
+
+	lines := strings.Split(args, "\n")
+	if len(lines) == 1 {
+		return " " + f.formatStatement(args, loc)
+	}
+
+	var (
+		b        strings.Builder
+		indent   = f.lineIndent() + formatIndent
+		depth    = 0  // the number of enclosing blocks
+		verbatim = "" // the end tag of an enclosing comment or raw block
+	)
+
+	b.WriteByte('\n')
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			b.WriteByte('\n')
+			continue
+		}
+
+		name := line
+		if m := statementMatcher.FindStringSubmatch(line); m != nil {
+			name = m[1]
+		}
+
+		switch {
+		case verbatim != "" && name != verbatim:
+			b.WriteString(indent + strings.Repeat(formatIndent, depth) + line + "\n")
+			continue
+		case name == "comment" || name == "raw":
+			verbatim = "end" + name
+		case name == verbatim:
+			verbatim = ""
+		}
+
+		lineDepth := depth
+		if bs, ok := f.config.Grammar.BlockSyntax(name); ok {
+			switch {
+			case bs.IsBlockEnd():
+				depth = max(depth-1, 0)
+				lineDepth = depth
+			case bs.IsClause():
+				lineDepth = max(depth-1, 0)
+			case bs.IsBlockStart() || name == "comment" || name == "raw":
+				depth++
+			}
+		}
+
+		lineLoc := loc
+		lineLoc.LineNo += i
+		b.WriteString(indent + strings.Repeat(formatIndent, lineDepth) + f.formatStatement(line, lineLoc) + "\n")
+	}
+
+	b.WriteString(indent[:len(indent)-len(formatIndent)])
+
+	return b.String()
+}
+
+// formatStatement formats a line of a statements tag as the tag it stands for.
+func (f *formatter) formatStatement(line string, loc SourceLoc) string {
+	if strings.HasPrefix(line, "#") {
+		return line
+	}
+
+	m := statementMatcher.FindStringSubmatch(line)
+	if m == nil || m[2] == "" {
+		return line
+	}
+
+	s, err := f.formatTagArgs(m[1], strings.TrimSpace(m[2]))
+	if err != nil {
+		f.fail(WrapError(err, Token{Type: TagTokenType, SourceLoc: loc, Name: m[1], Args: m[2], Source: line}))
+	}
+
+	return m[1] + " " + s
+}
+
+// lineIndent returns the leading whitespace of the last line of the output.
+func (f *formatter) lineIndent() string {
+	s := f.out.String()
+	line := s[strings.LastIndexByte(s, '\n')+1:]
+
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// formatTagArgs formats the arguments of a tag. It returns an error if the
+// tag's arguments are expressions or statements, and args doesn't parse.
+func (f *formatter) formatTagArgs(name, args string) (string, error) {
+	ok, err := f.config.checkTagArgs(name, args)
+	if err != nil {
+		return args, err
+	}
+
+	switch {
+	case strings.Contains(args, "\n"):
+		return args, nil
+	case ok:
+		return expressions.FormatSource(args)
+	default:
+		return collapseSpaces(args), nil
+	}
+}
+
+// collapseSpaces replaces each run of whitespace outside of quotes by a single space.
+func collapseSpaces(s string) string {
+	var (
+		b     strings.Builder
+		quote rune
+		space bool
+	)
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == ' ' || r == '\t':
+			space = true
+			continue
+		case r == '"' || r == '\'':
+			quote = r
+		}
+
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var cstTests = []string{
+	``,
+	`text`,
+	`a{{ x }}b`,
+	`{{- x -}} {%- if a -%}{{x}}{%else   %}y{%- endif%}`,
+	"{% for item in list %}\n  {{ item }}\n{% endfor %}",
+	"{% raw %} {{ x }} {% if %}{%- endraw %}",
+	"{% comment %}\n  {% for %}\n{% endcomment -%} z",
+	`{% if a %}{% comment %}{% endif %}{% endcomment %}{% endif %}`,
+	`{% undefined_tag a  b %}`,
+}
+
+func TestParseCST(t *testing.T) {
+	cfg := Config{Grammar: grammarFake{}}
+
+	for i, test := range cstTests {
+		t.Run(fmt.Sprintf("%02d", i+1), func(t *testing.T) {
+			root, err := cfg.ParseCST(test, SourceLoc{})
+			require.NoError(t, err, test)
+			require.Equal(t, test, root.String())
+		})
+	}
+
+	root, err := cfg.ParseCST(`{%- if a %}x{% else -%}y{% endif %}`, SourceLoc{})
+	require.NoError(t, err)
+	require.Len(t, root.Children, 1)
+	block := root.Children[0].(*CSTBlock)
+	require.True(t, block.Start.TrimLeft)
+	require.False(t, block.Start.TrimRight)
+	require.Len(t, block.Clauses, 1)
+	require.True(t, block.Clauses[0].Start.TrimRight)
+	require.Equal(t, "endif", block.End.Name)
+
+	for i, test := range parseErrorTests {
+		t.Run(fmt.Sprintf("error %02d", i+1), func(t *testing.T) {
+			_, err := cfg.ParseCST(test.in, SourceLoc{})
+			require.Errorf(t, err, test.in)
+			require.Containsf(t, err.Error(), test.expected, test.in)
+		})
+	}
+}
+
+var formatTests = []struct{ in, expected string }{
+	{`{{x}}`, `{{ x }}`},
+	{`{{-   x|default:"a  b"-}}`, `{{- x | default: "a  b" -}}`},
+	{`{%if a==1 and b%}{%endif%}`, `{% if a == 1 and b %}{% endif %}`},
+	{`{%for x in (1..n) limit:2%}{%endfor%}`, `{% for x in (1..n) limit: 2 %}{% endfor %}`},
+	{`{%   custom   a   "b  c" %}`, `{% custom a "b  c" %}`},
+	{
+		"{%if a%}\nx\n{%else%}\n      y\n{%endif%}",
+		"{% if a %}\nx\n{% else %}\n      y\n{% endif %}",
+	},
+	{
+		"  {% for x in xs %}\n<pre>\n  {{x}}\n</pre>\n      {% endfor %}",
+		"  {% for x in xs %}\n<pre>\n  {{ x }}\n</pre>\n      {% endfor %}",
+	},
+	{"a   \n   \nb", "a   \n   \nb"},
+	{
+		"{% if a %}\n{% raw %}\n{{x}}\n  {% endraw %}\n{% endif %}",
+		"{% if a %}\n{% raw %}\n{{x}}\n  {% endraw %}\n{% endif %}",
+	},
+	{
+		"{% if a %}\n{% comment %}\n   note\n{%endcomment%}\n{% endif %}",
+		"{% if a %}\n{% comment %}\n   note\n{% endcomment %}\n{% endif %}",
+	},
+	{`{%liquid echo  x|upcase%}`, `{% liquid echo x | upcase %}`},
+	{
+		"{% liquid assign x=1\n  echo x %}",
+		"{% liquid\n  assign x = 1\n  echo x\n%}",
+	},
+	{
+		"<p>\n  {%- liquid\n    # note\n    if a\n      echo   x\n\n    endif\n-%}\n</p>",
+		"<p>\n  {%- liquid\n    # note\n    if a\n      echo x\n\n    endif\n  -%}\n</p>",
+	},
+	{
+		"{% liquid\nfor x in xs\nif x\necho x\nelse\n        echo 'none'\nendif\nendfor\n%}",
+		"{% liquid\n  for x in xs\n    if x\n      echo x\n    else\n      echo 'none'\n    endif\n  endfor\n%}",
+	},
+	{
+		"{% liquid\n  comment\n  if a b c\n      endcomment\n  endif %}",
+		"{% liquid\n  comment\n    if a b c\n  endcomment\n  endif\n%}",
+	},
+}
+
+func TestFormat(t *testing.T) {
+	cfg := Config{Grammar: grammarFake{}}
+
+	for i, test := range formatTests {
+		t.Run(fmt.Sprintf("%02d", i+1), func(t *testing.T) {
+			out, err := cfg.Format(test.in, SourceLoc{})
+			require.NoError(t, err, test.in)
+			require.Equal(t, test.expected, out, test.in)
+
+			again, err := cfg.Format(out, SourceLoc{})
+			require.NoError(t, err)
+			require.Equal(t, out, again, "Format is idempotent")
+		})
+	}
+
+	_, err := cfg.Format(`{% if a %}`, SourceLoc{})
+	require.Error(t, err)
+}
+
+func TestFormat_errors(t *testing.T) {
+	cfg := Config{Grammar: grammarFake{}}
+
+	// Arguments that the expression grammar rejects aren't respaced, which
+	// could change their meaning.
+	tests := []struct {
+		in   string
+		line int
+	}{
+		{`{{ 'it''s' }}`, 1},
+		{`{{ }}`, 1},
+		{"\n{% if 'it''s' %}{% endif %}", 2},
+		{`{% for x %}{% endfor %}`, 1},
+		{"{% liquid\n  assign x = 1\n  echo 'it''s'\n%}", 3},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			_, err := cfg.Format(test.in, SourceLoc{LineNo: 1})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "syntax error")
+			require.Equal(t, test.line, err.LineNumber())
+		})
+	}
+
+	// The arguments of other tags are only collapsed.
+	out, err := cfg.Format(`{%   custom   'it''s' %}`, SourceLoc{})
+	require.NoError(t, err)
+	require.Equal(t, `{% custom 'it''s' %}`, out)
+}
+
+func TestFormat_delims(t *testing.T) {
+	cfg := Config{Grammar: grammarFake{}, Delims: []string{"<<", ">>", "<%", "%>"}}
+	out, err := cfg.Format("<%if a%>\n<<x>>\n<%endif%>", SourceLoc{})
+	require.NoError(t, err)
+	require.Equal(t, "<% if a %>\n<< x >>\n<% endif %>", out)
+}
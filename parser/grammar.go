@@ -19,3 +19,11 @@ type BlockSyntax interface {
 
 // Grammar returns a configuration's grammar.
 // func (c *Config) Grammar() Grammar { return c }
+
+// StatementsGrammar is implemented by a Grammar that defines statements tags.
+// The argument of a statements tag, such as {% liquid %}, is a sequence of tag
+// statements without delimiters, one per line. The parser parses them as
+// though each were a separate tag.
+type StatementsGrammar interface {
+	IsStatementsTag(string) bool
+}
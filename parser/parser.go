@@ -4,8 +4,6 @@ package parser
 import (
 	"fmt"
 	"strings"
-
-	"github.com/osteele/liquid/expressions"
 )
 
 // Parse parses a source template. It returns an AST root, that can be compiled and evaluated.
@@ -14,6 +12,13 @@ func (c *Config) Parse(source string, loc SourceLoc) (ASTNode, Error) {
 	return c.parseTokens(tokens)
 }
 
+// ParseStatements parses the argument of a statements tag, such as
+// {% liquid %}. Each line is a tag without delimiters. Blocks must end within
+// the argument.
+func (c *Config) ParseStatements(source string, loc SourceLoc) (ASTNode, Error) {
+	return c.parseTokens(ScanStatements(source, loc))
+}
+
 // Parse creates an AST from a sequence of tokens.
 func (c *Config) parseTokens(tokens []Token) (ASTNode, Error) { //nolint: gocyclo
 	// a stack of control tag state, for matching nested {%if}{%endif%} etc.
@@ -51,8 +56,12 @@ func (c *Config) parseTokens(tokens []Token) (ASTNode, Error) { //nolint: gocycl
 				rawTag.Slices = append(rawTag.Slices, tok.Source)
 			}
 		case tok.Type == ObjTokenType:
-			expr, err := expressions.Parse(tok.Args)
+			expr, err := c.ParseExpression(tok.Args)
 			if err != nil {
+				if c.ErrorMode != StrictMode {
+					*ap = append(*ap, &ASTError{tok, WrapError(err, tok), c.recoverObject(tok)})
+					continue
+				}
 				return nil, WrapError(err, tok)
 			}
 
@@ -64,7 +73,19 @@ func (c *Config) parseTokens(tokens []Token) (ASTNode, Error) { //nolint: gocycl
 				return nil, Errorf(tok, "Grammar field is nil")
 			}
 
-			if cs, ok := g.BlockSyntax(tok.Name); ok {
+			if sg, ok := g.(StatementsGrammar); ok && sg.IsStatementsTag(tok.Name) {
+				loc := tok.SourceLoc
+				if i := strings.Index(tok.Source, tok.Args); i > 0 {
+					loc.LineNo += strings.Count(tok.Source[:i], "\n")
+				}
+
+				seq, err := c.ParseStatements(tok.Args, loc)
+				if err != nil {
+					return nil, err
+				}
+
+				*ap = append(*ap, seq)
+			} else if cs, ok := g.BlockSyntax(tok.Name); ok {
 				switch {
 				case tok.Name == "comment":
 					inComment = true
@@ -118,3 +139,51 @@ func (c *Config) parseTokens(tokens []Token) (ASTNode, Error) { //nolint: gocycl
 
 	return root, nil
 }
+
+// recoverObject returns the object that the longest prefix of tok's filter
+// chain, that parses, would produce, or nil if not even the value before the
+// first filter parses.
+func (c *Config) recoverObject(tok Token) *ASTObject {
+	segments := splitFilters(tok.Args)
+	for n := len(segments) - 1; n > 0; n-- {
+		expr, err := c.ParseExpression(strings.Join(segments[:n], "|"))
+		if err == nil {
+			return &ASTObject{tok, expr}
+		}
+	}
+
+	return nil
+}
+
+// splitFilters splits the source of an object at each "|" that is outside of
+// strings and parentheses. A double-quoted string can contain escapes.
+func splitFilters(source string) []string {
+	var (
+		segments []string
+		start    int
+		depth    int
+		quote    byte
+	)
+
+	for i := 0; i < len(source); i++ {
+		switch ch := source[i]; {
+		case quote == '"' && ch == '\\':
+			i++
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == '|' && depth == 0:
+			segments = append(segments, source[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(segments, source[start:])
+}
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/expressions"
 )
 
 type (
@@ -23,6 +25,8 @@ func (g grammarFake) BlockSyntax(w string) (BlockSyntax, bool) {
 	}
 }
 
+func (g grammarFake) IsStatementsTag(w string) bool { return w == "liquid" }
+
 func (g blockSyntaxFake) IsBlock() bool { return true }
 func (g blockSyntaxFake) CanHaveParent(p BlockSyntax) bool {
 	return string(g) == "end"+p.TagName() || (g == "else" && p.TagName() == "if")
@@ -79,6 +83,36 @@ func TestParser(t *testing.T) {
 	}
 }
 
+func TestParse_errorModes(t *testing.T) {
+	cfg := Config{Grammar: grammarFake{}}
+	_, err := cfg.Parse(`{{ syntax error }}`, SourceLoc{})
+	require.Error(t, err)
+
+	cfg.ErrorMode = LaxMode
+	root, err := cfg.Parse(`a{{ syntax error }}b`, SourceLoc{})
+	require.NoError(t, err)
+	children := root.(*ASTSeq).Children
+	require.Len(t, children, 3)
+	require.IsType(t, &ASTError{}, children[1])
+	require.Contains(t, children[1].(*ASTError).Err.Error(), "syntax error")
+	require.Nil(t, children[1].(*ASTError).Recovered)
+
+	root, err = cfg.Parse(`{{ x | upcase | 'a|b' | }}`, SourceLoc{})
+	require.NoError(t, err)
+	recovered := root.(*ASTSeq).Children[0].(*ASTError).Recovered
+	require.NotNil(t, recovered)
+	require.Equal(t, "x | upcase", expressions.SyntaxTree(recovered.Expr).String())
+
+	_, err = cfg.Parse(`{% if test %}`, SourceLoc{})
+	require.Error(t, err)
+}
+
+func TestSplitFilters(t *testing.T) {
+	require.Equal(t, []string{"x"}, splitFilters("x"))
+	require.Equal(t, []string{"x ", " f: 'a|b', \"c\\\"|\" ", " g"}, splitFilters(`x | f: 'a|b', "c\"|" | g`))
+	require.Equal(t, []string{"(x | f) ", ""}, splitFilters("(x | f) |"))
+}
+
 func TestNewConfig(t *testing.T) {
 	g := grammarFake{}
 	cfg := NewConfig(g)
@@ -155,6 +189,14 @@ func TestWrapError(t *testing.T) {
 		wrapped := WrapError(inner, &token)
 		require.Equal(t, "f.html", wrapped.Path())
 	})
+
+	t.Run("Error input with only a line number", func(t *testing.T) {
+		lineToken := Token{SourceLoc: SourceLoc{LineNo: 4}, Source: "{% y %}"}
+		inner := Errorf(&lineToken, "inner")
+		wrapped := WrapError(inner, &token)
+		require.Equal(t, inner, wrapped)
+		require.Equal(t, 4, wrapped.LineNumber())
+	})
 }
 
 func TestToken_IsZero(t *testing.T) {
@@ -17,18 +17,7 @@ var customTokenMatchers sync.Map // key: [4]string, value: *regexp.Regexp
 
 // Scan breaks a string into a sequence of Tokens.
 func Scan(data string, loc SourceLoc, delims []string) (tokens []Token) {
-	if len(delims) != 4 {
-		delims = defaultDelims
-	} else {
-		normalized := make([]string, len(defaultDelims))
-		for i, delim := range delims {
-			if delim == "" {
-				delim = defaultDelims[i]
-			}
-			normalized[i] = delim
-		}
-		delims = normalized
-	}
+	delims = normalizeDelims(delims)
 
 	var tokenMatcher *regexp.Regexp
 	if delims[0] == "{{" && delims[1] == "}}" && delims[2] == "{%" && delims[3] == "%}" {
@@ -114,6 +103,54 @@ func Scan(data string, loc SourceLoc, delims []string) (tokens []Token) {
 	return tokens
 }
 
+// ScanStatements breaks the argument of a statements tag, such as
+// {% liquid %}, into a sequence of tag Tokens, one for each line. Blank lines,
+// and lines that begin with "#", are skipped. The Source of each token is the
+// text of its line.
+func ScanStatements(data string, loc SourceLoc) (tokens []Token) {
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m := statementMatcher.FindStringSubmatch(line)
+		if m == nil {
+			// not a tag name; let the compiler report an undefined tag
+			m = []string{line, line, ""}
+		}
+
+		tokens = append(tokens, Token{
+			Type:      TagTokenType,
+			SourceLoc: SourceLoc{Pathname: loc.Pathname, LineNo: loc.LineNo + i},
+			Name:      m[1],
+			Args:      m[2],
+			Source:    line,
+		})
+	}
+
+	return tokens
+}
+
+var statementMatcher = regexp.MustCompile(`^(\w+)(?:\s+(.*))?$`)
+
+// normalizeDelims replaces missing and empty delimiters by the defaults.
+func normalizeDelims(delims []string) []string {
+	if len(delims) != 4 {
+		return defaultDelims
+	}
+
+	normalized := make([]string, len(defaultDelims))
+	for i, delim := range delims {
+		if delim == "" {
+			delim = defaultDelims[i]
+		}
+		normalized[i] = delim
+	}
+
+	return normalized
+}
+
 func hasTrimLeft(source, leftDelim string) bool {
 	index := len(leftDelim)
 	return index < len(source) && source[index] == '-'
@@ -235,3 +235,22 @@ func TestScanCustomDelimiterWhitespaceControl(t *testing.T) {
 		{Type: TrimRightTokenType},
 	}, tokens)
 }
+
+func TestScanStatements(t *testing.T) {
+	tokens := ScanStatements("\n  assign a = 1\n\n  # comment\n  endif  \n  x-y", SourceLoc{Pathname: "page.html", LineNo: 3})
+	require.Len(t, tokens, 3)
+
+	require.Equal(t, TagTokenType, tokens[0].Type)
+	require.Equal(t, "assign", tokens[0].Name)
+	require.Equal(t, "a = 1", tokens[0].Args)
+	require.Equal(t, "assign a = 1", tokens[0].Source)
+	require.Equal(t, SourceLoc{Pathname: "page.html", LineNo: 4}, tokens[0].SourceLoc)
+
+	require.Equal(t, "endif", tokens[1].Name)
+	require.Empty(t, tokens[1].Args)
+	require.Equal(t, 7, tokens[1].SourceLoc.LineNo)
+
+	require.Equal(t, "x-y", tokens[2].Name)
+
+	require.Empty(t, ScanStatements("", SourceLoc{}))
+}
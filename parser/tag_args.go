@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"github.com/osteele/liquid/ast"
+	"github.com/osteele/liquid/expressions"
+)
+
+// tagArgSelectors are the statement selectors of the standard tags whose
+// arguments are expressions or statements. The arguments of a tag whose
+// selector is empty are an expression.
+var tagArgSelectors = map[string]string{
+	"assign":   expressions.AssignStatementSelector,
+	"case":     "",
+	"cycle":    expressions.CycleStatementSelector,
+	"echo":     "",
+	"elsif":    "",
+	"for":      expressions.LoopStatementSelector,
+	"if":       "",
+	"tablerow": expressions.LoopStatementSelector,
+	"unless":   "",
+	"when":     expressions.WhenStatementSelector,
+}
+
+// ParseTagArgs returns the syntax tree of the arguments of one of the
+// standard tags whose arguments are an expression or a statement, such as if
+// or assign. It returns nil for other tags, since their syntax is unknown.
+func ParseTagArgs(name, args string) (ast.Stmt, error) {
+	sel, ok := tagArgSelectors[name]
+	if !ok {
+		return nil, nil
+	}
+
+	return expressions.ParseSyntax(sel, args)
+}
+
+// checkTagArgs checks the arguments of a tag, using the configuration's
+// ParseTable. It reports whether the tag is one of the standard tags whose
+// arguments are an expression or a statement.
+func (c *Config) checkTagArgs(name, args string) (bool, error) {
+	sel, ok := tagArgSelectors[name]
+
+	switch {
+	case !ok:
+		return false, nil
+	case sel == "":
+		_, err := c.ParseExpression(args)
+		return true, err
+	default:
+		_, err := c.ParseStatement(sel, args)
+		return true, err
+	}
+}
@@ -0,0 +1,199 @@
+package render
+
+import (
+	"github.com/osteele/liquid/expressions"
+)
+
+// NodeAnalysis describes what a tag reads and defines. Tag definitions
+// supply it, via AddTagAnalyzer and AddBlockAnalyzer, so that Analyze can see
+// inside their arguments.
+type NodeAnalysis struct {
+	// Arguments are the expressions that the tag evaluates, including those
+	// in the clauses of a block.
+	Arguments []expressions.Expression
+	// Assigns are the variables that the tag sets. They are defined from the
+	// end of the tag onwards.
+	Assigns []string
+	// Locals are the variables that a block defines within its body, but not
+	// within its clauses; for example, the loop variable of a for block.
+	Locals []string
+	// Partials are the names of the templates that the tag renders.
+	Partials []string
+}
+
+// TagAnalyzer analyzes the arguments of a tag.
+type TagAnalyzer func(args string) NodeAnalysis
+
+// ConfigTagAnalyzer is a TagAnalyzer that also receives the configuration
+// that analyzes the tag.
+type ConfigTagAnalyzer func(c *Config, args string) NodeAnalysis
+
+// BlockAnalyzer analyzes the arguments of a block and its clauses.
+type BlockAnalyzer func(BlockNode) NodeAnalysis
+
+// ConfigBlockAnalyzer is a BlockAnalyzer that also receives the
+// configuration that analyzes the block.
+type ConfigBlockAnalyzer func(c *Config, node BlockNode) NodeAnalysis
+
+// AddTagAnalyzer sets the analyzer for a tag definition.
+func (c *Config) AddTagAnalyzer(name string, fn TagAnalyzer) {
+	c.tagAnalyzers[name] = func(_ *Config, args string) NodeAnalysis {
+		return fn(args)
+	}
+}
+
+// AddConfigTagAnalyzer sets the analyzer for a tag definition whose syntax
+// depends on the configuration, such as one added by AddConfigTag.
+func (c *Config) AddConfigTagAnalyzer(name string, fn ConfigTagAnalyzer) {
+	c.tagAnalyzers[name] = fn
+}
+
+// AddBlockAnalyzer sets the analyzer for a block definition.
+func (c *Config) AddBlockAnalyzer(name string, fn BlockAnalyzer) {
+	c.blockAnalyzers[name] = func(_ *Config, node BlockNode) NodeAnalysis {
+		return fn(node)
+	}
+}
+
+// AddConfigBlockAnalyzer sets the analyzer for a block definition whose
+// syntax depends on the configuration, such as one whose compiler is set
+// with ConfigCompiler.
+func (c *Config) AddConfigBlockAnalyzer(name string, fn ConfigBlockAnalyzer) {
+	c.blockAnalyzers[name] = fn
+}
+
+// Analysis is a static report of the variables, filters, and partials that a
+// template uses. Each list is in order of first appearance, without duplicates.
+type Analysis struct {
+	// Globals are the variable paths that the template reads from its bindings.
+	Globals []expressions.VariablePath
+	// Locals are the variable paths that the template reads from variables
+	// that it defines itself, with assign, capture, or a loop.
+	Locals []expressions.VariablePath
+	// Filters are the names of the filters that the template applies.
+	Filters []string
+	// Partials are the names of the templates that the template includes or
+	// renders. Names that are computed at render time are not listed.
+	Partials []string
+}
+
+// Analyze walks a compiled template, and reports the variables, filters, and
+// partials that it uses. Tags and blocks without an analyzer contribute only
+// the contents of their bodies.
+//
+// A variable is local from the end of the tag that assigns it, whether or
+// not that tag is executed, so a template that reads a variable before it
+// assigns it reads a global.
+func (c *Config) Analyze(root Node) Analysis {
+	a := analyzer{
+		config:   c,
+		assigned: map[string]bool{},
+		locals:   map[string]int{},
+		seen:     map[string]bool{},
+	}
+	a.node(root)
+
+	return a.Analysis
+}
+
+type analyzer struct {
+	Analysis
+
+	config   *Config
+	assigned map[string]bool // variables set by assign and capture
+	locals   map[string]int  // the number of enclosing blocks that define a variable
+	seen     map[string]bool // keys of the items already in Analysis
+}
+
+func (a *analyzer) node(n Node) {
+	switch n := n.(type) {
+	case *BlockNode:
+		var na NodeAnalysis
+		if fn, ok := a.config.blockAnalyzers[n.Name]; ok {
+			na = fn(a.config, *n)
+		}
+
+		a.arguments(na)
+
+		for _, name := range na.Locals {
+			a.locals[name]++
+		}
+
+		a.nodes(n.Body)
+
+		for _, name := range na.Locals {
+			a.locals[name]--
+		}
+
+		for _, clause := range n.Clauses {
+			a.nodes(clause.Body)
+		}
+
+		a.assigns(na)
+	case *TagNode:
+		if fn, ok := a.config.tagAnalyzers[n.Name]; ok {
+			na := fn(a.config, n.Args)
+			a.arguments(na)
+			a.assigns(na)
+		}
+	case *ObjectNode:
+		a.expression(n.expr)
+	case *SeqNode:
+		a.nodes(n.Children)
+	}
+}
+
+func (a *analyzer) nodes(nodes []Node) {
+	for _, n := range nodes {
+		a.node(n)
+	}
+}
+
+func (a *analyzer) arguments(na NodeAnalysis) {
+	for _, expr := range na.Arguments {
+		a.expression(expr)
+	}
+
+	for _, name := range na.Partials {
+		if a.add("partial:" + name) {
+			a.Partials = append(a.Partials, name)
+		}
+	}
+}
+
+func (a *analyzer) assigns(na NodeAnalysis) {
+	for _, name := range na.Assigns {
+		a.assigned[name] = true
+	}
+}
+
+func (a *analyzer) expression(expr expressions.Expression) {
+	variables, filters := expressions.Analyze(expr)
+	for _, path := range variables {
+		name := path.Name()
+		if a.assigned[name] || a.locals[name] > 0 {
+			if a.add("local:" + path.String()) {
+				a.Locals = append(a.Locals, path)
+			}
+		} else if a.add("global:" + path.String()) {
+			a.Globals = append(a.Globals, path)
+		}
+	}
+
+	for _, name := range filters {
+		if a.add("filter:" + name) {
+			a.Filters = append(a.Filters, name)
+		}
+	}
+}
+
+// add records that key has been seen, and reports whether it is new.
+func (a *analyzer) add(key string) bool {
+	if a.seen[key] {
+		return false
+	}
+
+	a.seen[key] = true
+
+	return true
+}
@@ -0,0 +1,44 @@
+package render
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	e "github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+)
+
+func TestAnalyze(t *testing.T) {
+	cfg := NewConfig()
+	cfg.AddTag("set", func(string) (func(io.Writer, Context) error, error) { return noopRenderer, nil })
+	cfg.AddTagAnalyzer("set", func(args string) NodeAnalysis {
+		expr, err := e.Parse(args)
+		require.NoError(t, err)
+
+		return NodeAnalysis{Arguments: []e.Expression{expr}, Assigns: []string{"v"}}
+	})
+	cfg.AddBlock("with").Compiler(func(BlockNode) (func(io.Writer, Context) error, error) { return noopRenderer, nil })
+	cfg.AddBlockAnalyzer("with", func(node BlockNode) NodeAnalysis {
+		return NodeAnalysis{Locals: []string{node.Args}, Partials: []string{"partial"}}
+	})
+	cfg.AddBlock("other").Compiler(func(BlockNode) (func(io.Writer, Context) error, error) { return noopRenderer, nil })
+
+	src := `{{ v }}{% set a.b | upcase %}{{ v.x }}` +
+		`{% with w %}{{ w.y }}{% endwith %}{{ w }}` +
+		`{% other %}{{ c | upcase | downcase }}{{ a.b }}{% endother %}`
+	root, err := cfg.Compile(src, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	analysis := cfg.Analyze(root)
+	require.Equal(t, []e.VariablePath{{"v"}, {"a", "b"}, {"w"}, {"c"}}, analysis.Globals)
+	require.Equal(t, []e.VariablePath{{"v", "x"}, {"w", "y"}}, analysis.Locals)
+	require.Equal(t, []string{"upcase", "downcase"}, analysis.Filters)
+	require.Equal(t, []string{"partial"}, analysis.Partials)
+
+	cfg.UnregisterTag("set")
+	cfg.AddTag("set", func(string) (func(io.Writer, Context) error, error) { return noopRenderer, nil })
+	analysis = cfg.Analyze(root)
+	require.Equal(t, []e.VariablePath{{"v"}, {"v", "x"}, {"w"}, {"c"}, {"a", "b"}}, analysis.Globals)
+}
@@ -0,0 +1,211 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/osteele/liquid/ast"
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+)
+
+// ToAST returns the syntax tree of a compiled template. The tree, including
+// the expression trees of its objects, is a copy; modifying it doesn't affect
+// the template.
+func ToAST(n Node) ast.Node {
+	switch n := n.(type) {
+	case *BlockNode:
+		return blockToAST(n)
+	case *ObjectNode:
+		return &ast.Object{Loc: astLoc(n.Token), Expr: ast.CopyExpr(expressions.SyntaxTree(n.expr))}
+	case *RawNode:
+		return &ast.Raw{Source: strings.Join(n.slices, "")}
+	case *SeqNode:
+		return &ast.Seq{Children: nodesToAST(n.Children)}
+	case *TagNode:
+		// In the lax and warn error modes, an object that failed to parse
+		// compiles to a tag node.
+		if n.Type == parser.ObjTokenType {
+			return &ast.Invalid{Loc: astLoc(n.Token), Source: n.Source}
+		}
+
+		return &ast.Tag{Loc: astLoc(n.Token), Name: n.Name, Args: n.Args, Stmt: tagStmt(n.Name, n.Args)}
+	case *TextNode:
+		return &ast.Text{Loc: astLoc(n.Token), Source: n.Source}
+	case *TrimNode:
+		if n.TrimDirection == parser.Left {
+			return &ast.Trim{Direction: ast.TrimLeft}
+		}
+
+		return &ast.Trim{Direction: ast.TrimRight}
+	default:
+		panic(fmt.Errorf("unexpected node type %T", n))
+	}
+}
+
+func blockToAST(n *BlockNode) *ast.Block {
+	b := &ast.Block{
+		Loc:  astLoc(n.Token),
+		Name: n.Name,
+		Args: n.Args,
+		Stmt: tagStmt(n.Name, n.Args),
+		Body: nodesToAST(n.Body),
+	}
+	for _, clause := range n.Clauses {
+		b.Clauses = append(b.Clauses, blockToAST(clause))
+	}
+
+	return b
+}
+
+// tagStmt returns the syntax tree of the arguments of a standard tag whose
+// arguments are an expression or a statement, or nil. A tag whose arguments
+// don't parse can only be in a template that was compiled in the lax or warn
+// error mode, in which it renders an error; it keeps its arguments as text.
+func tagStmt(name, args string) ast.Stmt {
+	stmt, err := parser.ParseTagArgs(name, args)
+	if err != nil {
+		return nil
+	}
+
+	return stmt
+}
+
+func nodesToAST(nodes []Node) []ast.Node {
+	out := make([]ast.Node, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, ToAST(n))
+	}
+
+	return out
+}
+
+func astLoc(tok parser.Token) ast.SourceLoc {
+	return ast.SourceLoc{Pathname: tok.SourceLoc.Pathname, LineNo: tok.SourceLoc.LineNo}
+}
+
+// CompileAST compiles a syntax tree, such as one that ToAST returned and that
+// has since been modified. It checks the tree against the grammar, as Compile
+// checks source text, and returns the same warnings as CompileWithWarnings.
+func (c *Config) CompileAST(root ast.Node) (Node, []parser.Error, parser.Error) {
+	n, err := c.astToParseTree(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c.compile(n)
+}
+
+// astToParseTree converts a syntax tree into the tree that the parser produces.
+func (c *Config) astToParseTree(n ast.Node) (parser.ASTNode, parser.Error) { //nolint: gocyclo
+	switch n := n.(type) {
+	case *ast.Block:
+		return c.astBlockToParseTree(n, nil)
+	case *ast.Invalid:
+		tok := parser.Token{Type: parser.ObjTokenType, SourceLoc: parserLoc(n.Loc), Source: n.Source}
+		return &parser.ASTError{Token: tok, Err: parser.Errorf(tok, "syntax error in %s", n.Source)}, nil
+	case *ast.Object:
+		tok := parser.Token{Type: parser.ObjTokenType, SourceLoc: parserLoc(n.Loc), Source: n.String()}
+		if n.Expr == nil {
+			return nil, parser.Errorf(tok, "object without an expression")
+		}
+
+		tok.Args = n.Expr.String()
+
+		expr, err := expressions.Compile(n.Expr)
+		if err != nil {
+			if c.ErrorMode != parser.StrictMode {
+				return &parser.ASTError{Token: tok, Err: parser.WrapError(err, tok)}, nil
+			}
+
+			return nil, parser.WrapError(err, tok)
+		}
+
+		return &parser.ASTObject{Token: tok, Expr: expr}, nil
+	case *ast.Raw:
+		return &parser.ASTRaw{Slices: []string{n.Source}}, nil
+	case *ast.Seq:
+		children, err := c.astNodesToParseTree(n.Children)
+		if err != nil {
+			return nil, err
+		}
+
+		return &parser.ASTSeq{Children: children}, nil
+	case *ast.Tag:
+		tok := parser.Token{Type: parser.TagTokenType, SourceLoc: parserLoc(n.Loc), Name: n.Name, Args: n.TagArgs(), Source: n.String()}
+		if _, ok := c.findBlockDef(n.Name); ok {
+			return nil, parser.Errorf(tok, "%s must be the name of an ast.Block or a clause", n.Name)
+		}
+
+		if c.IsStatementsTag(n.Name) {
+			return c.ParseStatements(tok.Args, tok.SourceLoc)
+		}
+
+		return &parser.ASTTag{Token: tok}, nil
+	case *ast.Text:
+		return &parser.ASTText{Token: parser.Token{Type: parser.TextTokenType, SourceLoc: parserLoc(n.Loc), Source: n.Source}}, nil
+	case *ast.Trim:
+		if n.Direction == ast.TrimLeft {
+			return &parser.ASTTrim{TrimDirection: parser.Left}, nil
+		}
+
+		return &parser.ASTTrim{TrimDirection: parser.Right}, nil
+	default:
+		return nil, parser.Errorf(parser.Token{}, "unexpected %T in a template syntax tree", n)
+	}
+}
+
+func (c *Config) astBlockToParseTree(n *ast.Block, parent *blockSyntax) (*parser.ASTBlock, parser.Error) {
+	tag := ast.Tag{Name: n.Name, Args: n.TagArgs()}
+	tok := parser.Token{Type: parser.TagTokenType, SourceLoc: parserLoc(n.Loc), Name: n.Name, Args: tag.Args, Source: tag.String()}
+
+	cs, ok := c.findBlockDef(n.Name)
+	switch {
+	case !ok:
+		return nil, parser.Errorf(tok, "undefined block tag %q", n.Name)
+	case parent == nil && !cs.IsBlockStart():
+		return nil, parser.Errorf(tok, "%s is not a block start tag", n.Name)
+	case parent == nil && cs.parser == nil:
+		// comment and raw
+		return nil, parser.Errorf(tok, "%s block has no compiler", n.Name)
+	case parent != nil && !(cs.IsClause() && cs.CanHaveParent(parent)):
+		return nil, parser.Errorf(tok, "%s is not a clause of %s", n.Name, parent.TagName())
+	case parent != nil && len(n.Clauses) > 0:
+		return nil, parser.Errorf(tok, "clause %s has clauses", n.Name)
+	}
+
+	body, err := c.astNodesToParseTree(n.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &parser.ASTBlock{Token: tok, Body: body}
+	for _, clause := range n.Clauses {
+		b, err := c.astBlockToParseTree(clause, cs)
+		if err != nil {
+			return nil, err
+		}
+
+		block.Clauses = append(block.Clauses, b)
+	}
+
+	return block, nil
+}
+
+func (c *Config) astNodesToParseTree(nodes []ast.Node) ([]parser.ASTNode, parser.Error) {
+	out := make([]parser.ASTNode, 0, len(nodes))
+	for _, n := range nodes {
+		pn, err := c.astToParseTree(n)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, pn)
+	}
+
+	return out, nil
+}
+
+func parserLoc(loc ast.SourceLoc) parser.SourceLoc {
+	return parser.SourceLoc{Pathname: loc.Pathname, LineNo: loc.LineNo}
+}
@@ -0,0 +1,84 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/ast"
+	"github.com/osteele/liquid/parser"
+)
+
+var astRoundTripTests = []string{
+	`text {{ page.title | upcase }}`,
+	`{{- int -}} {%- y -%} x`,
+	`{% if int > 1 -%} a {%- elsif page %} b {% else %} c {%- endif %}`,
+	`{% raw %}{{ x }}{% endraw %}`,
+}
+
+func TestToAST(t *testing.T) {
+	cfg := NewConfig()
+	addRenderTestTags(cfg)
+	cfg.AddBlock("raw")
+
+	for _, src := range astRoundTripTests {
+		root, err := cfg.Compile(src, parser.SourceLoc{})
+		require.NoError(t, err)
+		require.Equal(t, src, ToAST(root).String())
+	}
+
+	root, err := cfg.Compile("a\n{{ b }}", parser.SourceLoc{Pathname: "t.html", LineNo: 1})
+	require.NoError(t, err)
+
+	seq := ToAST(root).(*ast.Seq)
+	require.Equal(t, ast.SourceLoc{Pathname: "t.html", LineNo: 2}, seq.Children[1].(*ast.Object).Loc)
+}
+
+func TestCompileAST(t *testing.T) {
+	cfg := NewConfig()
+	addRenderTestTags(cfg)
+
+	root, err := cfg.Compile(`{% if int > 1 %}{{ page.title }}{% endif %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	tree := ast.Rewrite(ToAST(root), func(n ast.Node) ast.Node {
+		if p, ok := n.(*ast.Property); ok && p.Name == "title" {
+			p.Name = "subtitle"
+		}
+
+		return n
+	})
+
+	compiled, _, err := cfg.CompileAST(tree)
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	err = Render(compiled, buf, map[string]any{"int": 2, "page": map[string]any{"subtitle": "Sub"}}, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "Sub", buf.String())
+
+	errorTests := []struct {
+		tree     ast.Node
+		expected string
+	}{
+		{&ast.Tag{Name: "undefined_tag"}, "undefined tag"},
+		{&ast.Tag{Name: "if", Args: "true"}, "must be the name of an ast.Block"},
+		{&ast.Block{Name: "undefined"}, "undefined block tag"},
+		{&ast.Block{Name: "else"}, "not a block start tag"},
+		{&ast.Block{Name: "if", Args: "true", Clauses: []*ast.Block{{Name: "if"}}}, "not a clause of if"},
+		{&ast.Object{}, "object without an expression"},
+		{&ast.Object{Expr: &ast.BinaryOp{Op: "xor", Left: &ast.Literal{}, Right: &ast.Literal{}}}, "undefined operator"},
+		{&ast.Invalid{Source: "{{ a | }}"}, "syntax error"},
+	}
+	for _, test := range errorTests {
+		_, _, err := cfg.CompileAST(test.tree)
+		require.Error(t, err, test.tree.String())
+		require.Contains(t, err.Error(), test.expected)
+	}
+
+	cfg.ErrorMode = parser.LaxMode
+	_, warnings, err := cfg.CompileAST(&ast.Invalid{Source: "{{ a | }}"})
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+}
@@ -3,6 +3,7 @@ package render
 import (
 	"io"
 	"sort"
+	"strings"
 
 	"github.com/osteele/liquid/parser"
 )
@@ -10,13 +11,19 @@ import (
 // BlockCompiler builds a renderer for the tag instance.
 type BlockCompiler func(BlockNode) (func(io.Writer, Context) error, error)
 
+// ConfigBlockCompiler is a BlockCompiler that also receives the configuration
+// that compiles the tag.
+type ConfigBlockCompiler func(c *Config, node BlockNode) (func(io.Writer, Context) error, error)
+
 // blockSyntax tells the parser how to parse a control tag.
 type blockSyntax struct {
 	name                  string
 	isClauseTag, isEndTag bool
 	startName             string          // for an end tag, the name of the correspondign start tag
 	parents               map[string]bool // if non-nil, must be an immediate clause of one of these
-	parser                BlockCompiler
+	parser                ConfigBlockCompiler
+	isMacro               bool // defined by AddMacroBlock
+	uniqueArgs            bool // set by UniqueArgs
 }
 
 func (s *blockSyntax) CanHaveParent(parent parser.BlockSyntax) bool {
@@ -112,15 +119,89 @@ func (b blockDefBuilder) Clause(name string) blockDefBuilder {
 // 	return b
 // }
 
+// UniqueArgs tells the compiler that no two instances of this tag in a
+// template can have the same arguments, as two inheritance blocks can't have
+// the same name. The compiler reports the second instance.
+func (b blockDefBuilder) UniqueArgs() blockDefBuilder {
+	b.tag.uniqueArgs = true
+	return b
+}
+
 // Compiler sets the parser for a control tag definition.
 func (b blockDefBuilder) Compiler(fn BlockCompiler) {
+	b.tag.parser = func(_ *Config, node BlockNode) (func(io.Writer, Context) error, error) {
+		return fn(node)
+	}
+}
+
+// ConfigCompiler sets the parser for a control tag definition, whose parser
+// depends on the configuration. See AddConfigTag.
+func (b blockDefBuilder) ConfigCompiler(fn ConfigBlockCompiler) {
 	b.tag.parser = fn
 }
 
 // Renderer sets the render action for a control tag definition.
 func (b blockDefBuilder) Renderer(fn func(io.Writer, Context) error) {
-	b.tag.parser = func(node BlockNode) (func(io.Writer, Context) error, error) {
+	b.tag.parser = func(_ *Config, node BlockNode) (func(io.Writer, Context) error, error) {
 		// TODO syntax error if there are arguments?
 		return fn, nil
 	}
 }
+
+func (g grammar) hasUniqueArgs() bool {
+	for _, def := range g.blockDefs {
+		if def.uniqueArgs {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkUniqueArgs reports the first block, in source order, whose tag
+// requires unique arguments, and that has the same arguments as an earlier
+// block with that tag.
+func (c *compiler) checkUniqueArgs(root Node) parser.Error {
+	seen := map[string]map[string]bool{}
+
+	var check func(nodes []Node) parser.Error
+
+	check = func(nodes []Node) parser.Error {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *BlockNode:
+				if def, ok := c.findBlockDef(n.Name); ok && def.uniqueArgs {
+					args := strings.TrimSpace(n.Args)
+					if seen[n.Name][args] {
+						err := parser.Errorf(n, "%s %q is defined more than once", n.Name, args)
+						if !c.recover(err) {
+							return err
+						}
+					} else if seen[n.Name] == nil {
+						seen[n.Name] = map[string]bool{args: true}
+					} else {
+						seen[n.Name][args] = true
+					}
+				}
+
+				if err := check(n.Body); err != nil {
+					return err
+				}
+
+				for _, clause := range n.Clauses {
+					if err := check(clause.Body); err != nil {
+						return err
+					}
+				}
+			case *SeqNode:
+				if err := check(n.Children); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	return check([]Node{root})
+}
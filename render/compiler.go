@@ -2,22 +2,79 @@ package render
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/osteele/liquid/parser"
 )
 
 // Compile parses a source template. It returns an AST root, that can be evaluated.
 func (c *Config) Compile(source string, loc parser.SourceLoc) (Node, parser.Error) {
+	root, _, err := c.CompileWithWarnings(source, loc)
+	return root, err
+}
+
+// CompileWithWarnings is the same as Compile, except that it also returns the
+// syntax errors that were recovered from in the lax and warn error modes.
+// In strict mode the warnings are always empty.
+func (c *Config) CompileWithWarnings(source string, loc parser.SourceLoc) (Node, []parser.Error, parser.Error) {
 	root, err := c.Parse(source, loc)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	return c.compile(root)
+}
+
+func (c *Config) compile(root parser.ASTNode) (Node, []parser.Error, parser.Error) {
+	cc := compiler{Config: c}
+
+	node, err := cc.compileNode(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.hasUniqueArgs() {
+		if err := cc.checkUniqueArgs(node); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if c.hasMacros() {
+		node, err = cc.compileMacros(node)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if err := cc.checkNoCalls(node); err != nil {
+		return nil, nil, err
+	}
+
+	return node, cc.warnings, nil
+}
+
+// compiler holds the state of a single compilation.
+type compiler struct {
+	*Config
+
+	warnings []parser.Error
+}
+
+// recover records err as a warning, if the error mode allows the compiler to
+// continue past it.
+func (c *compiler) recover(err parser.Error) bool {
+	if c.ErrorMode == parser.StrictMode {
+		return false
 	}
 
-	return c.compileNode(root)
+	c.warnings = append(c.warnings, err)
+
+	return true
 }
 
+// noopRenderer renders the tags and blocks that failed to compile in the lax and warn error modes.
+func noopRenderer(io.Writer, Context) error { return nil }
+
 // nolint: gocyclo
-func (c *Config) compileNode(n parser.ASTNode) (Node, parser.Error) {
+func (c *compiler) compileNode(n parser.ASTNode) (Node, parser.Error) {
 	switch n := n.(type) {
 	case *parser.ASTBlock:
 		body, err := c.compileNodes(n.Body)
@@ -41,15 +98,30 @@ func (c *Config) compileNode(n parser.ASTNode) (Node, parser.Error) {
 			Clauses: branches,
 		}
 		if cd.parser != nil {
-			r, err := cd.parser(node)
+			r, err := cd.parser(c.Config, node)
 			if err != nil {
-				return nil, parser.WrapError(err, n)
+				err := parser.WrapError(err, n)
+				if !c.recover(err) {
+					return nil, err
+				}
+
+				r = noopRenderer
 			}
 
 			node.renderer = r
 		}
 
 		return &node, nil
+	case *parser.ASTError:
+		if !c.recover(n.Err) {
+			return nil, n.Err
+		}
+
+		if n.Recovered != nil {
+			return &ObjectNode{n.Recovered.Token, n.Recovered.Expr}, nil
+		}
+
+		return &TagNode{n.Token, noopRenderer}, nil
 	case *parser.ASTRaw:
 		return &RawNode{sourcelessNode{}, n.Slices}, nil
 	case *parser.ASTSeq:
@@ -58,18 +130,29 @@ func (c *Config) compileNode(n parser.ASTNode) (Node, parser.Error) {
 			return nil, err
 		}
 
-		return &SeqNode{sourcelessNode{}, children}, nil
+		return &SeqNode{Children: children}, nil
 	case *parser.ASTTag:
-		if td, ok := c.FindTagDefinition(n.Name); ok {
-			f, err := td(n.Args)
-			if err != nil {
-				return nil, parser.Errorf(n, "%s", err)
+		td, ok := c.FindTagDefinition(n.Name)
+		if !ok {
+			err := parser.Errorf(n, "undefined tag %q", n.Name)
+			if !c.recover(err) {
+				return nil, err
+			}
+
+			return &TagNode{n.Token, noopRenderer}, nil
+		}
+
+		f, err := td(n.Args)
+		if err != nil {
+			err := parser.Errorf(n, "%s", err)
+			if !c.recover(err) {
+				return nil, err
 			}
 
-			return &TagNode{n.Token, f}, nil
+			f = noopRenderer
 		}
 
-		return nil, parser.Errorf(n, "undefined tag %q", n.Name)
+		return &TagNode{n.Token, f}, nil
 	case *parser.ASTText:
 		return &TextNode{n.Token}, nil
 	case *parser.ASTObject:
@@ -81,7 +164,7 @@ func (c *Config) compileNode(n parser.ASTNode) (Node, parser.Error) {
 	}
 }
 
-func (c *Config) compileBlocks(blocks []*parser.ASTBlock) ([]*BlockNode, parser.Error) {
+func (c *compiler) compileBlocks(blocks []*parser.ASTBlock) ([]*BlockNode, parser.Error) {
 	out := make([]*BlockNode, 0, len(blocks))
 	for _, child := range blocks {
 		compiled, err := c.compileNode(child)
@@ -95,7 +178,7 @@ func (c *Config) compileBlocks(blocks []*parser.ASTBlock) ([]*BlockNode, parser.
 	return out, nil
 }
 
-func (c *Config) compileNodes(nodes []parser.ASTNode) ([]Node, parser.Error) {
+func (c *compiler) compileNodes(nodes []parser.ASTNode) ([]Node, parser.Error) {
 	out := make([]Node, 0, len(nodes))
 	for _, child := range nodes {
 		compiled, err := c.compileNode(child)
@@ -44,3 +44,24 @@ func TestCompile_errors(t *testing.T) {
 		})
 	}
 }
+
+func TestCompileWithWarnings(t *testing.T) {
+	settings := NewConfig()
+	addCompilerTestTags(settings)
+
+	_, warnings, err := settings.CompileWithWarnings(`{% block %}{% undefined_tag %}{% endblock %}`, parser.SourceLoc{})
+	require.Error(t, err)
+	require.Empty(t, warnings)
+
+	settings.ErrorMode = parser.WarnMode
+	root, warnings, err := settings.CompileWithWarnings(`{{ x | }}{% undefined_tag %}{% error_block %}{% enderror_block %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+	require.NotNil(t, root)
+	require.Len(t, warnings, 3)
+	require.Contains(t, warnings[0].Error(), "syntax error")
+	require.Contains(t, warnings[1].Error(), "undefined tag")
+	require.Contains(t, warnings[2].Error(), "block compiler error")
+
+	_, _, err = settings.CompileWithWarnings(`{% block %}`, parser.SourceLoc{})
+	require.Error(t, err)
+}
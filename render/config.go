@@ -1,6 +1,10 @@
 package render
 
 import (
+	"maps"
+	"slices"
+	"sync"
+
 	"github.com/osteele/liquid/parser"
 )
 
@@ -9,9 +13,30 @@ type Config struct {
 	parser.Config
 	grammar
 
-	Cache           map[string][]byte
+	Cache map[string][]byte
+	// Compiled holds compiled templates, by path. Like Cache, the include
+	// and render tags use it for the partials that the template store
+	// doesn't have.
+	Compiled map[string]Node
+	// Sources, if non-nil, holds template sources that the include and
+	// render tags use after Cache. Unlike Cache, it can be added to while
+	// templates render.
+	Sources         *SourceCache
 	StrictVariables bool
 	TemplateStore   TemplateStore
+	Limits          Limits
+	// PartialCache, if non-nil, holds compiled partials across renders.
+	// Set it with SetPartialCache.
+	PartialCache *PartialCache
+
+	// partialGeneration is the generation of PartialCache whose partials
+	// this configuration uses. See ResetPartialCache.
+	partialGeneration uint64
+
+	// storeHas records, by path, whether a TemplateStore that isn't a
+	// TemplateVersioner has a template that Compiled also holds. It is
+	// shared by clones, and replaced by ResetPartialCache.
+	storeHas *sync.Map
 
 	escapeReplacer Replacer
 
@@ -22,17 +47,46 @@ type Config struct {
 	JekyllExtensions bool
 }
 
+// Limits bounds the resources that a single render can use. A render that
+// exceeds a limit fails with an error whose cause is an expressions.LimitError.
+// The lax and warn error modes report these errors instead of rendering them inline.
+//
+// A zero field means no limit, except for MaxIncludeDepth.
+type Limits struct {
+	// MaxOutputBytes limits the number of bytes written to the render's writer.
+	MaxOutputBytes int
+	// MaxIterations limits the total number of iterations of all the for,
+	// tablerow, and render … for loops in the render, including those in partials.
+	MaxIterations int
+	// MaxRangeSize limits the number of elements in a range such as (1..n).
+	MaxRangeSize int
+	// MaxIncludeDepth limits the nesting of the include and render tags.
+	// Zero selects DefaultMaxIncludeDepth, so that a template that includes
+	// itself fails instead of exhausting the stack.
+	MaxIncludeDepth int
+}
+
+// DefaultMaxIncludeDepth is the include and render nesting limit when
+// Limits.MaxIncludeDepth is zero. It is the same as Shopify Liquid's.
+const DefaultMaxIncludeDepth = 100
+
 type grammar struct {
-	tags      map[string]TagCompiler
-	blockDefs map[string]*blockSyntax
+	tags           map[string]ConfigTagCompiler
+	blockDefs      map[string]*blockSyntax
+	tagAnalyzers   map[string]ConfigTagAnalyzer
+	blockAnalyzers map[string]ConfigBlockAnalyzer
+	statementsTags map[string]bool
 }
 
 // NewConfig creates a new Settings.
 // TemplateStore is initialized to a FileTemplateStore for backwards compatibility
 func NewConfig() Config {
 	g := grammar{
-		tags:      map[string]TagCompiler{},
-		blockDefs: map[string]*blockSyntax{},
+		tags:           map[string]ConfigTagCompiler{},
+		blockDefs:      map[string]*blockSyntax{},
+		tagAnalyzers:   map[string]ConfigTagAnalyzer{},
+		blockAnalyzers: map[string]ConfigBlockAnalyzer{},
+		statementsTags: map[string]bool{},
 	}
 
 	return Config{
@@ -40,6 +94,7 @@ func NewConfig() Config {
 		grammar:       g,
 		Cache:         map[string][]byte{},
 		TemplateStore: &FileTemplateStore{},
+		storeHas:      &sync.Map{},
 	}
 }
 
@@ -47,3 +102,36 @@ func (c *Config) SetAutoEscapeReplacer(replacer Replacer) {
 	c.escapeReplacer = replacer
 	c.AddSafeFilter()
 }
+
+// Clone returns a copy of the configuration. Tags, blocks, filters,
+// delimiters, and Cache entries can be added to or removed from the copy
+// without affecting c. The copy shares c's TemplateStore, Sources, and
+// PartialCache.
+func (c *Config) Clone() Config {
+	clone := *c
+	clone.Config.Config = c.Config.Config.Clone()
+	clone.grammar = c.grammar.clone()
+	clone.Grammar = clone.grammar
+	clone.Delims = slices.Clone(c.Delims)
+	clone.Cache = maps.Clone(c.Cache)
+	clone.Compiled = maps.Clone(c.Compiled)
+
+	return clone
+}
+
+func (g grammar) clone() grammar {
+	blockDefs := make(map[string]*blockSyntax, len(g.blockDefs))
+	for name, def := range g.blockDefs {
+		copied := *def
+		copied.parents = maps.Clone(def.parents)
+		blockDefs[name] = &copied
+	}
+
+	return grammar{
+		tags:           maps.Clone(g.tags),
+		blockDefs:      blockDefs,
+		tagAnalyzers:   maps.Clone(g.tagAnalyzers),
+		blockAnalyzers: maps.Clone(g.blockAnalyzers),
+		statementsTags: maps.Clone(g.statementsTags),
+	}
+}
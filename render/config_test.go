@@ -0,0 +1,44 @@
+package render
+
+import (
+	"io"
+	"testing"
+
+	"github.com/osteele/liquid/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Clone(t *testing.T) {
+	cfg := NewConfig()
+	cfg.AddFilter("f", func(s string) string { return s })
+	cfg.AddTag("t", func(string) (func(io.Writer, Context) error, error) { return noopRenderer, nil })
+	cfg.AddBlock("b").Clause("c")
+	cfg.Delims = []string{"[[", "]]", "[%", "%]"}
+	cfg.Cache["a"] = []byte("a")
+
+	clone := cfg.Clone()
+	clone.AddFilter("g", func(s string) string { return s })
+	clone.UnregisterTag("t")
+	clone.AddBlock("d").Clause("c")
+	clone.Delims[0] = "<<"
+	clone.Cache["b"] = []byte("b")
+
+	_, ok := cfg.FindTagDefinition("t")
+	require.True(t, ok)
+	_, ok = clone.FindTagDefinition("t")
+	require.False(t, ok)
+
+	require.NotContains(t, cfg.blockDefs, "d")
+	require.Equal(t, []string{"b"}, cfg.blockDefs["c"].ParentTags())
+	require.Equal(t, []string{"b", "d"}, clone.blockDefs["c"].ParentTags())
+	require.Equal(t, "[[", cfg.Delims[0])
+	require.NotContains(t, cfg.Cache, "b")
+
+	root, err := cfg.Compile(`[[ 1 | g ]]`, parser.SourceLoc{})
+	require.NoError(t, err)
+	require.Error(t, Render(root, io.Discard, nil, cfg))
+
+	root, err = clone.Compile(`<< 1 | g ]]`, parser.SourceLoc{})
+	require.NoError(t, err)
+	require.NoError(t, Render(root, io.Discard, nil, clone))
+}
@@ -2,21 +2,24 @@ package render
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"maps"
 	"strings"
 
 	"github.com/osteele/liquid/parser"
 
 	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/values"
 )
 
 // Context provides the rendering context for a tag renderer.
 type Context interface {
-	// Bindings returns the current lexical environment.
+	// Bindings returns the current lexical environment. In a render with a
+	// root scope value (see RenderValue), it holds only the variables that
+	// overlay the scope; Get also looks up the scope.
 	Bindings() map[string]any
 	// Get retrieves the value of a variable from the current lexical environment.
 	Get(name string) any
@@ -41,7 +44,7 @@ type Context interface {
 	// It's not guaranteed stable.
 	RenderChildren(io.Writer) Error
 	// RenderFile parses and renders a template. It's used in the implementation of the {% include %} tag.
-	// RenderFile does not cache the compiled template.
+	// RenderFile caches the compiled template only if the configuration has a PartialCache.
 	RenderFile(string, map[string]any) (string, error)
 	// Set updates the value of a variable in the current lexical environment.
 	// It's used in the implementation of the {% assign %} and {% capture %} tags.
@@ -61,6 +64,23 @@ type Context interface {
 	WrapError(err error) Error
 }
 
+// A GoContextProvider supplies the context.Context that the template is
+// being rendered with. The Context that the renderer passes to tags
+// implements it; check for it with a type assertion. Tags that perform
+// long-running work should stop when the context is canceled.
+type GoContextProvider interface {
+	Context() context.Context
+}
+
+// A RegistersProvider supplies the render's registers: state that the host
+// application supplies with WithRegisters, shared by the template and its
+// partials and not visible to the template. Tags can modify it. The Context
+// that the renderer passes to tags implements it; check for it with a type
+// assertion.
+type RegistersProvider interface {
+	Registers() map[string]any
+}
+
 type TemplateStore interface {
 	ReadTemplate(templatename string) ([]byte, error)
 }
@@ -111,7 +131,7 @@ func (c rendererContext) Evaluate(expr expressions.Expression) (out any, err err
 
 // EvaluateString evaluates an expression within the template context.
 func (c rendererContext) EvaluateString(source string) (out any, err error) {
-	return expressions.EvaluateString(source, expressions.NewContext(c.ctx.bindings, c.ctx.config.Config.Config))
+	return expressions.EvaluateString(source, c.ctx.newExpressionContext())
 }
 
 // Bindings returns the current lexical environment.
@@ -119,9 +139,21 @@ func (c rendererContext) Bindings() map[string]any {
 	return c.ctx.bindings
 }
 
+// Context returns the context.Context of the current render. It implements
+// GoContextProvider.
+func (c rendererContext) Context() context.Context {
+	return c.ctx.context
+}
+
+// Registers returns the registers of the current render. It implements
+// RegistersProvider.
+func (c rendererContext) Registers() map[string]any {
+	return c.ctx.registers()
+}
+
 // Get gets a variable value within an evaluation context.
 func (c rendererContext) Get(name string) any {
-	return c.ctx.bindings[name]
+	return c.ctx.get(name)
 }
 
 func (c rendererContext) ExpandTagArg() (string, error) {
@@ -134,7 +166,7 @@ func (c rendererContext) ExpandTagArg() (string, error) {
 
 		buf := new(bytes.Buffer)
 
-		err = Render(root, buf, c.ctx.bindings, c.ctx.config)
+		_, err = renderScope(c.ctx.context, root, buf, c.ctx.bindings, c.ctx.scope, c.ctx.config)
 		if err != nil {
 			return "", err
 		}
@@ -175,7 +207,7 @@ func (c rendererContext) RenderFileTo(w io.Writer, filename string, b map[string
 	maps.Copy(bindings, c.ctx.bindings)
 	maps.Copy(bindings, b)
 
-	return c.renderFileTo(w, filename, bindings)
+	return c.renderFileTo(w, filename, bindings, c.ctx.scope, false)
 }
 
 // RenderFileIsolated renders a template without inheriting the parent lexical scope.
@@ -193,32 +225,108 @@ func (c rendererContext) RenderFileIsolated(filename string, bindings map[string
 // RenderFileIsolatedTo renders a template directly to a writer without
 // inheriting the parent lexical scope.
 func (c rendererContext) RenderFileIsolatedTo(w io.Writer, filename string, bindings map[string]any) error {
-	return c.renderFileTo(w, filename, maps.Clone(bindings))
+	return c.renderFileTo(w, filename, maps.Clone(bindings), nil, true)
 }
 
-func (c rendererContext) renderFileTo(w io.Writer, filename string, bindings map[string]any) error {
-	source, err := c.ctx.config.TemplateStore.ReadTemplate(filename)
-	if err != nil && errors.Is(err, fs.ErrNotExist) {
-		// Is it cached?
-		if cval, ok := c.ctx.config.Cache[filename]; ok {
-			source = cval
-		} else {
-			return err
-		}
-	} else if err != nil {
+// CountIteration records a loop iteration against Limits.MaxIterations. It is
+// used as an optional internal extension to Context by the iteration tags.
+func (c rendererContext) CountIteration() error {
+	return c.ctx.countIteration()
+}
+
+// WriteValue writes a value as an object {{ value }} would. It is used as an
+// optional internal extension to Context by the echo tag.
+func (c rendererContext) WriteValue(w io.Writer, value any) error {
+	return c.ctx.writeValue(w, value)
+}
+
+// WriteMarkup writes text that the template rendered, as an object writes the
+// output of a macro: with contextual autoescaping, it is escaped unless it is
+// written in HTML text. It is used as an optional internal extension to
+// Context by the yield tag.
+func (c rendererContext) WriteMarkup(w io.Writer, s string) error {
+	return c.ctx.writeValue(w, c.ctx.config.markup(s))
+}
+
+// AddToCounter adds delta to the named increment and decrement counter, and
+// returns the new value. Counters start at zero, and are shared by the
+// template and its partials. A counter is separate from the variable with the
+// same name, and is its value only if no binding or assignment defines it. It is used as an optional
+// internal extension to Context by the increment and decrement tags.
+func (c rendererContext) AddToCounter(name string, delta int) int {
+	if c.ctx.state.counters == nil {
+		c.ctx.state.counters = map[string]int{}
+	}
+
+	c.ctx.state.counters[name] += delta
+
+	return c.ctx.state.counters[name]
+}
+
+// LoopOffset returns the position at which the last for loop with the given
+// forloop.name stopped, for offset:continue. It is used as an optional
+// internal extension to Context by the for tag.
+func (c rendererContext) LoopOffset(name string) int {
+	return c.ctx.state.offsets[name]
+}
+
+// SetLoopOffset records the position at which a for loop stopped.
+func (c rendererContext) SetLoopOffset(name string, offset int) {
+	if c.ctx.state.offsets == nil {
+		c.ctx.state.offsets = map[string]int{}
+	}
+
+	c.ctx.state.offsets[name] = offset
+}
+
+// renderFileTo renders a partial. A loop signal in an isolated partial, as
+// the render tag renders, doesn't reach the caller's loop.
+func (c rendererContext) renderFileTo(w io.Writer, filename string, bindings map[string]any, scope values.Value, isolated bool) error {
+	if err := c.ctx.checkIncludeDepth(); err != nil {
+		return c.WrapError(err)
+	}
+
+	root, err := c.ctx.loadPartial(filename)
+	if err != nil {
 		return err
 	}
 
-	root, ok := c.ctx.cachedPartial(filename, source)
-	if !ok {
-		root, err = c.ctx.config.Compile(string(source), parser.SourceLoc{Pathname: filename, LineNo: 1})
-		if err != nil {
-			return err
-		}
-		c.ctx.cachePartial(filename, source, root)
+	child := c.ctx.child(bindings)
+	child.setScope(scope)
+
+	if err := renderWithContext(root, w, child); err != nil {
+		return err
+	}
+
+	if isolated {
+		return child.strayLoopSignal()
 	}
 
-	return renderWithContext(root, w, c.ctx.child(bindings))
+	c.ctx.signalLoop(child.loopSignal, child.loopSignalLoc)
+
+	return nil
+}
+
+// SignalLoop sends a loop signal from the current tag; see LoopSignal. It is
+// used as an optional extension to Context by the break and continue tags,
+// and can be used by custom tags.
+func (c rendererContext) SignalLoop(s LoopSignal) {
+	switch {
+	case c.node != nil:
+		c.ctx.signalLoop(s, c.node)
+	case c.cn != nil:
+		c.ctx.signalLoop(s, c.cn)
+	default:
+		c.ctx.signalLoop(s, invalidLoc)
+	}
+}
+
+// TakeLoopSignal returns and clears the signal that a tag sent while the
+// loop's body rendered, or NoLoopSignal. It is used as an optional extension
+// to Context by the for and tablerow tags, and can be used by custom loop
+// tags.
+func (c rendererContext) TakeLoopSignal() LoopSignal {
+	return c.ctx.takeLoopSignal()
 }
 
 // InnerString renders the children to a string.
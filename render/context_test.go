@@ -2,6 +2,7 @@ package render
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -351,7 +352,7 @@ var setPathStateTests = []struct {
 func TestSetPathState(t *testing.T) {
 	for _, test := range setPathStateTests {
 		t.Run(test.name, func(t *testing.T) {
-			ctx := newNodeContext(test.initial, NewConfig())
+			ctx := newNodeContext(context.Background(), test.initial, NewConfig())
 			rc := rendererContext{ctx: ctx}
 
 			err := rc.SetPath(test.path, test.value)
@@ -430,7 +431,7 @@ func TestRenderFileScopeState(t *testing.T) {
 			cfg := NewConfig()
 			cfg.Cache["child"] = []byte(test.template)
 
-			parent := newNodeContext(maps.Clone(test.parent), cfg)
+			parent := newNodeContext(context.Background(), maps.Clone(test.parent), cfg)
 			rc := rendererContext{ctx: parent}
 
 			var buf bytes.Buffer
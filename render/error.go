@@ -1,6 +1,12 @@
 package render
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/osteele/liquid/expressions"
 	"github.com/osteele/liquid/parser"
 )
 
@@ -19,3 +25,46 @@ func renderErrorf(loc parser.Locatable, format string, a ...any) Error {
 func wrapRenderError(err error, loc parser.Locatable) Error {
 	return parser.WrapError(err, loc)
 }
+
+// recoverError writes err to w, in the lax and warn error modes, and returns nil.
+// In strict mode, and for cancellation, limit, and writer errors, it returns err.
+func (c *nodeContext) recoverError(w io.Writer, err Error) Error {
+	if err == nil || c.config.ErrorMode == parser.StrictMode {
+		return err
+	}
+
+	var limit expressions.LimitError
+	if errors.As(err, &limit) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	if _, werr := io.WriteString(w, inlineErrorText(err)); werr != nil {
+		return err
+	}
+
+	return nil
+}
+
+// inlineErrorText formats an error the way Shopify Liquid renders it in place of the failing tag or object.
+func inlineErrorText(err Error) string {
+	for {
+		inner, ok := err.Cause().(Error)
+		if !ok {
+			break
+		}
+
+		err = inner
+	}
+
+	cause := err.Cause()
+	if cause == nil {
+		return err.Error()
+	}
+
+	if line := err.LineNumber(); line > 0 {
+		return fmt.Sprintf("Liquid error (line %d): %s", line, cause)
+	}
+
+	return "Liquid error: " + cause.Error()
+}
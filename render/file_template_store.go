@@ -3,6 +3,7 @@ package render
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 )
@@ -13,6 +14,35 @@ type FileTemplateStore struct {
 }
 
 func (tl *FileTemplateStore) ReadTemplate(filename string) ([]byte, error) {
+	var source []byte
+
+	err := tl.withRoot(filename, func(root *os.Root, rel string) (err error) {
+		source, err = root.ReadFile(rel)
+		return err
+	})
+
+	return source, err
+}
+
+// TemplateVersion returns the file's modification time and size, for a
+// PartialCache.
+func (tl *FileTemplateStore) TemplateVersion(filename string) (string, error) {
+	var info fs.FileInfo
+
+	err := tl.withRoot(filename, func(root *os.Root, rel string) (err error) {
+		info, err = root.Stat(rel)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fileVersion(info), nil
+}
+
+// withRoot calls fn with Root, opened as an os.Root, and the path of filename
+// relative to it.
+func (tl *FileTemplateStore) withRoot(filename string, fn func(*os.Root, string) error) error {
 	rootPath := tl.Root
 	if rootPath == "" {
 		rootPath = "."
@@ -20,7 +50,7 @@ func (tl *FileTemplateStore) ReadTemplate(filename string) ([]byte, error) {
 
 	absRoot, err := filepath.Abs(rootPath)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	absFilename := filename
 	if !filepath.IsAbs(absFilename) {
@@ -28,23 +58,33 @@ func (tl *FileTemplateStore) ReadTemplate(filename string) ([]byte, error) {
 	}
 	rel, err := filepath.Rel(absRoot, filepath.Clean(absFilename))
 	if err != nil || !filepath.IsLocal(rel) {
-		return nil, fmt.Errorf("template path %q is outside root %q", filename, rootPath)
+		return fmt.Errorf("template path %q is outside root %q", filename, rootPath)
 	}
 
 	root, err := os.OpenRoot(absRoot)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	source, readErr := root.ReadFile(rel)
+	fnErr := fn(root, rel)
 	closeErr := root.Close()
 
 	switch {
-	case readErr == nil:
-		return source, closeErr
+	case fnErr == nil:
+		return closeErr
 	case closeErr == nil:
-		return source, readErr
+		return fnErr
 	default:
-		return source, errors.Join(readErr, closeErr)
+		return errors.Join(fnErr, closeErr)
 	}
 }
+
+// fileVersion returns a version string from a file's modification time and
+// size, or "" if the file system doesn't record modification times.
+func fileVersion(info fs.FileInfo) string {
+	if info.ModTime().IsZero() {
+		return ""
+	}
+
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
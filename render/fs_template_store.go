@@ -0,0 +1,54 @@
+package render
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+)
+
+// FSTemplateStore reads templates from an fs.FS, such as an embed.FS or an
+// fstest.MapFS. Template names are slash-separated paths within FS, after
+// the include and render tags have resolved them relative to the including
+// template; operating system separators are converted to slashes.
+type FSTemplateStore struct {
+	FS fs.FS
+}
+
+// ReadTemplate reads the named template from the file system. It returns an
+// error for a name that is outside the file system, such as one that starts
+// with "..".
+func (s *FSTemplateStore) ReadTemplate(filename string) ([]byte, error) {
+	name, err := fsPath(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.ReadFile(s.FS, name)
+}
+
+// TemplateVersion returns the file's modification time and size, for a
+// PartialCache. It returns "" for file systems such as embed.FS that don't
+// record modification times.
+func (s *FSTemplateStore) TemplateVersion(filename string) (string, error) {
+	name, err := fsPath(filename)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := fs.Stat(s.FS, name)
+	if err != nil {
+		return "", err
+	}
+
+	return fileVersion(info), nil
+}
+
+func fsPath(filename string) (string, error) {
+	name := path.Clean(filepath.ToSlash(filename))
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("template path %q is outside the template file system", filename)
+	}
+
+	return name, nil
+}
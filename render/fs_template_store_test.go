@@ -0,0 +1,56 @@
+package render
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFSTemplateStore(t *testing.T) {
+	store := &FSTemplateStore{FS: fstest.MapFS{
+		"header.liquid":       {Data: []byte("header")},
+		"pages/footer.liquid": {Data: []byte("footer")},
+		"pages/nested/a.html": {Data: []byte("a")},
+	}}
+
+	for name, expected := range map[string]string{
+		"header.liquid":                 "header",
+		"./header.liquid":               "header",
+		"pages/footer.liquid":           "footer",
+		"pages/nested/../footer.liquid": "footer",
+		"pages/nested/a.html":           "a",
+	} {
+		source, err := store.ReadTemplate(name)
+		require.NoError(t, err, name)
+		require.Equal(t, expected, string(source), name)
+	}
+
+	_, err := store.ReadTemplate("missing.liquid")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	for _, name := range []string{"../header.liquid", "/header.liquid", ""} {
+		_, err := store.ReadTemplate(name)
+		require.Error(t, err, name)
+	}
+}
+
+func TestFSTemplateStore_TemplateVersion(t *testing.T) {
+	store := &FSTemplateStore{FS: fstest.MapFS{
+		"dated.liquid":   {Data: []byte("a"), ModTime: time.Unix(1, 0)},
+		"undated.liquid": {Data: []byte("b")},
+	}}
+
+	version, err := store.TemplateVersion("dated.liquid")
+	require.NoError(t, err)
+	require.NotEmpty(t, version)
+
+	version, err = store.TemplateVersion("undated.liquid")
+	require.NoError(t, err)
+	require.Empty(t, version)
+
+	_, err = store.TemplateVersion("missing.liquid")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,565 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/osteele/liquid/values"
+)
+
+// HtmlContextEscaper is a Replacer that selects contextual autoescaping.
+// Instead of replacing the same characters in every object, the renderer
+// follows the HTML context of the template's text as it is rendered, and
+// escapes each object for its position: HTML text, an attribute value, a URL
+// attribute, a script or event-handler attribute, or a style element or
+// attribute.
+//
+// Values of the html/template types HTML, HTMLAttr, URL, JS, JSStr, and CSS
+// are trusted in the corresponding contexts. The context follows the text of
+// the template and its partials; it doesn't follow text that tags write.
+//
+// Used outside of a render, it escapes HTML, as HtmlEscaper does.
+var HtmlContextEscaper Replacer = htmlContextEscaper{}
+
+type htmlContextEscaper struct{}
+
+func (htmlContextEscaper) WriteString(w io.Writer, s string) (int, error) {
+	return HtmlEscaper.WriteString(w, s)
+}
+
+// contextualEscaping reports whether the configuration escapes objects for
+// their HTML context.
+func (c *Config) contextualEscaping() bool {
+	return c.escapeReplacer == HtmlContextEscaper
+}
+
+// markup returns text that the template rendered, such as the output of a
+// macro, as a value that an object writes without escaping it again. With
+// contextual escaping, the text was escaped for HTML text, so it is trusted
+// only there, and an object in another context escapes it.
+func (c *Config) markup(s string) any {
+	switch {
+	case c.contextualEscaping():
+		return template.HTML(s) //nolint:gosec // G203: s is escaped template output
+	case c.escapeReplacer != nil:
+		return values.SafeValue{Value: s}
+	default:
+		return s
+	}
+}
+
+type htmlState uint8
+
+const (
+	htmlText          htmlState = iota
+	htmlTagName                 // in the name of a start or end tag
+	htmlTag                     // in a tag, between attributes
+	htmlAttrName                // in an attribute name
+	htmlAfterAttrName           // after an attribute name, perhaps before =
+	htmlBeforeValue             // after =, before the attribute value
+	htmlAttrValue               // in an attribute value
+	htmlComment                 // in <!-- … -->
+	htmlRCDATA                  // in the text of a title or textarea element
+	htmlScript                  // in a script element
+	htmlStyle                   // in a style element
+)
+
+type attrKind uint8
+
+const (
+	attrPlain attrKind = iota
+	attrURL
+	attrJS
+	attrCSS
+)
+
+type jsState uint8
+
+const (
+	jsCode jsState = iota
+	jsDoubleQuote
+	jsSingleQuote
+	jsTemplate
+	jsLineComment
+	jsBlockComment
+)
+
+// htmlContext tracks the HTML context of a stream of template text.
+type htmlContext struct {
+	state    htmlState
+	endTag   bool   // the current tag is an end tag
+	name     []byte // the current tag or attribute name, lower case
+	element  string // the element whose text htmlRCDATA, htmlScript, or htmlStyle is in
+	attr     attrKind
+	delim    byte // the attribute value's quote, or 0 if it is unquoted
+	js       jsState
+	jsEscape bool // the last character was a backslash in a JavaScript string
+	urlStart bool // no part of the URL attribute value has been written
+	urlQuery bool // the URL attribute value has reached its query or fragment
+}
+
+// observe advances the context past template text.
+func (h *htmlContext) observe(s string) {
+	for i := 0; i < len(s); i++ {
+		i = h.step(s, i)
+	}
+}
+
+// step advances the context past s[i], and perhaps the characters that
+// follow it. It returns the index of the last character that it consumed.
+func (h *htmlContext) step(s string, i int) int { //nolint: gocyclo
+	c := s[i]
+	switch h.state {
+	case htmlText:
+		if c != '<' {
+			return i
+		}
+
+		rest := s[i+1:]
+		switch {
+		case strings.HasPrefix(rest, "!--"):
+			h.state = htmlComment
+			return i + 3
+		case len(rest) > 0 && isASCIILetter(rest[0]):
+			h.startTag(false)
+		case len(rest) > 1 && rest[0] == '/' && isASCIILetter(rest[1]):
+			h.startTag(true)
+			return i + 1
+		}
+	case htmlTagName:
+		switch {
+		case c == '>':
+			h.endOfTag()
+		case isHTMLSpace(c) || c == '/':
+			h.state = htmlTag
+		default:
+			h.name = append(h.name, toLower(c))
+		}
+	case htmlTag:
+		switch {
+		case c == '>':
+			h.endOfTag()
+		case isHTMLSpace(c) || c == '/':
+		default:
+			h.state = htmlAttrName
+			h.name = append(h.name[:0], toLower(c))
+		}
+	case htmlAttrName, htmlAfterAttrName:
+		switch {
+		case c == '>':
+			h.endOfTag()
+		case c == '=':
+			h.state = htmlBeforeValue
+		case isHTMLSpace(c):
+			h.state = htmlAfterAttrName
+		case c == '/':
+			h.state = htmlTag
+		case h.state == htmlAfterAttrName:
+			h.state = htmlAttrName
+			h.name = append(h.name[:0], toLower(c))
+		default:
+			h.name = append(h.name, toLower(c))
+		}
+	case htmlBeforeValue:
+		switch {
+		case c == '>':
+			h.endOfTag()
+		case isHTMLSpace(c):
+		case c == '"' || c == '\'':
+			h.startAttrValue(c)
+		default:
+			h.startAttrValue(0)
+			return h.step(s, i)
+		}
+	case htmlAttrValue:
+		switch {
+		case h.delim != 0 && c == h.delim:
+			h.state = htmlTag
+		case h.delim == 0 && isHTMLSpace(c):
+			h.state = htmlTag
+		case h.delim == 0 && c == '>':
+			h.endOfTag()
+		case h.attr == attrURL:
+			h.urlStart = false
+			if c == '?' || c == '#' {
+				h.urlQuery = true
+			}
+		case h.attr == attrJS:
+			return h.stepJS(s, i)
+		}
+	case htmlComment:
+		if end := strings.Index(s[i:], "-->"); end >= 0 {
+			h.state = htmlText
+			return i + end + 2
+		}
+
+		return len(s) - 1
+	case htmlRCDATA, htmlScript, htmlStyle:
+		if c == '<' && hasPrefixFold(s[i+1:], "/"+h.element) {
+			h.state = htmlTagName
+			h.endTag = true
+			h.name = h.name[:0]
+			return i + 1
+		}
+
+		if h.state == htmlScript {
+			return h.stepJS(s, i)
+		}
+	}
+
+	return i
+}
+
+func (h *htmlContext) startTag(end bool) {
+	h.state = htmlTagName
+	h.endTag = end
+	h.name = h.name[:0]
+}
+
+func (h *htmlContext) startAttrValue(delim byte) {
+	h.state = htmlAttrValue
+	h.delim = delim
+	h.attr = attrKindOf(string(h.name))
+	h.js = jsCode
+	h.jsEscape = false
+	h.urlStart = true
+	h.urlQuery = false
+}
+
+func (h *htmlContext) endOfTag() {
+	h.state = htmlText
+	if h.endTag {
+		return
+	}
+
+	switch name := string(h.name); name {
+	case "script":
+		h.state = htmlScript
+		h.element = name
+		h.js = jsCode
+		h.jsEscape = false
+	case "style":
+		h.state = htmlStyle
+		h.element = name
+	case "textarea", "title":
+		h.state = htmlRCDATA
+		h.element = name
+	}
+}
+
+// stepJS advances the JavaScript context past s[i].
+func (h *htmlContext) stepJS(s string, i int) int {
+	c := s[i]
+	switch h.js {
+	case jsCode:
+		switch {
+		case c == '"':
+			h.js = jsDoubleQuote
+		case c == '\'':
+			h.js = jsSingleQuote
+		case c == '`':
+			h.js = jsTemplate
+		case c == '/' && i+1 < len(s) && s[i+1] == '/':
+			h.js = jsLineComment
+			return i + 1
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			h.js = jsBlockComment
+			return i + 1
+		}
+	case jsDoubleQuote, jsSingleQuote, jsTemplate:
+		switch {
+		case h.jsEscape:
+			h.jsEscape = false
+		case c == '\\':
+			h.jsEscape = true
+		case c == jsQuote[h.js]:
+			h.js = jsCode
+		}
+	case jsLineComment:
+		if c == '\n' {
+			h.js = jsCode
+		}
+	case jsBlockComment:
+		if c == '*' && i+1 < len(s) && s[i+1] == '/' {
+			h.js = jsCode
+			return i + 1
+		}
+	}
+
+	return i
+}
+
+var jsQuote = map[jsState]byte{jsDoubleQuote: '"', jsSingleQuote: '\'', jsTemplate: '`'}
+
+// writeValue writes the value of an object, escaped for the current context.
+func (h *htmlContext) writeValue(w io.Writer, value any) error {
+	if html, ok := value.(template.HTML); ok && h.state == htmlText {
+		_, err := io.WriteString(w, string(html))
+		return err
+	}
+
+	if h.state == htmlBeforeValue {
+		// The value starts an unquoted attribute value.
+		h.startAttrValue(0)
+	}
+
+	var s string
+	switch h.state {
+	case htmlScript:
+		s = h.escapeJS(value)
+	case htmlStyle:
+		s = escapeCSS(value)
+	case htmlTag:
+		if attr, ok := value.(template.HTMLAttr); ok {
+			_, err := io.WriteString(w, string(attr))
+			return err
+		}
+
+		s = unquotedAttrEscaper.Replace(stringify(value))
+	case htmlAttrValue:
+		switch h.attr {
+		case attrURL:
+			s = h.escapeURL(value)
+		case attrJS:
+			s = h.escapeJS(value)
+		case attrCSS:
+			s = escapeCSS(value)
+		default:
+			s = stringify(value)
+		}
+
+		if h.delim == 0 {
+			s = unquotedAttrEscaper.Replace(s)
+		} else {
+			s = HtmlEscaper.Replace(s)
+		}
+	default:
+		s = HtmlEscaper.Replace(stringify(value))
+	}
+
+	_, err := io.WriteString(w, s)
+
+	return err
+}
+
+func (h *htmlContext) escapeJS(value any) string {
+	switch h.js {
+	case jsCode:
+		if js, ok := value.(template.JS); ok {
+			return string(js)
+		}
+
+		return jsValue(value)
+	case jsDoubleQuote, jsSingleQuote, jsTemplate:
+		if js, ok := value.(template.JSStr); ok {
+			return string(js)
+		}
+
+		return escapeJSString(stringify(value))
+	default:
+		// Comments can't contain a value.
+		return ""
+	}
+}
+
+func (h *htmlContext) escapeURL(value any) string {
+	u, trusted := value.(template.URL)
+	s := stringify(value)
+	start := h.urlStart
+	if s != "" {
+		h.urlStart = false
+	}
+
+	switch {
+	case h.urlQuery:
+		return escapeURLComponent(s)
+	case start && !trusted && !isSafeURL(s):
+		return "#ZgotmplZ"
+	case trusted:
+		return normalizeURL(string(u))
+	default:
+		return normalizeURL(s)
+	}
+}
+
+// stringify returns the text that an object writes for a value.
+func stringify(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	var buf bytes.Buffer
+	if err := writeObject(&buf, value); err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return buf.String()
+}
+
+// jsValue returns a JavaScript expression for a value.
+func jsValue(value any) string {
+	// json.Marshal escapes <, >, &, U+2028, and U+2029.
+	data, err := json.Marshal(values.ToLiquid(value))
+	if err != nil {
+		data, _ = json.Marshal(stringify(value))
+	}
+
+	return string(data)
+}
+
+// escapeJSString escapes text for a JavaScript string literal.
+func escapeJSString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r < ' ' || r == '\u2028' || r == '\u2029' || strings.ContainsRune(`\'"`+"`"+`<>&=/+$`, r):
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// escapeCSS escapes text for a CSS value, string, or identifier.
+func escapeCSS(value any) string {
+	if css, ok := value.(template.CSS); ok {
+		return string(css)
+	}
+
+	s := stringify(value)
+
+	var b strings.Builder
+	for i, r := range s {
+		if r >= ' ' && !strings.ContainsRune(`"&'()+/:;<>\{}`+"`", r) {
+			b.WriteRune(r)
+			continue
+		}
+
+		fmt.Fprintf(&b, `\%x`, r)
+		// A hexadecimal digit or a space after an escape would be part of it.
+		if next := i + utf8.RuneLen(r); next < len(s) && (isHexDigit(s[next]) || s[next] == ' ') {
+			b.WriteByte(' ')
+		}
+	}
+
+	return b.String()
+}
+
+// isSafeURL reports whether a URL is relative or has a scheme that can't run
+// code.
+func isSafeURL(s string) bool {
+	scheme, _, found := strings.Cut(s, ":")
+	if !found || strings.ContainsAny(scheme, "/?#") {
+		return true
+	}
+
+	switch strings.ToLower(scheme) {
+	case "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeURL percent-encodes the characters that can't appear in a URL,
+// leaving its structure intact.
+func normalizeURL(s string) string {
+	return percentEncode(s, func(c byte) bool {
+		return isURLUnreserved(c) || strings.IndexByte("!#$%&*+,/:;=?@[]", c) >= 0
+	})
+}
+
+// escapeURLComponent percent-encodes every character that isn't unreserved,
+// for a value in a query string or fragment.
+func escapeURLComponent(s string) string {
+	return percentEncode(s, isURLUnreserved)
+}
+
+func percentEncode(s string, keep func(byte) bool) string {
+	var b strings.Builder
+	for i := range len(s) {
+		if c := s[i]; keep(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// unquotedAttrEscaper escapes the characters that would end an unquoted
+// attribute value, as well as HTML markup characters.
+var unquotedAttrEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`'`, "&#39;",
+	`<`, "&lt;",
+	`>`, "&gt;",
+	`"`, "&#34;",
+	"=", "&#61;",
+	"`", "&#96;",
+	" ", "&#32;",
+	"\t", "&#9;",
+	"\n", "&#10;",
+	"\f", "&#12;",
+	"\r", "&#13;",
+)
+
+// urlAttrs are the attributes whose values are URLs.
+var urlAttrs = map[string]bool{
+	"action": true, "archive": true, "background": true, "cite": true,
+	"classid": true, "codebase": true, "data": true, "formaction": true,
+	"href": true, "icon": true, "longdesc": true, "manifest": true,
+	"poster": true, "profile": true, "src": true, "srcset": true, "usemap": true,
+	"xmlns": true,
+}
+
+func attrKindOf(name string) attrKind {
+	if _, local, ok := strings.Cut(name, ":"); ok {
+		name = local
+	}
+
+	name = strings.TrimPrefix(name, "data-")
+
+	switch {
+	case strings.HasPrefix(name, "on"):
+		return attrJS
+	case name == "style":
+		return attrCSS
+	case urlAttrs[name] || strings.Contains(name, "url") || strings.Contains(name, "uri"):
+		return attrURL
+	default:
+		return attrPlain
+	}
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func isASCIILetter(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func isHTMLSpace(c byte) bool { return strings.IndexByte(" \t\n\f\r", c) >= 0 }
+
+func isURLUnreserved(c byte) bool {
+	return isASCIILetter(c) || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0
+}
+
+func toLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+
+	return c
+}
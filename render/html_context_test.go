@@ -0,0 +1,102 @@
+package render
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/parser"
+)
+
+var htmlContextBindings = map[string]any{
+	"markup":      "<b>\"x\" & 'y'</b>",
+	"spaced":      "a b=c",
+	"js":          "javascript:alert(1)",
+	"path":        "/a b/c?d",
+	"query":       "a&b=c d",
+	"quote":       `"); alert('x`,
+	"n":           3,
+	"list":        []any{"a", 1},
+	"css":         "red; background: url(x)",
+	"html":        template.HTML("<i>ok</i>"),
+	"attr":        template.HTMLAttr(`checked`),
+	"url":         template.URL("javascript:void(0)"),
+	"jscode":      template.JS("f()"),
+	"jsstr":       template.JSStr(`\x41`),
+	"trusted_css": template.CSS("color: red"),
+}
+
+var htmlContextTests = []struct{ in, expected string }{
+	// text and attributes
+	{`<p>{{ markup }}</p>`, `<p>&lt;b&gt;&#34;x&#34; &amp; &#39;y&#39;&lt;/b&gt;</p>`},
+	{`<p title="{{ markup }}">`, `<p title="&lt;b&gt;&#34;x&#34; &amp; &#39;y&#39;&lt;/b&gt;">`},
+	{`<p title={{ spaced }}>`, `<p title=a&#32;b&#61;c>`},
+	{`<p title="a" {{ attr }}>`, `<p title="a" checked>`},
+	{`<p>{{ html }}</p>`, `<p><i>ok</i></p>`},
+	{`<p title="{{ html }}">`, `<p title="&lt;i&gt;ok&lt;/i&gt;">`},
+	{`<!-- {{ markup }} -->{{ markup }}`, `<!-- &lt;b&gt;&#34;x&#34; &amp; &#39;y&#39;&lt;/b&gt; -->&lt;b&gt;&#34;x&#34; &amp; &#39;y&#39;&lt;/b&gt;`},
+	{`<textarea><p title="{{ markup }}"></textarea>`, `<textarea><p title="&lt;b&gt;&#34;x&#34; &amp; &#39;y&#39;&lt;/b&gt;"></textarea>`},
+
+	// URLs
+	{`<a href="{{ js }}">`, `<a href="#ZgotmplZ">`},
+	{`<a href="{{ url }}">`, `<a href="javascript:void%280%29">`},
+	{`<a href="{{ path }}">`, `<a href="/a%20b/c?d">`},
+	{`<a href="/search?q={{ query }}">`, `<a href="/search?q=a%26b%3Dc%20d">`},
+	{`<a href="/{{ js }}">`, `<a href="/javascript:alert%281%29">`},
+	{`<img data-src='{{ js }}'>`, `<img data-src='#ZgotmplZ'>`},
+
+	// scripts
+	{`<script>var x = {{ markup }};</script>`, `<script>var x = "\u003cb\u003e\"x\" \u0026 'y'\u003c/b\u003e";</script>`},
+	{`<script>var x = {{ n }}, y = {{ list }};</script>`, `<script>var x = 3, y = ["a",1];</script>`},
+	{`<script>var x = "{{ quote }}";</script>`, `<script>var x = "\u0022); alert(\u0027x";</script>`},
+	{`<script>var x = '\'{{ n }}';</script>`, `<script>var x = '\'3';</script>`},
+	{`<script>var x = {{ jscode }}, y = "{{ jsstr }}";</script>`, `<script>var x = f(), y = "\x41";</script>`},
+	{`<script>// "</script>{{ markup }}`, `<script>// "</script>&lt;b&gt;&#34;x&#34; &amp; &#39;y&#39;&lt;/b&gt;`},
+	{`<SCRIPT>x = {{ n }}</SCRIPT>{{ n }}`, `<SCRIPT>x = 3</SCRIPT>3`},
+	{`<button onclick="f({{ markup }})">`, `<button onclick="f(&#34;\u003cb\u003e\&#34;x\&#34; \u0026 &#39;y&#39;\u003c/b\u003e&#34;)">`},
+	{`<button onclick="f('{{ quote }}')">`, `<button onclick="f('\u0022); alert(\u0027x')">`},
+
+	// styles
+	{`<style>p { color: {{ css }} }</style>`, `<style>p { color: red\3b  background\3a  url\28x\29 }</style>`},
+	{`<p style="color: {{ css }}">`, `<p style="color: red\3b  background\3a  url\28x\29">`},
+	{`<p style="{{ trusted_css }}">`, `<p style="color: red">`},
+}
+
+func TestHtmlContextEscaper(t *testing.T) {
+	cfg := NewConfig()
+	cfg.SetAutoEscapeReplacer(HtmlContextEscaper)
+
+	for _, test := range htmlContextTests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, parser.SourceLoc{})
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			err = Render(root, buf, htmlContextBindings, cfg)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestHtmlContextEscaper_partials(t *testing.T) {
+	cfg := NewConfig()
+	cfg.SetAutoEscapeReplacer(HtmlContextEscaper)
+	cfg.Cache["partial"] = []byte(`{{ n }}, "{{ quote }}"`)
+	cfg.AddTag("partial", func(string) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			return ctx.(rendererContext).RenderFileTo(w, "partial", nil)
+		}, nil
+	})
+
+	root, err := cfg.Compile(`<script>f({% partial %})</script>{{ markup | safe }}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	err = Render(root, buf, htmlContextBindings, cfg)
+	require.NoError(t, err)
+	require.Equal(t, `<script>f(3, "\u0022); alert(\u0027x")</script><b>"x" & 'y'</b>`, buf.String())
+}
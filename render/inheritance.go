@@ -0,0 +1,166 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"slices"
+
+	"github.com/osteele/liquid/parser"
+)
+
+// inheritance is the state of template inheritance, shared by a template and
+// the chain of layouts that it extends.
+type inheritance struct {
+	blocks    map[string][]inheritedBlock // the overriding blocks, most derived first
+	rendering int                         // the depth of inherited block bodies being rendered
+}
+
+// An inheritedBlock is a block tag, and the context of the template that
+// defines it.
+type inheritedBlock struct {
+	node *BlockNode
+	ctx  *nodeContext
+}
+
+// layoutRequest records the layout tag that a template has executed.
+type layoutRequest struct {
+	filename string
+	loc      parser.Locatable
+}
+
+func (c *nodeContext) inheritanceState() *inheritance {
+	if c.inheritance == nil {
+		c.inheritance = &inheritance{blocks: map[string][]inheritedBlock{}}
+	}
+
+	return c.inheritance
+}
+
+// SetLayout makes the current template extend the named template. The rest
+// of the template's output is discarded, apart from the blocks that override
+// those of the layout, and the layout is rendered in its place. It is used as
+// an optional internal extension to Context by the layout tag.
+func (c rendererContext) SetLayout(w io.Writer, filename string) error {
+	if c.ctx.layout != nil {
+		return c.Errorf("a template can only have one layout")
+	}
+
+	if tw, ok := w.(*trimWriter); ok {
+		if _, err := tw.Flush(); err != nil {
+			return err
+		}
+
+		tw.w = io.Discard
+		tw.html = nil
+	}
+
+	var loc parser.Locatable = c.cn
+	if c.node != nil {
+		loc = c.node
+	}
+
+	c.ctx.layout = &layoutRequest{filename: filename, loc: loc}
+
+	return nil
+}
+
+// RenderInheritedBlock renders the current block, or the block of the same
+// name from the most derived template that overrides it. In a template that
+// has a layout, it instead records the block as an override. It is used as an
+// optional internal extension to Context by the block tag.
+func (c rendererContext) RenderInheritedBlock(w io.Writer, name string) error {
+	inh := c.ctx.inheritanceState()
+	block := inheritedBlock{c.cn, c.ctx}
+
+	if c.ctx.layout != nil && inh.rendering == 0 {
+		// A block in a loop is recorded the first time that it renders.
+		// The compiler reports distinct blocks with the same name.
+		for _, b := range inh.blocks[name] {
+			if b.ctx == c.ctx {
+				return nil
+			}
+		}
+
+		inh.blocks[name] = append(inh.blocks[name], block)
+
+		return nil
+	}
+
+	return inh.render(w, append(slices.Clone(inh.blocks[name]), block))
+}
+
+// render renders the first block of chain. The blocks that it overrides are
+// available to it as block.super.
+func (inh *inheritance) render(w io.Writer, chain []inheritedBlock) error {
+	b := chain[0]
+	super := &superBlock{inh: inh, chain: chain[1:], config: &b.ctx.config}
+
+	saved, bound := b.ctx.bindings["block"]
+	b.ctx.bindings["block"] = map[string]any{"super": super}
+	inh.rendering++
+
+	err := b.ctx.RenderSequence(w, b.node.Body)
+
+	inh.rendering--
+	if bound {
+		b.ctx.bindings["block"] = saved
+	} else {
+		delete(b.ctx.bindings, "block")
+	}
+
+	if super.err != nil {
+		return super.err
+	}
+
+	return err
+}
+
+// superBlock is the value of block.super. It renders the overridden blocks
+// the first time that it is used.
+type superBlock struct {
+	inh      *inheritance
+	chain    []inheritedBlock
+	config   *Config // marks the output as markup; see Config.markup
+	rendered bool
+	value    any
+	err      error
+}
+
+func (s *superBlock) ToLiquid() any {
+	if !s.rendered {
+		s.rendered = true
+
+		buf := new(bytes.Buffer)
+		if len(s.chain) > 0 {
+			s.err = s.inh.render(buf, s.chain)
+		}
+
+		s.value = s.config.markup(buf.String())
+	}
+
+	return s.value
+}
+
+// renderLayout renders the layout of a template that has executed a layout
+// tag, with the blocks that the template and its descendants override.
+func (c *nodeContext) renderLayout(w io.Writer) Error {
+	layout := c.layout
+	if err := c.checkIncludeDepth(); err != nil {
+		return wrapRenderError(err, layout.loc)
+	}
+
+	root, err := c.loadPartial(layout.filename)
+	if err != nil {
+		return c.recoverError(w, wrapRenderError(err, layout.loc))
+	}
+
+	lc := c.child(c.bindings)
+	lc.inheritance = c.inheritanceState()
+	lc.setScope(c.scope)
+
+	if err := renderWithContext(root, w, lc); err != nil {
+		return err
+	}
+
+	return lc.strayLoopSignal()
+}
@@ -0,0 +1,489 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/osteele/liquid/ast"
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+)
+
+// A macroSignature is the name and parameters of a macro, e.g.
+// "price(amount, currency='USD')".
+type macroSignature struct {
+	name   string
+	params []macroParam
+}
+
+type macroParam struct {
+	name string
+	def  expressions.Expression // the default value, or nil if the parameter is required
+}
+
+// A macro is a macro block, as the compiler hoists it to the root of its
+// template.
+type macro struct {
+	*macroSignature
+
+	body   []Node
+	macros map[string]*macro // the macros that the body can call
+}
+
+var macroNameRe = regexp.MustCompile(`^[a-zA-Z_][\w-]*\??$`)
+
+// AddMacroBlock defines a block that defines a macro: a fragment of the
+// template with parameters, that expressions later in the same template call
+// like a function, e.g. {% macro price(amount, currency='USD') %}…{% endmacro %}
+// and {{ price(product.price) }}. A parameter default is evaluated, when the
+// macro is called without that argument, in the scope of the parameters
+// before it.
+//
+// A macro can be called anywhere in the template that defines it, before or
+// after its block, whether or not the block is within a block that renders;
+// the block itself renders nothing. Its body renders in a scope that contains
+// only its parameters, as a partial that the render tag renders does. The
+// compiler reports macros that are defined more than once, calls to macros
+// that the template doesn't define, and calls whose arguments don't match the
+// parameters.
+func (c *Config) AddMacroBlock(name string) {
+	b := c.AddBlock(name)
+	b.tag.isMacro = true
+	b.ConfigCompiler(macroBlockCompiler)
+
+	c.AddConfigBlockAnalyzer(name, macroBlockAnalyzer)
+}
+
+// macroBlockCompiler checks the signature of a macro block. The compiler
+// defines the macro; see compileMacros.
+func macroBlockCompiler(c *Config, node BlockNode) (func(io.Writer, Context) error, error) {
+	if _, err := c.parseMacroSignature(node.Args); err != nil {
+		return nil, err
+	}
+
+	return noopRenderer, nil
+}
+
+func macroBlockAnalyzer(c *Config, node BlockNode) NodeAnalysis {
+	sig, err := c.parseMacroSignature(node.Args)
+	if err != nil {
+		return NodeAnalysis{}
+	}
+
+	var na NodeAnalysis
+	for _, p := range sig.params {
+		if p.def != nil {
+			na.Arguments = append(na.Arguments, p.def)
+		}
+
+		na.Locals = append(na.Locals, p.name)
+	}
+
+	return na
+}
+
+// parseMacroSignature parses a macro name, followed by an optional list of
+// parameters. A parameter is a name, or a name and a default value, which is
+// parsed with the configuration's ParseTable.
+func (c *Config) parseMacroSignature(source string) (*macroSignature, error) {
+	source = strings.TrimSpace(source)
+	name, params, hasParams := strings.Cut(source, "(")
+	name = strings.TrimSpace(name)
+
+	if !macroNameRe.MatchString(name) {
+		return nil, fmt.Errorf("macro requires a name, e.g. price(amount, currency='USD'); got %q", source)
+	}
+
+	sig := &macroSignature{name: name}
+	if !hasParams {
+		return sig, nil
+	}
+
+	params, ok := strings.CutSuffix(params, ")")
+	if !ok {
+		return nil, fmt.Errorf("macro %q: expected ) at the end of the parameters", name)
+	}
+
+	if strings.TrimSpace(params) == "" {
+		return sig, nil
+	}
+
+	for _, param := range splitMacroParams(params) {
+		pname, def, hasDefault := strings.Cut(param, "=")
+		pname = strings.TrimSpace(pname)
+
+		if !macroNameRe.MatchString(pname) {
+			return nil, fmt.Errorf("macro %q: invalid parameter %q", name, strings.TrimSpace(param))
+		}
+
+		if sig.index(pname) >= 0 {
+			return nil, fmt.Errorf("macro %q: duplicate parameter %q", name, pname)
+		}
+
+		p := macroParam{name: pname}
+		if hasDefault {
+			expr, err := c.ParseExpression(def)
+			if err != nil {
+				return nil, fmt.Errorf("macro %q: default value of %q: %w", name, pname, err)
+			}
+
+			p.def = expr
+		}
+
+		sig.params = append(sig.params, p)
+	}
+
+	return sig, nil
+}
+
+// splitMacroParams splits a parameter list at the commas that aren't within a
+// string or brackets.
+func splitMacroParams(s string) []string {
+	var (
+		params []string
+		depth  int
+		quote  byte
+		start  int
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			depth--
+		case ch == ',' && depth == 0:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(params, s[start:])
+}
+
+// index returns the position of the named parameter, or -1.
+func (s *macroSignature) index(name string) int {
+	for i, p := range s.params {
+		if p.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// checkCall reports whether a call with nargs positional arguments and the
+// named keyword arguments supplies each required parameter exactly once.
+func (s *macroSignature) checkCall(nargs int, keywords []string) error {
+	if nargs > len(s.params) {
+		return fmt.Errorf("macro %q takes %d arguments; got %d", s.name, len(s.params), nargs)
+	}
+
+	supplied := make([]bool, len(s.params))
+	for i := range nargs {
+		supplied[i] = true
+	}
+
+	for _, kw := range keywords {
+		i := s.index(kw)
+		switch {
+		case i < 0:
+			return fmt.Errorf("macro %q has no parameter %q", s.name, kw)
+		case supplied[i]:
+			return fmt.Errorf("macro %q got more than one value for %q", s.name, kw)
+		}
+
+		supplied[i] = true
+	}
+
+	for i, p := range s.params {
+		if !supplied[i] && p.def == nil {
+			return fmt.Errorf("macro %q is missing argument %q", s.name, p.name)
+		}
+	}
+
+	return nil
+}
+
+// CallFunction renders a macro that the template has defined, and returns its
+// output. It implements expressions.Caller.
+func (c *nodeContext) CallFunction(name string, args []any, kwargs map[string]any) (any, error) {
+	m, ok := c.macros[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined macro %q", name)
+	}
+
+	keywords := make([]string, 0, len(kwargs))
+	for k := range kwargs {
+		keywords = append(keywords, k)
+	}
+
+	sort.Strings(keywords)
+
+	if err := m.checkCall(len(args), keywords); err != nil {
+		return nil, err
+	}
+
+	if err := c.checkIncludeDepth(); err != nil {
+		return nil, err
+	}
+
+	mc := c.child(map[string]any{})
+	mc.macros = m.macros
+
+	for i, p := range m.params {
+		value, ok := kwargs[p.name]
+		switch {
+		case i < len(args):
+			value = args[i]
+		case !ok:
+			v, err := mc.Evaluate(p.def)
+			if err != nil {
+				return nil, err
+			}
+
+			value = v
+		}
+
+		mc.bindings[p.name] = value
+	}
+
+	buf := new(bytes.Buffer)
+	if err := mc.RenderSequence(buf, m.body); err != nil {
+		return nil, err
+	}
+
+	if err := mc.strayLoopSignal(); err != nil {
+		return nil, err
+	}
+
+	return c.config.markup(buf.String()), nil
+}
+
+func (g grammar) hasMacros() bool {
+	for _, def := range g.blockDefs {
+		if def.isMacro {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileMacros defines the macros of a compiled template, for the root to
+// bind when it renders, and reports the calls to macros that the template
+// doesn't define, or whose arguments don't match the macro's parameters. The
+// compiler only uses it if the configuration has a macro block; otherwise
+// checkNoCalls reports every call.
+func (c *compiler) compileMacros(root Node) (Node, parser.Error) {
+	mc := macroChecker{compiler: c, macros: map[string]*macro{}}
+	mc.define(root)
+
+	if mc.err == nil {
+		c.inspectCalls(root, func(loc parser.Locatable, call *ast.Call) bool {
+			if err := mc.checkCall(call); err != nil {
+				mc.fail(parser.Errorf(loc, "%s", err))
+			}
+
+			return mc.err == nil
+		})
+	}
+
+	if mc.err != nil {
+		return nil, mc.err
+	}
+
+	if len(mc.macros) == 0 {
+		return root, nil
+	}
+
+	seq, ok := root.(*SeqNode)
+	if !ok {
+		seq = &SeqNode{Children: []Node{root}}
+	}
+
+	seq.macros = mc.macros
+
+	return seq, nil
+}
+
+// checkNoCalls reports the calls in a template that is compiled by a
+// configuration without a macro block, which can't define what they call.
+func (c *compiler) checkNoCalls(root Node) parser.Error {
+	var err parser.Error
+
+	c.inspectCalls(root, func(loc parser.Locatable, call *ast.Call) bool {
+		e := parser.Errorf(loc, "undefined macro %q: macros are not enabled", call.Name)
+		if !c.recover(e) {
+			err = e
+		}
+
+		return err == nil
+	})
+
+	return err
+}
+
+type macroChecker struct {
+	*compiler
+
+	macros map[string]*macro
+	err    parser.Error
+}
+
+// fail records err, unless the error mode allows the compiler to continue
+// past it.
+func (mc *macroChecker) fail(err parser.Error) {
+	if !mc.recover(err) {
+		mc.err = err
+	}
+}
+
+// define adds the macros of the macro blocks within n.
+func (mc *macroChecker) define(n Node) {
+	if mc.err != nil {
+		return
+	}
+
+	switch n := n.(type) {
+	case *BlockNode:
+		if def, ok := mc.findBlockDef(n.Name); ok && def.isMacro {
+			// The block compiler has reported a bad signature.
+			if sig, err := mc.parseMacroSignature(n.Args); err == nil {
+				if _, ok := mc.macros[sig.name]; ok {
+					mc.fail(parser.Errorf(n, "macro %q is defined more than once", sig.name))
+					if mc.err != nil {
+						return
+					}
+				} else {
+					mc.macros[sig.name] = &macro{sig, n.Body, mc.macros}
+				}
+			}
+		}
+
+		for _, child := range n.Body {
+			mc.define(child)
+		}
+
+		for _, clause := range n.Clauses {
+			for _, child := range clause.Body {
+				mc.define(child)
+			}
+		}
+	case *SeqNode:
+		for _, child := range n.Children {
+			mc.define(child)
+		}
+	}
+}
+
+func (mc *macroChecker) checkCall(call *ast.Call) error {
+	m, ok := mc.macros[call.Name]
+	if !ok {
+		return fmt.Errorf("undefined macro %q", call.Name)
+	}
+
+	keywords := make([]string, len(call.KeywordArgs))
+	for i, kw := range call.KeywordArgs {
+		keywords[i] = kw.Name
+	}
+
+	return m.checkCall(len(call.Args), keywords)
+}
+
+// inspectCalls calls fn for each call in the expressions within n, with the
+// node that contains it, until fn returns false. Since a call is a name
+// followed by arguments in parentheses, it only analyzes the objects and
+// tags whose source contains a parenthesis. It reports whether fn always
+// returned true.
+func (c *compiler) inspectCalls(n Node, fn func(parser.Locatable, *ast.Call) bool) bool {
+	switch n := n.(type) {
+	case *BlockNode:
+		if analyze, ok := c.blockAnalyzers[n.Name]; ok && blockHasParens(n) {
+			if !inspectArgumentCalls(n, analyze(c.Config, *n).Arguments, fn) {
+				return false
+			}
+		}
+
+		if !c.inspectNodeCalls(n.Body, fn) {
+			return false
+		}
+
+		for _, clause := range n.Clauses {
+			if !c.inspectNodeCalls(clause.Body, fn) {
+				return false
+			}
+		}
+	case *TagNode:
+		if analyze, ok := c.tagAnalyzers[n.Name]; ok && strings.Contains(n.Args, "(") {
+			return inspectArgumentCalls(n, analyze(c.Config, n.Args).Arguments, fn)
+		}
+	case *ObjectNode:
+		if strings.Contains(n.Args, "(") {
+			return inspectArgumentCalls(n, []expressions.Expression{n.expr}, fn)
+		}
+	case *SeqNode:
+		return c.inspectNodeCalls(n.Children, fn)
+	}
+
+	return true
+}
+
+func (c *compiler) inspectNodeCalls(nodes []Node, fn func(parser.Locatable, *ast.Call) bool) bool {
+	for _, n := range nodes {
+		if !c.inspectCalls(n, fn) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func blockHasParens(n *BlockNode) bool {
+	if strings.Contains(n.Args, "(") {
+		return true
+	}
+
+	for _, clause := range n.Clauses {
+		if strings.Contains(clause.Args, "(") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inspectArgumentCalls(loc parser.Locatable, exprs []expressions.Expression, fn func(parser.Locatable, *ast.Call) bool) bool {
+	ok := true
+
+	for _, expr := range exprs {
+		tree := expressions.SyntaxTree(expr)
+		if tree == nil {
+			continue
+		}
+
+		ast.Inspect(tree, func(n ast.Node) bool {
+			if call, isCall := n.(*ast.Call); isCall && ok {
+				ok = fn(loc, call)
+			}
+
+			return ok
+		})
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,72 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/expressions"
+)
+
+func TestParseMacroSignature(t *testing.T) {
+	tests := []struct {
+		in       string
+		name     string
+		params   []string
+		defaults []bool
+	}{
+		{`f`, "f", nil, nil},
+		{` f() `, "f", nil, nil},
+		{`price(amount, currency='USD')`, "price", []string{"amount", "currency"}, []bool{false, true}},
+		{`f(a = "x, y", b=(1..3), c=d[", "])`, "f", []string{"a", "b", "c"}, []bool{true, true, true}},
+	}
+
+	cfg := NewConfig()
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			sig, err := cfg.parseMacroSignature(test.in)
+			require.NoError(t, err)
+			require.Equal(t, test.name, sig.name)
+
+			var (
+				params   []string
+				defaults []bool
+			)
+			for _, p := range sig.params {
+				params = append(params, p.name)
+				defaults = append(defaults, p.def != nil)
+			}
+
+			require.Equal(t, test.params, params)
+			require.Equal(t, test.defaults, defaults)
+		})
+	}
+
+	for _, in := range []string{``, `f(`, `1f()`, `f(a,)`, `f(a=)`, `f(a, a)`} {
+		_, err := cfg.parseMacroSignature(in)
+		require.Errorf(t, err, in)
+	}
+
+	// the defaults are parsed with the configuration's parse table
+	cfg.ParseTable = expressions.NewRecordingParseTable()
+	_, err := cfg.parseMacroSignature(`f(a='x')`)
+	require.NoError(t, err)
+	require.Len(t, cfg.ParseTable.Parses(), 1)
+	require.Equal(t, "'x'", cfg.ParseTable.Parses()[0].Source)
+}
+
+func TestMacroSignature_checkCall(t *testing.T) {
+	cfg := NewConfig()
+	sig, err := cfg.parseMacroSignature(`f(a, b, c=1)`)
+	require.NoError(t, err)
+
+	require.NoError(t, sig.checkCall(2, nil))
+	require.NoError(t, sig.checkCall(3, nil))
+	require.NoError(t, sig.checkCall(1, []string{"b"}))
+	require.NoError(t, sig.checkCall(0, []string{"c", "b", "a"}))
+
+	require.EqualError(t, sig.checkCall(4, nil), `macro "f" takes 3 arguments; got 4`)
+	require.EqualError(t, sig.checkCall(1, nil), `macro "f" is missing argument "b"`)
+	require.EqualError(t, sig.checkCall(1, []string{"a", "b"}), `macro "f" got more than one value for "a"`)
+	require.EqualError(t, sig.checkCall(2, []string{"d"}), `macro "f" has no parameter "d"`)
+}
@@ -2,9 +2,13 @@ package render
 
 import (
 	"bytes"
+	"context"
 	"maps"
+	"strings"
 
 	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+	"github.com/osteele/liquid/values"
 )
 
 // nodeContext provides the evaluation context for rendering the AST.
@@ -14,8 +18,30 @@ import (
 type nodeContext struct {
 	bindings     map[string]any
 	config       Config
+	context      context.Context
+	done         <-chan struct{}
 	exprCtx      expressions.Context
 	partialCache map[string]cachedPartial
+	state        *renderState
+	depth        int            // include and render nesting depth
+	scope        values.Value   // the variables that bindings doesn't define; see RenderValue
+	layout       *layoutRequest // set by the layout tag
+	inheritance  *inheritance   // shared by a template and its layouts
+	macros       map[string]*macro
+	assigned     map[string]bool // the variables that Assign has set
+	inputs       map[string]any  // the variables that the render started with
+
+	loopSignal    LoopSignal       // set by a break or continue tag; see LoopSignal
+	loopSignalLoc parser.Locatable // the tag that set loopSignal
+}
+
+// renderState is shared by the contexts of a top-level render and its partials.
+type renderState struct {
+	iterations int
+	counters   map[string]int              // the increment and decrement counters
+	offsets    map[string]int              // the loop positions for offset:continue, by forloop.name
+	registers  map[string]any              // the host's state; see WithRegisters
+	regions    map[string]*strings.Builder // the content_for regions
 }
 
 type cachedPartial struct {
@@ -24,43 +50,132 @@ type cachedPartial struct {
 }
 
 // newNodeContext creates a new evaluation context.
-func newNodeContext(scope map[string]any, c Config) *nodeContext {
+func newNodeContext(ctx context.Context, scope map[string]any, c Config) *nodeContext {
 	// The assign tag modifies the scope, so make a copy first.
 	// TODO this isn't really the right place for this.
 	vars := make(map[string]any, len(scope))
 	maps.Copy(vars, scope)
 
 	c.Config.StrictVariables = c.StrictVariables
-	ctx := nodeContext{
+	c.Config.MaxRangeSize = c.Limits.MaxRangeSize
+	nc := nodeContext{
 		bindings: vars,
+		inputs:   scope,
 		config:   c,
+		context:  ctx,
+		done:     ctx.Done(),
+		state:    &renderState{registers: RegistersFromContext(ctx)},
 	}
-	ctx.exprCtx = expressions.NewContext(vars, c.Config.Config)
-	return &ctx
+	nc.exprCtx = expressions.NewContext(vars, c.Config.Config)
+	nc.exprCtx.(filterArgumentSetter).SetFilterArguments(&nc)
+	nc.exprCtx.(callerSetter).SetCaller(&nc)
+	nc.exprCtx.(countersSetter).SetCounters(&nc)
+	return &nc
+}
+
+type filterArgumentSetter interface {
+	SetFilterArguments(expressions.FilterArguments)
+}
+
+type callerSetter interface {
+	SetCaller(expressions.Caller)
+}
+
+type countersSetter interface {
+	SetCounters(expressions.Counters)
 }
 
 func (c *nodeContext) child(scope map[string]any) *nodeContext {
-	child := newNodeContext(scope, c.config)
+	child := newNodeContext(c.context, scope, c.config)
 	child.partialCache = c.partialCache
+	child.state = c.state
+	child.depth = c.depth + 1
 	return child
 }
 
-func (c *nodeContext) cachedPartial(filename string, source []byte) (Node, bool) {
-	entry, ok := c.partialCache[filename]
-	return entry.root, ok && bytes.Equal(entry.source, source)
+// countIteration records a loop iteration against Limits.MaxIterations.
+func (c *nodeContext) countIteration() error {
+	c.state.iterations++
+	if limit := c.config.Limits.MaxIterations; limit > 0 && c.state.iterations > limit {
+		return expressions.LimitError{Limit: "loop iteration", Max: limit}
+	}
+
+	return nil
+}
+
+// checkIncludeDepth reports whether another level of include or render nesting is allowed.
+func (c *nodeContext) checkIncludeDepth() error {
+	limit := c.config.Limits.MaxIncludeDepth
+	if limit == 0 {
+		limit = DefaultMaxIncludeDepth
+	}
+
+	if limit > 0 && c.depth >= limit {
+		return expressions.LimitError{Limit: "include depth", Max: limit}
+	}
+
+	return nil
+}
+
+// canceled returns the context's error if it has been canceled or its
+// deadline has passed. It doesn't allocate, so it is cheap enough to call
+// before every node and loop iteration.
+func (c *nodeContext) canceled() error {
+	select {
+	case <-c.done:
+		return c.context.Err()
+	default:
+		return nil
+	}
 }
 
-func (c *nodeContext) cachePartial(filename string, source []byte, root Node) {
+// loadPartial returns the compiled template of a partial. It uses the
+// configuration's Compiled templates, then its PartialCache if there is one,
+// and otherwise a cache that lasts for the current render.
+func (c *nodeContext) loadPartial(filename string) (Node, error) {
+	if root, ok := c.config.compiledPartial(filename); ok {
+		return root, nil
+	}
+
+	if pc := c.config.PartialCache; pc != nil {
+		return pc.load(&c.config, filename)
+	}
+
+	source, err := c.config.readTemplate(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry, ok := c.partialCache[filename]; ok && bytes.Equal(entry.source, source) {
+		return entry.root, nil
+	}
+
+	root, err := c.config.Compile(string(source), parser.SourceLoc{Pathname: filename, LineNo: 1})
+	if err != nil {
+		return nil, err
+	}
+
 	if c.partialCache == nil {
 		c.partialCache = make(map[string]cachedPartial)
 	}
+
 	c.partialCache[filename] = cachedPartial{
 		source: bytes.Clone(source),
 		root:   root,
 	}
+
+	return root, nil
 }
 
 // Evaluate evaluates an expression within the template context.
 func (c *nodeContext) Evaluate(expr expressions.Expression) (out any, err error) {
 	return expr.Evaluate(c.exprCtx)
 }
+
+// Counter returns the value of an increment and decrement counter, which is
+// the value of a variable that the bindings don't define, as in Shopify
+// Liquid.
+func (c *nodeContext) Counter(name string) (int, bool) {
+	n, ok := c.state.counters[name]
+	return n, ok
+}
@@ -54,6 +54,7 @@ type SeqNode struct {
 	sourcelessNode
 
 	Children []Node
+	macros   map[string]*macro // for the root of a template, the macros that it defines
 }
 
 // TrimNode is a trim object.
@@ -0,0 +1,245 @@
+package render
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"io/fs"
+	"sync"
+
+	"github.com/osteele/liquid/parser"
+)
+
+// A TemplateVersioner is a TemplateStore that can report a template's version,
+// such as its modification time, without reading it. A PartialCache uses the
+// version to decide whether a compiled partial is current. If the store isn't
+// a TemplateVersioner, or TemplateVersion returns "", the cache reads the
+// template and compares its source instead.
+type TemplateVersioner interface {
+	TemplateVersion(templatename string) (string, error)
+}
+
+// PartialCache holds the compiled templates of the partials that the include
+// and render tags read, across renders. It is safe for concurrent use. Assign
+// one to Config.PartialCache to use it.
+//
+// Without a cache, each render reads and compiles each partial that it uses.
+// With a cache, a render reads a partial, and compiles it only if its source
+// has changed. If the template store is a TemplateVersioner, a render
+// doesn't read a partial whose version is unchanged.
+type PartialCache struct {
+	maxEntries int
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        list.List // of *partialCacheEntry, most recently used first
+	generation uint64    // advanced by Config.ResetPartialCache
+}
+
+type partialCacheEntry struct {
+	name       string
+	version    string
+	source     []byte
+	root       Node
+	generation uint64 // the generation of the configuration that compiled root
+}
+
+// NewPartialCache creates a PartialCache that holds at most maxEntries
+// partials, evicting the least recently used. Zero means no limit.
+func NewPartialCache(maxEntries int) *PartialCache {
+	return &PartialCache{maxEntries: maxEntries, entries: map[string]*list.Element{}}
+}
+
+// Invalidate removes a partial from the cache.
+func (pc *PartialCache) Invalidate(name string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if e, ok := pc.entries[name]; ok {
+		pc.lru.Remove(e)
+		delete(pc.entries, name)
+	}
+}
+
+// Clear removes every partial from the cache.
+func (pc *PartialCache) Clear() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.entries = map[string]*list.Element{}
+	pc.lru.Init()
+}
+
+// SetPartialCache sets c's PartialCache. The configuration uses the partials
+// that pc holds for the configurations whose changes pc has seen.
+func (c *Config) SetPartialCache(pc *PartialCache) {
+	c.PartialCache = pc
+	c.partialGeneration = 0
+
+	if pc != nil {
+		pc.mu.Lock()
+		c.partialGeneration = pc.generation
+		pc.mu.Unlock()
+	}
+}
+
+// ResetPartialCache clears c's PartialCache, if it has one, for a change to c
+// that affects how partials compile. The cache then holds only the partials
+// that c, and the configurations made from it, compile: a render that began
+// with an earlier configuration, and finishes compiling a partial after the
+// reset, doesn't store it. It also forgets which of the Compiled templates
+// the template store has, for a change to the store.
+func (c *Config) ResetPartialCache() {
+	c.storeHas = &sync.Map{}
+
+	pc := c.PartialCache
+	if pc == nil {
+		return
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.generation++
+	pc.entries = map[string]*list.Element{}
+	pc.lru.Init()
+	c.partialGeneration = pc.generation
+}
+
+// MaxEntries returns the cache's size limit. Zero means no limit.
+func (pc *PartialCache) MaxEntries() int {
+	return pc.maxEntries
+}
+
+// Len returns the number of partials in the cache.
+func (pc *PartialCache) Len() int {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	return len(pc.entries)
+}
+
+func (pc *PartialCache) get(name string, generation uint64) (partialCacheEntry, bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	e, ok := pc.entries[name]
+	if !ok || e.Value.(*partialCacheEntry).generation != generation {
+		return partialCacheEntry{}, false
+	}
+
+	pc.lru.MoveToFront(e)
+
+	return *e.Value.(*partialCacheEntry), true
+}
+
+func (pc *PartialCache) put(entry partialCacheEntry) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	// a configuration from before the last reset may compile differently
+	if entry.generation != pc.generation {
+		return
+	}
+
+	if e, ok := pc.entries[entry.name]; ok {
+		*e.Value.(*partialCacheEntry) = entry
+		pc.lru.MoveToFront(e)
+
+		return
+	}
+
+	pc.entries[entry.name] = pc.lru.PushFront(&entry)
+
+	for pc.maxEntries > 0 && pc.lru.Len() > pc.maxEntries {
+		oldest := pc.lru.Back()
+		pc.lru.Remove(oldest)
+		delete(pc.entries, oldest.Value.(*partialCacheEntry).name)
+	}
+}
+
+// load returns the compiled partial, reading and compiling it if it is not
+// in the cache or has changed.
+func (pc *PartialCache) load(c *Config, filename string) (Node, error) {
+	var version string
+	if v, ok := c.TemplateStore.(TemplateVersioner); ok {
+		// If the version is unavailable, ReadTemplate reports the error.
+		version, _ = v.TemplateVersion(filename)
+	}
+
+	cached, ok := pc.get(filename, c.partialGeneration)
+	if ok && version != "" && cached.version == version {
+		return cached.root, nil
+	}
+
+	source, err := c.readTemplate(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	root := cached.root
+	if !ok || !bytes.Equal(cached.source, source) {
+		root, err = c.Compile(string(source), parser.SourceLoc{Pathname: filename, LineNo: 1})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pc.put(partialCacheEntry{
+		name:       filename,
+		version:    version,
+		source:     bytes.Clone(source),
+		root:       root,
+		generation: c.partialGeneration,
+	})
+
+	return root, nil
+}
+
+// readTemplate reads a partial from the template store, or from Cache or
+// Sources if the store doesn't have it.
+func (c *Config) readTemplate(filename string) ([]byte, error) {
+	source, err := c.TemplateStore.ReadTemplate(filename)
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		if cval, ok := c.Cache[filename]; ok {
+			return cval, nil
+		}
+
+		if cval, ok := c.Sources.Get(filename); ok {
+			return cval, nil
+		}
+	}
+
+	return source, err
+}
+
+// compiledPartial returns the template that Compiled holds for a partial, if
+// the template store doesn't have it. If the store is a TemplateVersioner,
+// its version tells whether it has the template. Otherwise the store is read
+// once for each path, until ResetPartialCache.
+func (c *Config) compiledPartial(filename string) (Node, bool) {
+	root, ok := c.Compiled[filename]
+	if !ok {
+		return nil, false
+	}
+
+	if v, ok := c.TemplateStore.(TemplateVersioner); ok {
+		_, err := v.TemplateVersion(filename)
+		return root, errors.Is(err, fs.ErrNotExist)
+	}
+
+	if c.storeHas != nil {
+		if has, ok := c.storeHas.Load(filename); ok {
+			return root, !has.(bool)
+		}
+	}
+
+	_, err := c.TemplateStore.ReadTemplate(filename)
+	missing := errors.Is(err, fs.ErrNotExist)
+
+	if c.storeHas != nil {
+		c.storeHas.Store(filename, !missing)
+	}
+
+	return root, missing
+}
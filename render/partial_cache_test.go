@@ -0,0 +1,254 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/parser"
+)
+
+// countingStore is a TemplateStore that counts reads. Wrap it in a
+// versionedStore to make it a TemplateVersioner.
+type countingStore struct {
+	mu        sync.Mutex
+	templates map[string]string
+	versions  map[string]string
+	reads     map[string]int
+}
+
+func newCountingStore(templates map[string]string) *countingStore {
+	return &countingStore{templates: templates, reads: map[string]int{}}
+}
+
+func (s *countingStore) ReadTemplate(name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reads[name]++
+	source, ok := s.templates[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	return []byte(source), nil
+}
+
+func (s *countingStore) set(name, source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.templates[name] = source
+}
+
+func (s *countingStore) readCount(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reads[name]
+}
+
+type versionedStore struct{ *countingStore }
+
+func (s versionedStore) TemplateVersion(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.templates[name]; !ok {
+		return "", fs.ErrNotExist
+	}
+
+	return s.versions[name], nil
+}
+
+func renderPartial(t *testing.T, cfg Config, name string) string {
+	t.Helper()
+
+	ctx := newNodeContext(context.Background(), map[string]any{}, cfg)
+
+	var buf bytes.Buffer
+	require.NoError(t, rendererContext{ctx: ctx}.RenderFileTo(&buf, name, nil))
+
+	return buf.String()
+}
+
+func TestPartialCache(t *testing.T) {
+	store := newCountingStore(map[string]string{"a": "{{ 1 }}"})
+	cfg := NewConfig()
+	cfg.TemplateStore = store
+	cfg.PartialCache = NewPartialCache(0)
+
+	require.Equal(t, "1", renderPartial(t, cfg, "a"))
+	require.Equal(t, "1", renderPartial(t, cfg, "a"))
+	require.Equal(t, 1, cfg.PartialCache.Len())
+	first, _ := cfg.PartialCache.get("a", 0)
+
+	// Without a TemplateVersioner, the cache rereads the source, and
+	// recompiles only if it has changed.
+	require.Equal(t, 2, store.readCount("a"))
+	renderPartial(t, cfg, "a")
+	cached, _ := cfg.PartialCache.get("a", 0)
+	require.Same(t, first.root, cached.root)
+
+	store.set("a", "{{ 2 }}")
+	require.Equal(t, "2", renderPartial(t, cfg, "a"))
+
+	cfg.PartialCache.Invalidate("a")
+	require.Equal(t, 0, cfg.PartialCache.Len())
+	require.Equal(t, "2", renderPartial(t, cfg, "a"))
+
+	cfg.PartialCache.Clear()
+	require.Equal(t, 0, cfg.PartialCache.Len())
+}
+
+func TestPartialCache_versions(t *testing.T) {
+	store := versionedStore{newCountingStore(map[string]string{"a": "v1"})}
+	store.versions = map[string]string{"a": "1"}
+	cfg := NewConfig()
+	cfg.TemplateStore = store
+	cfg.PartialCache = NewPartialCache(0)
+
+	require.Equal(t, "v1", renderPartial(t, cfg, "a"))
+	require.Equal(t, "v1", renderPartial(t, cfg, "a"))
+	require.Equal(t, 1, store.readCount("a"))
+
+	// A changed source with an unchanged version isn't seen.
+	store.set("a", "v2")
+	require.Equal(t, "v1", renderPartial(t, cfg, "a"))
+
+	store.mu.Lock()
+	store.versions["a"] = "2"
+	store.mu.Unlock()
+	require.Equal(t, "v2", renderPartial(t, cfg, "a"))
+	require.Equal(t, 2, store.readCount("a"))
+}
+
+func TestPartialCache_reset(t *testing.T) {
+	store := newCountingStore(map[string]string{"a": "{{ 1 }}"})
+	old := NewConfig()
+	old.TemplateStore = store
+	old.SetPartialCache(NewPartialCache(0))
+	require.Equal(t, "1", renderPartial(t, old, "a"))
+
+	cfg := old.Clone()
+	cfg.ResetPartialCache()
+	require.Equal(t, 0, cfg.PartialCache.Len())
+
+	// A render with the old configuration neither uses nor stores partials.
+	require.Equal(t, "1", renderPartial(t, old, "a"))
+	require.Equal(t, 0, cfg.PartialCache.Len())
+
+	require.Equal(t, "1", renderPartial(t, cfg, "a"))
+	require.Equal(t, 1, cfg.PartialCache.Len())
+	_, ok := cfg.PartialCache.get("a", old.partialGeneration)
+	require.False(t, ok)
+	entry, _ := cfg.PartialCache.get("a", cfg.partialGeneration)
+
+	// A configuration made from the reset one uses its partials, as does
+	// one that is given the cache.
+	other := NewConfig()
+	other.TemplateStore = store
+	other.SetPartialCache(cfg.PartialCache)
+
+	for _, c := range []Config{cfg.Clone(), other} {
+		require.Equal(t, "1", renderPartial(t, c, "a"))
+		cached, _ := cfg.PartialCache.get("a", c.partialGeneration)
+		require.Same(t, entry.root, cached.root)
+	}
+}
+
+func TestPartialCache_maxEntries(t *testing.T) {
+	store := newCountingStore(map[string]string{"a": "a", "b": "b", "c": "c"})
+	cfg := NewConfig()
+	cfg.TemplateStore = store
+	cfg.PartialCache = NewPartialCache(2)
+
+	renderPartial(t, cfg, "a")
+	renderPartial(t, cfg, "b")
+	renderPartial(t, cfg, "a")
+	renderPartial(t, cfg, "c")
+	require.Equal(t, 2, cfg.PartialCache.Len())
+
+	_, ok := cfg.PartialCache.get("b", 0)
+	require.False(t, ok, "least recently used entry is evicted")
+	_, ok = cfg.PartialCache.get("a", 0)
+	require.True(t, ok)
+	_, ok = cfg.PartialCache.get("c", 0)
+	require.True(t, ok)
+}
+
+func TestPartialCache_concurrent(t *testing.T) {
+	templates := map[string]string{}
+	for i := range 8 {
+		templates[fmt.Sprint(i)] = fmt.Sprintf("{{ %d }}", i)
+	}
+
+	cfg := NewConfig()
+	cfg.TemplateStore = newCountingStore(templates)
+	cfg.PartialCache = NewPartialCache(4)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				name := fmt.Sprint((g + i) % 8)
+				require.Equal(t, name, renderPartial(t, cfg, name))
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, cfg.PartialCache.Len(), 4)
+}
+
+func TestConfig_compiledPartial(t *testing.T) {
+	cfg := NewConfig()
+	store := newCountingStore(map[string]string{"b": "store"})
+	cfg.TemplateStore = store
+
+	root, err := cfg.Compile("compiled", parser.SourceLoc{})
+	require.NoError(t, err)
+
+	cfg.Compiled = map[string]Node{"a": root, "b": root}
+
+	// the store is read once, to find that it doesn't have the template
+	require.Equal(t, "compiled", renderPartial(t, cfg, "a"))
+	require.Equal(t, "compiled", renderPartial(t, cfg, "a"))
+	require.Equal(t, 1, store.readCount("a"))
+	require.Equal(t, "store", renderPartial(t, cfg, "b"))
+
+	store.set("a", "store")
+	require.Equal(t, "compiled", renderPartial(t, cfg, "a"))
+	cfg.ResetPartialCache()
+	require.Equal(t, "store", renderPartial(t, cfg, "a"))
+
+	// a TemplateVersioner reports whether it has the template without
+	// reading it
+	versioned := newCountingStore(map[string]string{"b": "store"})
+	cfg.TemplateStore = versionedStore{versioned}
+	require.Equal(t, "compiled", renderPartial(t, cfg, "a"))
+	require.Equal(t, "compiled", renderPartial(t, cfg, "a"))
+	require.Equal(t, 0, versioned.readCount("a"))
+	require.Equal(t, "store", renderPartial(t, cfg, "b"))
+}
+
+func TestFileTemplateStore_TemplateVersion(t *testing.T) {
+	store := &FileTemplateStore{Root: "testdata"}
+
+	version, err := store.TemplateVersion("render_file.txt")
+	require.NoError(t, err)
+	require.NotEmpty(t, version)
+
+	_, err = store.TemplateVersion("missing.txt")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = store.TemplateVersion("../partial_cache.go")
+	require.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package render
+
+import (
+	"context"
+	"reflect"
+)
+
+type registersKey struct{}
+
+// WithRegisters returns a copy of ctx that carries registers for a render.
+// Registers hold the host application's state for a single render. Tags read
+// and write them with Context.Registers, and filters that take a
+// context.Context read them with RegistersFromContext. Unlike bindings,
+// they aren't visible to templates.
+func WithRegisters(ctx context.Context, registers map[string]any) context.Context {
+	return context.WithValue(ctx, registersKey{}, registers)
+}
+
+// RegistersFromContext returns the registers that ctx carries, or nil.
+func RegistersFromContext(ctx context.Context) map[string]any {
+	registers, _ := ctx.Value(registersKey{}).(map[string]any)
+	return registers
+}
+
+// registers returns the render's registers, creating them if the render
+// wasn't given any.
+func (c *nodeContext) registers() map[string]any {
+	if c.state.registers == nil {
+		c.state.registers = map[string]any{}
+	}
+
+	return c.state.registers
+}
+
+var (
+	contextType       = reflect.TypeOf((*context.Context)(nil)).Elem()
+	renderContextType = reflect.TypeOf((*Context)(nil)).Elem()
+)
+
+// FilterArgument supplies the render's context.Context, or its Context, to
+// filters whose first parameter has that type. It implements
+// expressions.FilterArguments.
+func (c *nodeContext) FilterArgument(t reflect.Type) (any, bool) {
+	switch t {
+	case contextType:
+		// A tag may have created registers that the context doesn't carry.
+		if c.state.registers != nil && RegistersFromContext(c.context) == nil {
+			return WithRegisters(c.context, c.state.registers), true
+		}
+
+		return c.context, true
+	case renderContextType:
+		return rendererContext{ctx: c}, true
+	default:
+		return nil, false
+	}
+}
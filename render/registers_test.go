@@ -0,0 +1,74 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/parser"
+)
+
+func TestRegisters(t *testing.T) {
+	cfg := NewConfig()
+	cfg.AddFilter("register", func(ctx context.Context, name string) any {
+		return RegistersFromContext(ctx)[name]
+	})
+	cfg.AddTag("count", func(string) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			registers := ctx.(RegistersProvider).Registers()
+			n, _ := registers["count"].(int)
+			registers["count"] = n + 1
+			_, err := fmt.Fprint(w, n+1)
+			return err
+		}, nil
+	})
+	cfg.AddTag("partial", func(string) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			return ctx.(rendererContext).RenderFileIsolatedTo(w, "partial", nil)
+		}, nil
+	})
+	cfg.Cache["partial"] = []byte(`{% count %}`)
+
+	render := func(ctx context.Context, source string) string {
+		t.Helper()
+
+		root, err := cfg.Compile(source, parser.SourceLoc{})
+		require.NoError(t, err)
+
+		buf := new(bytes.Buffer)
+		err = RenderContext(ctx, root, buf, map[string]any{}, cfg)
+		require.NoError(t, err)
+
+		return buf.String()
+	}
+
+	registers := map[string]any{"shop": "example", "count": 10}
+	ctx := WithRegisters(context.Background(), registers)
+	require.Equal(t, "example:", render(ctx, `{{ "shop" | register }}:{{ shop }}`))
+	require.Equal(t, "11,12,12", render(ctx, `{% count %},{% partial %},{{ "count" | register }}`))
+	require.Equal(t, 12, registers["count"])
+
+	// A render without registers has its own.
+	require.Equal(t, "1,1", render(context.Background(), `{% count %},{{ "count" | register }}`))
+}
+
+func TestFilterArgument_renderContext(t *testing.T) {
+	cfg := NewConfig()
+	cfg.AddFilter("asset_url", func(ctx Context, path string) string {
+		shop := ctx.Get("shop").(map[string]any)
+		return fmt.Sprintf("https://%s/%s?v=%v", shop["domain"], path, ctx.(RegistersProvider).Registers()["version"])
+	})
+
+	root, err := cfg.Compile(`{{ "a.css" | asset_url }}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	ctx := WithRegisters(context.Background(), map[string]any{"version": 2})
+	buf := new(bytes.Buffer)
+	err = RenderContext(ctx, root, buf, map[string]any{"shop": map[string]any{"domain": "example.com"}}, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/a.css?v=2", buf.String())
+}
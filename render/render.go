@@ -2,12 +2,14 @@
 package render
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"reflect"
 	"strconv"
 	"time"
 
+	"github.com/osteele/liquid/expressions"
 	"github.com/osteele/liquid/parser"
 
 	"github.com/osteele/liquid/values"
@@ -15,11 +17,22 @@ import (
 
 // Render renders the render tree.
 func Render(node Node, w io.Writer, vars map[string]any, c Config) Error {
-	return renderWithContext(node, w, newNodeContext(vars, c))
+	return RenderContext(context.Background(), node, w, vars, c)
+}
+
+// RenderContext renders the render tree. Rendering stops, and returns the
+// context's error, when ctx is canceled or its deadline passes.
+func RenderContext(ctx context.Context, node Node, w io.Writer, vars map[string]any, c Config) Error {
+	_, err := renderScope(ctx, node, w, vars, nil, c)
+	return err
 }
 
 func renderWithContext(node Node, w io.Writer, ctx *nodeContext) Error {
 	tw := trimWriter{w: w}
+	// A partial continues the HTML context of the template that renders it.
+	if parent, ok := w.(*trimWriter); ok && ctx.config.contextualEscaping() {
+		tw.html = parent.htmlContext()
+	}
 
 	err := node.render(&tw, ctx)
 	if err != nil {
@@ -30,6 +43,10 @@ func renderWithContext(node Node, w io.Writer, ctx *nodeContext) Error {
 		return wrapRenderError(err, invalidLoc)
 	}
 
+	if ctx.layout != nil {
+		return ctx.renderLayout(w)
+	}
+
 	return nil
 }
 
@@ -41,8 +58,15 @@ func (c *nodeContext) RenderSequence(w io.Writer, seq []Node) Error {
 	}
 
 	for _, n := range seq {
-		err := n.render(tw, c)
-		if err != nil {
+		if c.loopSignal != NoLoopSignal {
+			break
+		}
+
+		if err := c.canceled(); err != nil {
+			return wrapRenderError(err, invalidLoc)
+		}
+
+		if err := n.render(tw, c); err != nil {
 			return err
 		}
 	}
@@ -68,11 +92,15 @@ func (n *BlockNode) render(w *trimWriter, ctx *nodeContext) Error {
 
 	err := renderer(w, rendererContext{ctx, nil, n})
 
-	return wrapRenderError(err, n)
+	return ctx.recoverError(w, wrapRenderError(err, n))
 }
 
 func (n *RawNode) render(w *trimWriter, ctx *nodeContext) Error {
 	for _, s := range n.slices {
+		if ctx.config.contextualEscaping() {
+			w.htmlContext().observe(s)
+		}
+
 		_, err := io.WriteString(w, s)
 		if err != nil {
 			return wrapRenderError(err, n)
@@ -85,34 +113,56 @@ func (n *RawNode) render(w *trimWriter, ctx *nodeContext) Error {
 func (n *ObjectNode) render(w *trimWriter, ctx *nodeContext) Error {
 	value, err := ctx.Evaluate(n.expr)
 	if err != nil {
+		return ctx.recoverError(w, wrapRenderError(err, n))
+	}
+
+	if err := ctx.writeValue(w, value); err != nil {
 		return wrapRenderError(err, n)
 	}
 
+	return nil
+}
+
+// writeValue writes the value of an object, escaping it if the configuration
+// has an escape replacer and the value isn't safe.
+func (c *nodeContext) writeValue(w io.Writer, value any) error {
 	if sv, isSafe := value.(values.SafeValue); isSafe {
-		err = writeObject(w, sv.Value)
-	} else {
-		var fw io.Writer
-		if replacer := ctx.config.escapeReplacer; replacer != nil {
-			fw = &replacerWriter{
-				replacer: replacer,
-				w:        w,
-			}
-		} else {
-			fw = w
+		return writeObject(w, sv.Value)
+	}
+
+	if c.config.contextualEscaping() {
+		if tw, ok := w.(*trimWriter); ok {
+			return tw.htmlContext().writeValue(w, value)
 		}
-		err = writeObject(fw, value)
+
+		return (&htmlContext{}).writeValue(w, value)
 	}
-	if err != nil {
-		return wrapRenderError(err, n)
+
+	if replacer := c.config.escapeReplacer; replacer != nil {
+		w = &replacerWriter{
+			replacer: replacer,
+			w:        w,
+		}
 	}
 
-	return nil
+	return writeObject(w, value)
 }
 
 func (n *SeqNode) render(w *trimWriter, ctx *nodeContext) Error {
+	if n.macros != nil {
+		ctx.macros = n.macros
+	}
+
 	for _, c := range n.Children {
-		err := c.render(w, ctx)
-		if err != nil {
+		if ctx.loopSignal != NoLoopSignal {
+			break
+		}
+
+		if err := ctx.canceled(); err != nil {
+			return wrapRenderError(err, invalidLoc)
+		}
+
+		if err := c.render(w, ctx); err != nil {
 			return err
 		}
 	}
@@ -122,10 +172,14 @@ func (n *SeqNode) render(w *trimWriter, ctx *nodeContext) Error {
 
 func (n *TagNode) render(w *trimWriter, ctx *nodeContext) Error {
 	err := wrapRenderError(n.renderer(w, rendererContext{ctx, n, nil}), n)
-	return err
+	return ctx.recoverError(w, err)
 }
 
-func (n *TextNode) render(w *trimWriter, _ *nodeContext) Error {
+func (n *TextNode) render(w *trimWriter, ctx *nodeContext) Error {
+	if ctx.config.contextualEscaping() {
+		w.htmlContext().observe(n.Source)
+	}
+
 	_, err := io.WriteString(w, n.Source)
 	return wrapRenderError(err, n)
 }
@@ -209,3 +263,21 @@ func (h *replacerWriter) Write(p []byte) (n int, err error) {
 func (h *replacerWriter) WriteString(s string) (n int, err error) {
 	return h.replacer.WriteString(h.w, s)
 }
+
+// limitWriter fails a render that writes more than Limits.MaxOutputBytes.
+type limitWriter struct {
+	w         io.Writer
+	remaining int
+	limit     int
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if len(p) > lw.remaining {
+		return 0, expressions.LimitError{Limit: "output size", Max: lw.limit}
+	}
+
+	n, err := lw.w.Write(p)
+	lw.remaining -= n
+
+	return n, err
+}
@@ -142,6 +142,20 @@ func TestRenderErrors(t *testing.T) {
 	}
 }
 
+func TestRenderErrors_lax(t *testing.T) {
+	cfg := NewConfig()
+	cfg.ErrorMode = parser.LaxMode
+	addRenderTestTags(cfg)
+
+	root, err := cfg.Compile("x{% errblock %}{% enderrblock %}\n{{ 1 | undefined_filter }}y", parser.SourceLoc{LineNo: 1})
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	err = Render(root, buf, renderTestBindings, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "xLiquid error (line 1): errblock error\nLiquid error (line 2): undefined filter \"undefined_filter\"y", buf.String())
+}
+
 func TestRenderStrictVariables(t *testing.T) {
 	cfg := NewConfig()
 	cfg.StrictVariables = true
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/osteele/liquid/values"
+)
+
+// A Result is the state that a render leaves behind, apart from its output.
+type Result struct {
+	// Regions are the named regions of content that the template's
+	// content_for tags filled. It is nil if there are none.
+	Regions map[string]string
+	// Assigns are the variables of the template's top-level scope that the
+	// assign and capture tags, or other tags that call Assign, set, with
+	// their values at the end of the render. A variable whose final value
+	// equals its value in the render's variables isn't included. It is nil
+	// if there are none.
+	Assigns map[string]any
+}
+
+// RenderWithResult is the same as RenderContext, and also returns the state
+// that the render leaves behind.
+func RenderWithResult(ctx context.Context, node Node, w io.Writer, vars map[string]any, c Config) (Result, Error) {
+	nc, err := renderScope(ctx, node, w, vars, nil, c)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return nc.result(), nil
+}
+
+// renderScope renders the node with the variables vars, and then the
+// properties of scope, if it isn't nil. It returns the context, for the
+// render's Result.
+func renderScope(ctx context.Context, node Node, w io.Writer, vars map[string]any, scope values.Value, c Config) (*nodeContext, Error) {
+	if limit := c.Limits.MaxOutputBytes; limit > 0 {
+		w = &limitWriter{w: w, remaining: limit, limit: limit}
+	}
+
+	nc := newNodeContext(ctx, vars, c)
+	nc.setScope(scope)
+
+	if err := renderWithContext(node, w, nc); err != nil {
+		return nil, err
+	}
+
+	if err := nc.strayLoopSignal(); err != nil {
+		return nil, err
+	}
+
+	return nc, nil
+}
+
+// result returns the state that a top-level render leaves behind.
+func (c *nodeContext) result() Result {
+	var result Result
+
+	for name := range c.assigned {
+		value := c.bindings[name]
+		if input, ok := c.inputs[name]; ok && values.Equal(input, value) {
+			continue
+		}
+
+		if result.Assigns == nil {
+			result.Assigns = map[string]any{}
+		}
+
+		result.Assigns[name] = value
+	}
+
+	if regions := c.state.regions; len(regions) > 0 {
+		result.Regions = make(map[string]string, len(regions))
+		for name, b := range regions {
+			result.Regions[name] = b.String()
+		}
+	}
+
+	return result
+}
+
+// Assign sets a variable, as Set does, and records it as one of the render's
+// Assigns; see Result. It is used as an optional extension to Context by the
+// assign and capture tags, and can be used by custom tags that define
+// variables.
+func (c rendererContext) Assign(name string, value any) {
+	c.ctx.bindings[name] = value
+
+	if c.ctx.assigned == nil {
+		c.ctx.assigned = map[string]bool{}
+	}
+
+	c.ctx.assigned[name] = true
+}
+
+// AppendRegion appends content to a named region. Regions are shared by a
+// render and its partials. It is used as an optional internal extension to
+// Context by the content_for tag.
+func (c rendererContext) AppendRegion(name, content string) {
+	if c.ctx.state.regions == nil {
+		c.ctx.state.regions = map[string]*strings.Builder{}
+	}
+
+	b, ok := c.ctx.state.regions[name]
+	if !ok {
+		b = new(strings.Builder)
+		c.ctx.state.regions[name] = b
+	}
+
+	b.WriteString(content)
+}
+
+// Region returns the content of a named region. It is used as an optional
+// internal extension to Context by the yield tag.
+func (c rendererContext) Region(name string) string {
+	if b, ok := c.ctx.state.regions[name]; ok {
+		return b.String()
+	}
+
+	return ""
+}
@@ -0,0 +1,65 @@
+package render
+
+import (
+	"context"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/parser"
+)
+
+type assigner interface {
+	Assign(string, any)
+}
+
+func TestRenderWithResult_assigns(t *testing.T) {
+	cfg := NewConfig()
+	cfg.AddTag("assign", func(name string) (func(io.Writer, Context) error, error) {
+		return func(_ io.Writer, ctx Context) error {
+			ctx.(assigner).Assign(name, ctx.Get("value"))
+			return nil
+		}, nil
+	})
+	cfg.AddTag("set", func(name string) (func(io.Writer, Context) error, error) {
+		return func(_ io.Writer, ctx Context) error {
+			ctx.Set(name, "set")
+			return nil
+		}, nil
+	})
+
+	nan := math.NaN()
+	tests := []struct {
+		in       string
+		vars     map[string]any
+		expected map[string]any
+	}{
+		{`{% set x %}`, map[string]any{}, nil},
+		{`{% assign x %}{% set y %}`, map[string]any{"value": 1}, map[string]any{"x": 1}},
+		{`{% assign value %}`, map[string]any{"value": "same"}, nil},
+		{`{% assign x %}`, map[string]any{"value": 2, "x": 1}, map[string]any{"x": 2}},
+		{`{% assign x %}`, map[string]any{"value": 1.0, "x": 1}, nil},
+		{`{% assign x %}{% set x %}`, map[string]any{"value": 1}, map[string]any{"x": "set"}},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, parser.SourceLoc{})
+			require.NoError(t, err)
+
+			result, err := RenderWithResult(context.Background(), root, io.Discard, test.vars, cfg)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, result.Assigns)
+		})
+	}
+
+	// A variable that isn't assigned isn't reported, even if it isn't equal
+	// to itself.
+	root, err := cfg.Compile(`{% set y %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	result, err := RenderWithResult(context.Background(), root, io.Discard, map[string]any{"value": nan}, cfg)
+	require.NoError(t, err)
+	require.Nil(t, result.Assigns)
+}
@@ -0,0 +1,89 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/values"
+)
+
+// RenderValue is the same as RenderWithResult, except that the variables are
+// the properties of scope: the keys of a map, or the fields and methods of a
+// struct or struct pointer, or those of the value of a drop. Assignments go
+// into a scope that overlays it, so scope isn't modified.
+func RenderValue(ctx context.Context, node Node, w io.Writer, scope any, c Config) (Result, Error) {
+	sv, err := scopeValue(scope)
+	if err != nil {
+		return Result{}, wrapRenderError(err, invalidLoc)
+	}
+
+	nc, rerr := renderScope(ctx, node, w, map[string]any{}, sv, c)
+	if rerr != nil {
+		return Result{}, rerr
+	}
+
+	return nc.result(), nil
+}
+
+func scopeValue(scope any) (values.Value, error) {
+	scope = values.ToLiquid(scope)
+
+	rt := reflect.TypeOf(scope)
+	if rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	if rt == nil || rt.Kind() != reflect.Struct && rt.Kind() != reflect.Map {
+		return nil, fmt.Errorf("scope must be a map, a struct, or a drop; got %T", scope)
+	}
+
+	return values.ValueOf(scope), nil
+}
+
+type scopeSetter interface {
+	SetScope(values.Value)
+}
+
+// setScope sets the value whose properties are the variables that the
+// bindings don't define.
+func (c *nodeContext) setScope(scope values.Value) {
+	c.scope = scope
+	if scope != nil {
+		c.exprCtx.(scopeSetter).SetScope(scope)
+	}
+}
+
+// get returns the value of a variable, from the bindings, the scope, or the
+// counters.
+func (c *nodeContext) get(name string) any {
+	value, ok := c.bindings[name]
+	if !ok && c.scope != nil {
+		value, ok = values.Property(c.scope, name)
+	}
+
+	if !ok {
+		if n, isCounter := c.Counter(name); isCounter {
+			return n
+		}
+	}
+
+	return value
+}
+
+// newExpressionContext returns an expression context for the bindings and
+// scope, for expressions that are evaluated outside of the render.
+func (c *nodeContext) newExpressionContext() expressions.Context {
+	ctx := expressions.NewContext(c.bindings, c.config.Config.Config)
+	ctx.(filterArgumentSetter).SetFilterArguments(c)
+	ctx.(callerSetter).SetCaller(c)
+	ctx.(countersSetter).SetCounters(c)
+
+	if c.scope != nil {
+		ctx.(scopeSetter).SetScope(c.scope)
+	}
+
+	return ctx
+}
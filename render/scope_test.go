@@ -0,0 +1,100 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/parser"
+)
+
+type scopeModel struct {
+	Title  string
+	Author struct{ Name string }
+	Tags   []string `liquid:"tags"`
+}
+
+func (m scopeModel) Upper() string { return "UPPER" }
+
+type scopeDrop struct{}
+
+func (scopeDrop) ToLiquid() any { return map[string]any{"title": "drop"} }
+
+func TestRenderValue(t *testing.T) {
+	model := scopeModel{Title: "Page", Tags: []string{"a", "b"}}
+	model.Author.Name = "Ann"
+
+	cfg := NewConfig()
+	cfg.AddTag("get", func(name string) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			_, err := fmt.Fprint(w, ctx.Get(name))
+			return err
+		}, nil
+	})
+	cfg.AddTag("set", func(name string) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			ctx.(assigner).Assign(name, "set")
+			return nil
+		}, nil
+	})
+	cfg.AddTag("include", func(string) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			return ctx.(rendererContext).RenderFileTo(w, "partial", nil)
+		}, nil
+	})
+	cfg.AddTag("isolated", func(string) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			return ctx.(rendererContext).RenderFileIsolatedTo(w, "partial", nil)
+		}, nil
+	})
+	cfg.Cache["partial"] = []byte(`[{{ Title }}]`)
+
+	tests := []struct {
+		in       string
+		scope    any
+		expected string
+	}{
+		{`{{ Title }} by {{ Author.Name }}: {{ tags[1] }}`, model, `Page by Ann: b`},
+		{`{{ Title }} {{ Upper }} {{ missing }}`, &model, `Page UPPER `},
+		{`{% set Title %}{{ Title }} {% get Title %} {% get Upper %}`, model, `set set UPPER`},
+		{`{% include %}{% isolated %}`, model, `[Page][]`},
+		{`{{ title }}`, map[string]any{"title": "map"}, `map`},
+		{`{{ title }}`, scopeDrop{}, `drop`},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, parser.SourceLoc{})
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			_, err = RenderValue(context.Background(), root, buf, test.scope, cfg)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+
+	// Assignments overlay the scope.
+	root, err := cfg.Compile(`{% set Title %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	result, err := RenderValue(context.Background(), root, io.Discard, &model, cfg)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"Title": "set"}, result.Assigns)
+	require.Equal(t, "Page", model.Title)
+
+	root, err = cfg.Compile(`{{ x }}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	_, err = RenderValue(context.Background(), root, io.Discard, "string", cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "scope must be a map, a struct, or a drop")
+
+	cfg.StrictVariables = true
+	_, err = RenderValue(context.Background(), root, io.Discard, model, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "undefined variable")
+}
@@ -0,0 +1,59 @@
+package render
+
+import "github.com/osteele/liquid/parser"
+
+// A LoopSignal tells the enclosing loop to stop rendering its body, and then
+// to break or continue. The break and continue tags send it with SignalLoop.
+//
+// A signal isn't an error. Once it is sent, the sequences that enclose the
+// tag stop rendering their remaining nodes, and return normally, so that
+// the if, case, and capture tags, and custom block tags, return as they
+// would at the end of their bodies. The loop takes the signal with
+// TakeLoopSignal.
+//
+// A signal in a template that include renders reaches the includer's loop.
+// A signal that no loop takes, at the top level of a template, or of a
+// template that render renders, or of the body of a macro, is an error.
+type LoopSignal int
+
+const (
+	// NoLoopSignal is the zero LoopSignal, for a body that rendered to its end.
+	NoLoopSignal LoopSignal = iota
+	// LoopBreak ends the loop.
+	LoopBreak
+	// LoopContinue starts the loop's next iteration.
+	LoopContinue
+)
+
+func (s LoopSignal) String() string {
+	switch s {
+	case LoopBreak:
+		return "break"
+	case LoopContinue:
+		return "continue"
+	default:
+		return "none"
+	}
+}
+
+// signalLoop records a loop signal that the tag at loc sent.
+func (c *nodeContext) signalLoop(s LoopSignal, loc parser.Locatable) {
+	c.loopSignal, c.loopSignalLoc = s, loc
+}
+
+// takeLoopSignal returns and clears the pending loop signal.
+func (c *nodeContext) takeLoopSignal() LoopSignal {
+	s := c.loopSignal
+	c.loopSignal, c.loopSignalLoc = NoLoopSignal, nil
+
+	return s
+}
+
+// strayLoopSignal returns an error for a loop signal that no loop took.
+func (c *nodeContext) strayLoopSignal() Error {
+	if c.loopSignal == NoLoopSignal {
+		return nil
+	}
+
+	return renderErrorf(c.loopSignalLoc, "%s outside a loop", c.loopSignal)
+}
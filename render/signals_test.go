@@ -0,0 +1,109 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/parser"
+)
+
+type loopSignaler interface {
+	SignalLoop(LoopSignal)
+	TakeLoopSignal() LoopSignal
+}
+
+func TestLoopSignal_customTags(t *testing.T) {
+	cfg := NewConfig()
+	cfg.AddTag("stop", func(string) (func(io.Writer, Context) error, error) {
+		return func(_ io.Writer, ctx Context) error {
+			ctx.(loopSignaler).SignalLoop(LoopBreak)
+			return nil
+		}, nil
+	})
+	cfg.AddTag("skip", func(string) (func(io.Writer, Context) error, error) {
+		return func(_ io.Writer, ctx Context) error {
+			ctx.(loopSignaler).SignalLoop(LoopContinue)
+			return nil
+		}, nil
+	})
+	// wrap renders its body between brackets, so a signal in the body ends
+	// the body but not the closing bracket.
+	cfg.AddBlock("wrap").Compiler(func(BlockNode) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			if _, err := io.WriteString(w, "["); err != nil {
+				return err
+			}
+
+			if err := ctx.RenderChildren(w); err != nil {
+				return err
+			}
+
+			_, err := io.WriteString(w, "]")
+
+			return err
+		}, nil
+	})
+	// thrice renders its body three times, as a loop.
+	cfg.AddBlock("thrice").Compiler(func(BlockNode) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			for range 3 {
+				if err := ctx.RenderChildren(w); err != nil {
+					return err
+				}
+
+				if ctx.(loopSignaler).TakeLoopSignal() == LoopBreak {
+					break
+				}
+			}
+
+			return nil
+		}, nil
+	})
+
+	// twice renders its body twice, separated by a bar. It isn't a loop, so
+	// after a signal in the body, the second rendering is empty.
+	cfg.AddBlock("twice").Compiler(func(BlockNode) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			a, err := ctx.InnerString()
+			if err != nil {
+				return err
+			}
+
+			b, err := ctx.InnerString()
+			if err != nil {
+				return err
+			}
+
+			_, err = io.WriteString(w, a+"|"+b)
+
+			return err
+		}, nil
+	})
+
+	tests := []struct{ in, expected string }{
+		{`{% thrice %}a{% wrap %}b{% skip %}c{% endwrap %}d{% endthrice %}`, `a[b]a[b]a[b]`},
+		{`{% thrice %}a{% wrap %}b{% stop %}c{% endwrap %}d{% endthrice %}e`, `a[b]e`},
+		{`{% thrice %}{% thrice %}a{% stop %}{% endthrice %}b{% endthrice %}`, `ababab`},
+		{`{% thrice %}{% twice %}a{% stop %}b{% endtwice %};{% endthrice %}`, `a|`},
+		{`{% thrice %}{% twice %}a{% skip %}b{% endtwice %};{% endthrice %}`, `a|a|a|`},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, parser.SourceLoc{})
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			require.NoError(t, Render(root, buf, map[string]any{}, cfg))
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+
+	root, err := cfg.Compile("{% wrap %}\n{% skip %}{% endwrap %}", parser.SourceLoc{LineNo: 1})
+	require.NoError(t, err)
+
+	err = Render(root, io.Discard, map[string]any{}, cfg)
+	require.EqualError(t, err, "Liquid error (line 2): continue outside a loop in {% skip %}")
+}
@@ -0,0 +1,55 @@
+package render
+
+import (
+	"maps"
+	"sync"
+)
+
+// A SourceCache holds template sources by path, like Config.Cache. Unlike
+// Cache, it is safe for concurrent use, and a configuration shares it with
+// its clones, so that sources can be added to it while templates render,
+// without copying the configuration. Assign one to Config.Sources to use it.
+type SourceCache struct {
+	mu      sync.RWMutex
+	sources map[string][]byte
+}
+
+// NewSourceCache creates an empty SourceCache.
+func NewSourceCache() *SourceCache {
+	return &SourceCache{sources: map[string][]byte{}}
+}
+
+// Get returns the source with the given path. A nil *SourceCache is empty.
+func (sc *SourceCache) Get(path string) ([]byte, bool) {
+	if sc == nil {
+		return nil, false
+	}
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	source, ok := sc.sources[path]
+
+	return source, ok
+}
+
+// Set adds or replaces the source with the given path.
+func (sc *SourceCache) Set(path string, source []byte) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.sources[path] = source
+}
+
+// Clone returns a new SourceCache with the same sources. The clone of a nil
+// *SourceCache is nil.
+func (sc *SourceCache) Clone() *SourceCache {
+	if sc == nil {
+		return nil
+	}
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	return &SourceCache{sources: maps.Clone(sc.sources)}
+}
@@ -0,0 +1,42 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceCache(t *testing.T) {
+	var nilCache *SourceCache
+	_, ok := nilCache.Get("a")
+	require.False(t, ok)
+	require.Nil(t, nilCache.Clone())
+
+	sc := NewSourceCache()
+	sc.Set("a", []byte("a"))
+	clone := sc.Clone()
+	clone.Set("b", []byte("b"))
+
+	source, ok := sc.Get("a")
+	require.True(t, ok)
+	require.Equal(t, "a", string(source))
+	_, ok = sc.Get("b")
+	require.False(t, ok)
+
+	// The include tag reads Sources after Cache, and a clone of the
+	// configuration shares them.
+	cfg := NewConfig()
+	cfg.Sources = sc
+	cfg.Cache["a"] = []byte("cached")
+	clone2 := cfg.Clone()
+	sc.Set("c", []byte("c"))
+
+	for name, expected := range map[string]string{"a": "cached", "c": "c"} {
+		source, err := clone2.readTemplate(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(source))
+	}
+
+	_, err := clone2.readTemplate("missing")
+	require.Error(t, err)
+}
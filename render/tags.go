@@ -8,18 +8,51 @@ import (
 // TODO instead of using the bare function definition, use a structure that defines how to parse
 type TagCompiler func(expr string) (func(io.Writer, Context) error, error)
 
+// ConfigTagCompiler is a TagCompiler that also receives the configuration
+// that compiles the tag.
+type ConfigTagCompiler func(c *Config, expr string) (func(io.Writer, Context) error, error)
+
 // AddTag creates a tag definition.
 func (c *Config) AddTag(name string, td TagCompiler) {
+	c.tags[name] = func(_ *Config, expr string) (func(io.Writer, Context) error, error) {
+		return td(expr)
+	}
+}
+
+// AddConfigTag creates a tag definition whose compiler depends on the
+// configuration. A configuration can be cloned, so use this instead of a
+// TagCompiler that refers to the Config that it was added to.
+func (c *Config) AddConfigTag(name string, td ConfigTagCompiler) {
 	c.tags[name] = td
 }
 
 // FindTagDefinition looks up a tag definition.
 func (c *Config) FindTagDefinition(name string) (TagCompiler, bool) {
 	td, ok := c.tags[name]
-	return td, ok
+	if !ok {
+		return nil, false
+	}
+
+	return func(expr string) (func(io.Writer, Context) error, error) {
+		return td(c, expr)
+	}, true
+}
+
+// AddStatementsTag defines a tag, such as {% liquid %}, whose argument is a
+// sequence of tag statements without delimiters, one per line. The parser
+// replaces the tag by the statements, so it has no compiler.
+func (c *Config) AddStatementsTag(name string) {
+	c.statementsTags[name] = true
+}
+
+// IsStatementsTag is part of the parser.StatementsGrammar interface.
+func (g grammar) IsStatementsTag(name string) bool {
+	return g.statementsTags[name]
 }
 
 // UnregisterTag removes a tag definition.
 func (c *Config) UnregisterTag(name string) {
 	delete(c.tags, name)
+	delete(c.tagAnalyzers, name)
+	delete(c.statementsTags, name)
 }
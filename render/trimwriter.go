@@ -13,6 +13,16 @@ type trimWriter struct {
 	w    io.Writer
 	buf  bytes.Buffer
 	trim bool
+	html *htmlContext // the HTML context of the output, for contextual autoescaping
+}
+
+// htmlContext returns the HTML context of the writer's output.
+func (tw *trimWriter) htmlContext() *htmlContext {
+	if tw.html == nil {
+		tw.html = &htmlContext{}
+	}
+
+	return tw.html
 }
 
 // Write writes b to the current buffer. If the trim flag is set,
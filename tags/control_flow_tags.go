@@ -41,9 +41,9 @@ func (c elseCase) body() *render.BlockNode { return c.b }
 
 func (c elseCase) test(any, render.Context) (bool, error) { return true, nil }
 
-func caseTagCompiler(node render.BlockNode) (func(io.Writer, render.Context) error, error) {
+func caseTagCompiler(cfg *render.Config, node render.BlockNode) (func(io.Writer, render.Context) error, error) {
 	// TODO syntax error on non-empty node.Body
-	expr, err := e.Parse(node.Args)
+	expr, err := cfg.ParseExpression(node.Args)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +53,7 @@ func caseTagCompiler(node render.BlockNode) (func(io.Writer, render.Context) err
 	for _, clause := range node.Clauses {
 		switch clause.Name {
 		case "when":
-			stmt, err := e.ParseStatement(e.WhenStatementSelector, clause.Args)
+			stmt, err := cfg.ParseStatement(e.WhenStatementSelector, clause.Args)
 			if err != nil {
 				return nil, err
 			}
@@ -85,14 +85,33 @@ func caseTagCompiler(node render.BlockNode) (func(io.Writer, render.Context) err
 	}, nil
 }
 
-func ifTagCompiler(polarity bool) func(render.BlockNode) (func(io.Writer, render.Context) error, error) { //nolint: gocyclo
-	return func(node render.BlockNode) (func(io.Writer, render.Context) error, error) {
+func caseTagAnalyzer(node render.BlockNode) render.NodeAnalysis {
+	var analysis render.NodeAnalysis
+	if expr, err := e.Parse(node.Args); err == nil {
+		analysis.Arguments = append(analysis.Arguments, expr)
+	}
+
+	for _, clause := range node.Clauses {
+		if clause.Name != "when" {
+			continue
+		}
+
+		if stmt, err := e.ParseStatement(e.WhenStatementSelector, clause.Args); err == nil {
+			analysis.Arguments = append(analysis.Arguments, stmt.Exprs...)
+		}
+	}
+
+	return analysis
+}
+
+func ifTagCompiler(polarity bool) render.ConfigBlockCompiler { //nolint: gocyclo
+	return func(cfg *render.Config, node render.BlockNode) (func(io.Writer, render.Context) error, error) {
 		type branchRec struct {
 			test e.Expression
 			body *render.BlockNode
 		}
 
-		expr, err := e.Parse(node.Args)
+		expr, err := cfg.ParseExpression(node.Args)
 		if err != nil {
 			return nil, err
 		}
@@ -111,7 +130,7 @@ func ifTagCompiler(polarity bool) func(render.BlockNode) (func(io.Writer, render
 			case "else":
 			// TODO syntax error if this isn't the last branch
 			case "elsif":
-				t, err := e.Parse(c.Args)
+				t, err := cfg.ParseExpression(c.Args)
 				if err != nil {
 					return nil, err
 				}
@@ -138,3 +157,23 @@ func ifTagCompiler(polarity bool) func(render.BlockNode) (func(io.Writer, render
 		}, nil
 	}
 }
+
+// ifTagAnalyzer analyzes if and unless blocks.
+func ifTagAnalyzer(node render.BlockNode) render.NodeAnalysis {
+	var analysis render.NodeAnalysis
+	if expr, err := e.Parse(node.Args); err == nil {
+		analysis.Arguments = append(analysis.Arguments, expr)
+	}
+
+	for _, clause := range node.Clauses {
+		if clause.Name != "elsif" {
+			continue
+		}
+
+		if expr, err := e.Parse(clause.Args); err == nil {
+			analysis.Arguments = append(analysis.Arguments, expr)
+		}
+	}
+
+	return analysis
+}
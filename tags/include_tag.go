@@ -1,6 +1,7 @@
 package tags
 
 import (
+	"errors"
 	"io"
 
 	"github.com/osteele/liquid/expressions"
@@ -11,8 +12,12 @@ type fileWriter interface {
 	RenderFileTo(io.Writer, string, map[string]any) error
 }
 
-func includeTag(source string) (func(io.Writer, render.Context) error, error) {
-	expr, err := expressions.Parse(source)
+func includeTag(cfg *render.Config, source string) (func(io.Writer, render.Context) error, error) {
+	if cfg.JekyllExtensions {
+		return jekyllIncludeTag(cfg, source, false)
+	}
+
+	expr, err := cfg.ParseExpression(source)
 	if err != nil {
 		return nil, err
 	}
@@ -33,17 +38,53 @@ func includeTag(source string) (func(io.Writer, render.Context) error, error) {
 			return ctx.WrapError(err)
 		}
 
-		if fw, ok := ctx.(fileWriter); ok {
-			return fw.RenderFileTo(w, filename, map[string]any{})
-		}
+		return renderInclude(w, ctx, filename, map[string]any{})
+	}, nil
+}
 
-		s, err := ctx.RenderFile(filename, map[string]any{})
-		if err != nil {
-			return err
-		}
+func includeRelativeTag(cfg *render.Config, source string) (func(io.Writer, render.Context) error, error) {
+	if !cfg.JekyllExtensions {
+		return nil, errors.New("syntax error: include_relative tag requires Jekyll extensions to be enabled")
+	}
 
-		_, err = io.WriteString(w, s)
+	return jekyllIncludeTag(cfg, source, true)
+}
 
+// renderInclude renders a partial with the bindings of the context and b.
+func renderInclude(w io.Writer, ctx render.Context, filename string, b map[string]any) error {
+	if fw, ok := ctx.(fileWriter); ok {
+		return fw.RenderFileTo(w, filename, b)
+	}
+
+	s, err := ctx.RenderFile(filename, b)
+	if err != nil {
 		return err
-	}, nil
+	}
+
+	_, err = io.WriteString(w, s)
+
+	return err
+}
+
+func includeTagAnalyzer(cfg *render.Config, source string) render.NodeAnalysis {
+	if cfg.JekyllExtensions {
+		return jekyllIncludeTagAnalyzer(source)
+	}
+
+	return partialNameAnalyzer(source)
+}
+
+// partialNameAnalyzer analyzes the arguments of a tag whose argument is an
+// expression that evaluates to a partial's name.
+func partialNameAnalyzer(source string) render.NodeAnalysis {
+	expr, err := expressions.Parse(source)
+	if err != nil {
+		return render.NodeAnalysis{}
+	}
+
+	if name, ok := literalString(expr); ok {
+		return render.NodeAnalysis{Partials: []string{name}}
+	}
+
+	return render.NodeAnalysis{Arguments: []expressions.Expression{expr}}
 }
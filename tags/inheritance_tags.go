@@ -0,0 +1,180 @@
+package tags
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/render"
+)
+
+type layoutSetter interface {
+	SetLayout(io.Writer, string) error
+}
+
+type inheritedBlockRenderer interface {
+	RenderInheritedBlock(io.Writer, string) error
+}
+
+type regionStore interface {
+	AppendRegion(name, content string)
+	Region(name string) string
+}
+
+// markupWriter is implemented by render contexts that write rendered text
+// escaped for the HTML context of its position.
+type markupWriter interface {
+	WriteMarkup(w io.Writer, s string) error
+}
+
+// AddInheritanceTags defines the template inheritance tags: layout, its
+// synonym extends, and block; and the content_for and yield tags, which fill
+// and print named regions.
+func AddInheritanceTags(c *render.Config) {
+	c.AddConfigTag("layout", layoutTag)
+	c.AddConfigTag("extends", layoutTag)
+	c.AddConfigTag("yield", yieldTag)
+	c.AddBlock("block").UniqueArgs().Compiler(blockTagCompiler)
+	c.AddBlock("content_for").ConfigCompiler(contentForTagCompiler)
+
+	c.AddTagAnalyzer("layout", partialNameAnalyzer)
+	c.AddTagAnalyzer("extends", partialNameAnalyzer)
+	c.AddTagAnalyzer("yield", regionNameAnalyzer)
+	c.AddBlockAnalyzer("block", blockTagAnalyzer)
+	c.AddBlockAnalyzer("content_for", func(node render.BlockNode) render.NodeAnalysis {
+		return regionNameAnalyzer(node.Args)
+	})
+}
+
+func layoutTag(cfg *render.Config, source string) (func(io.Writer, render.Context) error, error) {
+	expr, err := cfg.ParseExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w io.Writer, ctx render.Context) error {
+		value, err := ctx.Evaluate(expr)
+		if err != nil {
+			return err
+		}
+
+		rel, ok := value.(string)
+		if !ok {
+			return ctx.Errorf("layout requires a string argument; got %v", value)
+		}
+
+		filename, err := resolveTemplatePath(ctx.SourceFile(), rel)
+		if err != nil {
+			return ctx.WrapError(err)
+		}
+
+		ls, ok := ctx.(layoutSetter)
+		if !ok {
+			return ctx.Errorf("layout is not supported by this context")
+		}
+
+		return ls.SetLayout(w, filename)
+	}, nil
+}
+
+func blockTagCompiler(node render.BlockNode) (func(io.Writer, render.Context) error, error) {
+	name := strings.TrimSpace(node.Args)
+	if name == "" || strings.ContainsAny(name, " \t\r\n") {
+		return nil, fmt.Errorf("block tag requires a name; got %q", node.Args)
+	}
+
+	return func(w io.Writer, ctx render.Context) error {
+		r, ok := ctx.(inheritedBlockRenderer)
+		if !ok {
+			return ctx.RenderChildren(w)
+		}
+
+		return r.RenderInheritedBlock(w, name)
+	}, nil
+}
+
+func blockTagAnalyzer(render.BlockNode) render.NodeAnalysis {
+	return render.NodeAnalysis{Locals: []string{"block"}}
+}
+
+func contentForTagCompiler(cfg *render.Config, node render.BlockNode) (func(io.Writer, render.Context) error, error) {
+	expr, err := parseRegionName(cfg, "content_for", node.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	return captureBody(func(ctx render.Context, s string) error {
+		name, err := evaluateRegionName(ctx, "content_for", expr)
+		if err != nil {
+			return err
+		}
+
+		rs, ok := ctx.(regionStore)
+		if !ok {
+			return ctx.Errorf("content_for is not supported by this context")
+		}
+
+		rs.AppendRegion(name, s)
+
+		return nil
+	}), nil
+}
+
+func yieldTag(cfg *render.Config, source string) (func(io.Writer, render.Context) error, error) {
+	expr, err := parseRegionName(cfg, "yield", source)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w io.Writer, ctx render.Context) error {
+		name, err := evaluateRegionName(ctx, "yield", expr)
+		if err != nil {
+			return err
+		}
+
+		rs, ok := ctx.(regionStore)
+		if !ok {
+			return ctx.Errorf("yield is not supported by this context")
+		}
+
+		if mw, ok := ctx.(markupWriter); ok {
+			return mw.WriteMarkup(w, rs.Region(name))
+		}
+
+		_, err = io.WriteString(w, rs.Region(name))
+
+		return err
+	}, nil
+}
+
+func parseRegionName(cfg *render.Config, tag, source string) (expressions.Expression, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("%s tag requires a region name", tag)
+	}
+
+	return cfg.ParseExpression(source)
+}
+
+func evaluateRegionName(ctx render.Context, tag string, expr expressions.Expression) (string, error) {
+	value, err := ctx.Evaluate(expr)
+	if err != nil {
+		return "", err
+	}
+
+	name, ok := value.(string)
+	if !ok {
+		return "", ctx.Errorf("%s requires a string argument; got %v", tag, value)
+	}
+
+	return name, nil
+}
+
+func regionNameAnalyzer(source string) render.NodeAnalysis {
+	expr, err := expressions.Parse(source)
+	if err != nil {
+		return render.NodeAnalysis{}
+	}
+
+	return render.NodeAnalysis{Arguments: []expressions.Expression{expr}}
+}
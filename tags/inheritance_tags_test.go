@@ -0,0 +1,232 @@
+package tags
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+	"github.com/osteele/liquid/render"
+)
+
+func TestInheritanceTags(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddInheritanceTags(&cfg)
+	cfg.Cache["base.html"] = []byte(`<title>{% block title %}Site{% endblock %}</title><main>{% block content %}empty{% endblock %}</main>`)
+	cfg.Cache["middle.html"] = []byte(`{% layout "base.html" %}{% block title %}{{ block.super }} - Section{% endblock %}`)
+	cfg.Cache["nested.html"] = []byte(`[{% block outer %}outer {% block inner %}inner{% endblock %}{% endblock %}]`)
+
+	loc := parser.SourceLoc{Pathname: "page.html", LineNo: 1}
+	bindings := map[string]any{"name": "base.html", "var": "value"}
+
+	tests := []struct{ in, expected string }{
+		{`{% layout "base.html" %}{% block content %}Hello{% endblock %}`, `<title>Site</title><main>Hello</main>`},
+		{`{% extends "base.html" %}ignored{% block title %}Page{% endblock %}ignored`, `<title>Page</title><main>empty</main>`},
+		{`{% layout "base.html" %}{% block title %}{{ block.super }}: Page{% endblock %}`, `<title>Site: Page</title><main>empty</main>`},
+		{`{% layout name %}{% block content %}{{ var }}{% endblock %}`, `<title>Site</title><main>value</main>`},
+		{`{% layout "base.html" %}{% assign var = "assigned" %}{% block content %}{{ var }}{% endblock %}`, `<title>Site</title><main>assigned</main>`},
+		{`{% layout "base.html" %}{% for i in (1..3) %}{% block content %}x{% endblock %}{% endfor %}`, `<title>Site</title><main>x</main>`},
+
+		// chains
+		{`{% layout "middle.html" %}{% block content %}Hello{% endblock %}`, `<title>Site - Section</title><main>Hello</main>`},
+		{`{% layout "middle.html" %}{% block title %}{{ block.super }}: Page{% endblock %}`, `<title>Site - Section: Page</title><main>empty</main>`},
+
+		// nested blocks
+		{`{% layout "nested.html" %}{% block inner %}replaced{% endblock %}`, `[outer replaced]`},
+		{`{% layout "nested.html" %}{% block outer %}({{ block.super }}){% endblock %}`, `[(outer inner)]`},
+		{`{% layout "nested.html" %}{% block outer %}{% block inner %}new{% endblock %}{% endblock %}`, `[new]`},
+
+		// without a layout, blocks render their contents
+		{`a{% block content %}b{% endblock %}c`, `abc`},
+		{`{% block content %}{{ block.super }}b{% endblock %}`, `b`},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, loc)
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			err = render.Render(root, buf, bindings, cfg)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestInheritanceTags_errors(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddInheritanceTags(&cfg)
+	cfg.Cache["base.html"] = []byte(`<title>{% block title %}Site{% endblock %}</title><main>{% block content %}empty{% endblock %}</main>`)
+	cfg.Cache["middle.html"] = []byte(`{% layout "base.html" %}{% block title %}{{ block.super }} - Section{% endblock %}`)
+	cfg.Cache["broken.html"] = []byte(`{% block content %}{% endblock %}{{ x | undefined_filter }}`)
+
+	loc := parser.SourceLoc{Pathname: "page.html", LineNo: 1}
+
+	_, err := cfg.Compile(`{% block %}{% endblock %}`, loc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "block tag requires a name")
+
+	_, err = cfg.Compile("{% block title %}{% endblock %}\n{% if a %}{% block title %}{% endblock %}{% endif %}", loc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `block "title" is defined more than once`)
+	require.Equal(t, 2, err.LineNumber())
+
+	tests := []struct {
+		in, message, path string
+		line              int
+	}{
+		{`{% layout 10 %}`, "requires a string", "page.html", 1},
+		{"\n{% layout \"missing.html\" %}", "missing.html", "page.html", 2},
+		{"{% layout \"base.html\" %}\n{% layout \"base.html\" %}", "only have one layout", "page.html", 2},
+		{"{% layout \"base.html\" %}\n{% block content %}\n{{ x | undefined_filter }}{% endblock %}", "undefined_filter", "page.html", 3},
+		{"{% layout \"middle.html\" %}\n{% block title %}\n{{ x | undefined_filter }}{% endblock %}", "undefined_filter", "page.html", 3},
+		{`{% layout "broken.html" %}`, "undefined_filter", "broken.html", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, loc)
+			require.NoError(t, err)
+
+			err = render.Render(root, new(bytes.Buffer), map[string]any{}, cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.message)
+
+			re, ok := err.(render.Error)
+			require.True(t, ok)
+			require.Equal(t, test.path, re.Path())
+			require.Equal(t, test.line, re.LineNumber())
+		})
+	}
+}
+
+func TestInheritanceTags_cycle(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddInheritanceTags(&cfg)
+	cfg.Cache["cycle.html"] = []byte(`{% layout "cycle.html" %}`)
+
+	root, err := cfg.Compile(`{% layout "cycle.html" %}`, parser.SourceLoc{Pathname: "page.html", LineNo: 1})
+	require.NoError(t, err)
+
+	err = render.Render(root, new(bytes.Buffer), map[string]any{}, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "include depth")
+}
+
+func TestInheritanceTags_autoescape(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddInheritanceTags(&cfg)
+	cfg.SetAutoEscapeReplacer(render.HtmlEscaper)
+	cfg.Cache["escaped.html"] = []byte(`{% block content %}<b>{{ var }}</b>{% endblock %}`)
+
+	root, err := cfg.Compile(`{% layout "escaped.html" %}{% block content %}{{ block.super }}<i>{{ var }}</i>{% endblock %}`, parser.SourceLoc{Pathname: "page.html", LineNo: 1})
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	err = render.Render(root, buf, map[string]any{"var": "<&>"}, cfg)
+	require.NoError(t, err)
+	require.Equal(t, `<b>&lt;&amp;&gt;</b><i>&lt;&amp;&gt;</i>`, buf.String())
+}
+
+func TestInheritanceTags_contextualAutoescape(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddInheritanceTags(&cfg)
+	cfg.SetAutoEscapeReplacer(render.HtmlContextEscaper)
+	cfg.Cache["link.html"] = []byte(`<a href="{% block link %}{{ u }}{% endblock %}">{% block content %}<b>{{ var }}</b>{% endblock %}</a>`)
+	loc := parser.SourceLoc{Pathname: "page.html", LineNo: 1}
+	bindings := map[string]any{"u": "javascript:alert(1)", "var": "<&>"}
+
+	tests := []struct{ in, expected string }{
+		{`{% layout "link.html" %}`, `<a href="#ZgotmplZ"><b>&lt;&amp;&gt;</b></a>`},
+		{`{% layout "link.html" %}{% block link %}{{ u }}{% endblock %}`, `<a href="#ZgotmplZ"><b>&lt;&amp;&gt;</b></a>`},
+		// block.super is escaped for the position of the object that uses it.
+		{`{% layout "link.html" %}{% block link %}{{ block.super }}{% endblock %}`, `<a href="#ZgotmplZ"><b>&lt;&amp;&gt;</b></a>`},
+		{`{% layout "link.html" %}{% block content %}{{ block.super }}<i>{{ var }}</i>{% endblock %}`, `<a href="#ZgotmplZ"><b>&lt;&amp;&gt;</b><i>&lt;&amp;&gt;</i></a>`},
+
+		// So is the content of a region.
+		{`{% content_for "link" %}{{ u }}{% endcontent_for %}<a href="{% yield "link" %}">`, `<a href="#ZgotmplZ">`},
+		{`{% content_for "head" %}<b>{{ var }}</b>{% endcontent_for %}{% yield "head" %}`, `<b>&lt;&amp;&gt;</b>`},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, loc)
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			err = render.Render(root, buf, bindings, cfg)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestInheritanceTags_analyze(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddInheritanceTags(&cfg)
+
+	root, err := cfg.Compile(`{% layout "base.html" %}{% block title %}{{ block.super }}{{ page }}{% endblock %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	analysis := cfg.Analyze(root)
+	require.Equal(t, []string{"base.html"}, analysis.Partials)
+	require.Equal(t, []expressions.VariablePath{{"page"}}, analysis.Globals)
+}
+
+func TestRegionTags(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddInheritanceTags(&cfg)
+	cfg.Cache["page.html"] = []byte(`<head>{% yield "head" %}</head>{% block content %}{% endblock %}`)
+	cfg.Cache["scripts.html"] = []byte(`{% content_for "head" %}<script src="{{ src }}"></script>{% endcontent_for %}`)
+	loc := parser.SourceLoc{Pathname: "index.html", LineNo: 1}
+	bindings := map[string]any{"name": "head", "src": "a.js"}
+
+	tests := []struct {
+		in, expected string
+		regions      map[string]string
+	}{
+		{`{% content_for "head" %}a{% endcontent_for %}b{% yield "head" %}`, `ba`, map[string]string{"head": "a"}},
+		{`{% content_for name %}a{% endcontent_for %}{% content_for "head" %}b{% endcontent_for %}`, ``, map[string]string{"head": "ab"}},
+		{`[{% yield "missing" %}]`, `[]`, nil},
+		{`{% include "scripts.html" %}{% yield "head" %}`, `<script src="a.js"></script>`, map[string]string{"head": `<script src="a.js"></script>`}},
+		{
+			`{% layout "page.html" %}{% content_for "head" %}<title>{% endcontent_for %}{% block content %}body{% endblock %}`,
+			`<head><title></head>body`,
+			map[string]string{"head": "<title>"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, loc)
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			result, err := render.RenderWithResult(context.Background(), root, buf, bindings, cfg)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, buf.String())
+			require.Equal(t, test.regions, result.Regions)
+		})
+	}
+
+	_, err := cfg.Compile(`{% yield %}`, loc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "yield tag requires a region name")
+
+	_, err = cfg.Compile(`{% content_for %}{% endcontent_for %}`, loc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "content_for tag requires a region name")
+
+	root, err := cfg.Compile(`{% yield 1 %}`, loc)
+	require.NoError(t, err)
+	err = render.Render(root, new(bytes.Buffer), bindings, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "yield requires a string argument")
+}
@@ -1,10 +1,10 @@
 package tags
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"math"
 	"reflect"
 	"sort"
 
@@ -17,11 +17,9 @@ import (
 // An IterationKeyedMap is a map that yields its keys, instead of (key, value) pairs, when iterated.
 type IterationKeyedMap map[string]any
 
-const forloopVarName = "forloop"
-
-var (
-	errLoopContinueLoop = errors.New("continue outside a loop")
-	errLoopBreak        = errors.New("break outside a loop")
+const (
+	forloopVarName      = "forloop"
+	tablerowloopVarName = "tablerowloop"
 )
 
 type iterable interface {
@@ -29,20 +27,67 @@ type iterable interface {
 	Index(int) any
 }
 
+// iterationCounter is implemented by render contexts that enforce a limit on
+// the total number of loop iterations.
+type iterationCounter interface {
+	CountIteration() error
+}
+
+// loopSignaler is implemented by render contexts that carry the signals of
+// the break and continue tags to their loop.
+type loopSignaler interface {
+	SignalLoop(render.LoopSignal)
+	TakeLoopSignal() render.LoopSignal
+}
+
+// loopOffsetStore is implemented by render contexts that record where each
+// for loop stopped, for offset:continue.
+type loopOffsetStore interface {
+	LoopOffset(name string) int
+	SetLoopOffset(name string, offset int)
+}
+
+// countIteration records a loop iteration, if ctx enforces an iteration limit.
+func countIteration(ctx render.Context) error {
+	if counter, ok := ctx.(iterationCounter); ok {
+		return counter.CountIteration()
+	}
+
+	return nil
+}
+
+// goContext returns the context.Context of the render, if ctx supplies one.
+func goContext(ctx render.Context) context.Context {
+	if p, ok := ctx.(render.GoContextProvider); ok {
+		return p.Context()
+	}
+
+	return context.Background()
+}
+
 func breakTag(string) (func(io.Writer, render.Context) error, error) {
-	return func(_ io.Writer, ctx render.Context) error {
-		return ctx.WrapError(errLoopBreak)
-	}, nil
+	return loopSignalTag(render.LoopBreak), nil
 }
 
 func continueTag(string) (func(io.Writer, render.Context) error, error) {
+	return loopSignalTag(render.LoopContinue), nil
+}
+
+func loopSignalTag(s render.LoopSignal) func(io.Writer, render.Context) error {
 	return func(_ io.Writer, ctx render.Context) error {
-		return ctx.WrapError(errLoopContinueLoop)
-	}, nil
+		signaler, ok := ctx.(loopSignaler)
+		if !ok {
+			return ctx.Errorf("%s outside a loop", s)
+		}
+
+		signaler.SignalLoop(s)
+
+		return nil
+	}
 }
 
-func cycleTag(args string) (func(io.Writer, render.Context) error, error) {
-	stmt, err := expressions.ParseStatement(expressions.CycleStatementSelector, args)
+func cycleTag(cfg *render.Config, args string) (func(io.Writer, render.Context) error, error) {
+	stmt, err := cfg.ParseStatement(expressions.CycleStatementSelector, args)
 	if err != nil {
 		return nil, err
 	}
@@ -51,6 +96,10 @@ func cycleTag(args string) (func(io.Writer, render.Context) error, error) {
 
 	return func(w io.Writer, ctx render.Context) error {
 		loopVar := ctx.Get(forloopVarName)
+		if loopVar == nil {
+			loopVar = ctx.Get(tablerowloopVarName)
+		}
+
 		if loopVar == nil {
 			return ctx.Errorf("cycle must be within a forloop")
 		}
@@ -68,8 +117,8 @@ func cycleTag(args string) (func(io.Writer, render.Context) error, error) {
 	}, nil
 }
 
-func loopTagCompiler(node render.BlockNode) (func(io.Writer, render.Context) error, error) {
-	stmt, err := expressions.ParseStatement(expressions.LoopStatementSelector, node.Args)
+func loopTagCompiler(cfg *render.Config, node render.BlockNode) (func(io.Writer, render.Context) error, error) {
+	stmt, err := cfg.ParseStatement(expressions.LoopStatementSelector, node.Args)
 	if err != nil {
 		return nil, err
 	}
@@ -86,11 +135,17 @@ func loopTagCompiler(node render.BlockNode) (func(io.Writer, render.Context) err
 			return nil
 		}
 
-		iter, err = applyLoopModifiers(stmt.Loop, ctx, iter)
+		name := loopName(stmt.Loop)
+
+		iter, offset, err := applyLoopModifiers(stmt.Loop, name, ctx, iter)
 		if err != nil {
 			return err
 		}
 
+		if store, ok := ctx.(loopOffsetStore); ok && node.Name == "for" {
+			store.SetLoopOffset(name, offset+iter.Len())
+		}
+
 		if len(node.Clauses) > 1 {
 			return errors.New("for loops accept at most one else clause")
 		}
@@ -99,74 +154,132 @@ func loopTagCompiler(node render.BlockNode) (func(io.Writer, render.Context) err
 			return ctx.RenderBlock(w, node.Clauses[0])
 		}
 
-		return loopRenderer{stmt.Loop, node.Name}.render(iter, w, ctx)
+		return loopRenderer{stmt.Loop, node.Name, name}.render(iter, w, ctx)
 	}, nil
 }
 
+// loopName returns the value of forloop.name, which is the loop variable and
+// the collection expression, as in "item-products".
+func loopName(loop expressions.Loop) string {
+	collection := ""
+	if n := expressions.SyntaxTree(loop.Expr); n != nil {
+		collection = n.String()
+	}
+
+	return loop.Variable + "-" + collection
+}
+
+func loopTagAnalyzer(node render.BlockNode) render.NodeAnalysis {
+	stmt, err := expressions.ParseStatement(expressions.LoopStatementSelector, node.Args)
+	if err != nil {
+		return render.NodeAnalysis{}
+	}
+
+	loopVar := forloopVarName
+	if node.Name == "tablerow" {
+		loopVar = tablerowloopVarName
+	}
+
+	analysis := render.NodeAnalysis{
+		Arguments: []expressions.Expression{stmt.Expr},
+		Locals:    []string{stmt.Loop.Variable, loopVar},
+	}
+	for _, expr := range []expressions.Expression{stmt.Limit, stmt.Offset, stmt.Cols} {
+		if expr != nil {
+			analysis.Arguments = append(analysis.Arguments, expr)
+		}
+	}
+
+	return analysis
+}
+
 type loopRenderer struct {
 	expressions.Loop
 
 	tagName string
+	name    string // forloop.name
 }
 
 func (loop loopRenderer) render(iter iterable, w io.Writer, ctx render.Context) error {
+	l := iter.Len()
+
 	// loop decorator
-	decorator, err := makeLoopDecorator(loop, ctx)
+	decorator, err := makeLoopDecorator(loop, ctx, l)
 	if err != nil {
 		return err
 	}
 
+	loopVarName := forloopVarName
+	if loop.tagName == "tablerow" {
+		loopVarName = tablerowloopVarName
+	}
+
 	// shallow-bind the loop variables; restore on exit
-	defer func(index, forloop any) {
-		ctx.Set(forloopVarName, index)
-		ctx.Set(loop.Variable, forloop)
-	}(ctx.Get(forloopVarName), ctx.Get(loop.Variable))
+	defer func(loopObject, variable any) {
+		ctx.Set(loopVarName, loopObject)
+		ctx.Set(loop.Variable, variable)
+	}(ctx.Get(loopVarName), ctx.Get(loop.Variable))
 
 	cycleMap := map[string]int{}
-	// Pre-allocate the forloop map once and reuse it across iterations.
-	forloopMap := map[string]any{
+	// Pre-allocate the loop object once and reuse it across iterations.
+	loopMap := map[string]any{
 		"first":   false,
 		"last":    false,
 		"index":   0,
 		"index0":  0,
 		"rindex":  0,
 		"rindex0": 0,
-		"length":  0,
+		"length":  l,
 		".cycles": cycleMap,
 	}
-	ctx.Set(forloopVarName, forloopMap)
 
-loop:
+	if loopVarName == forloopVarName {
+		loopMap["name"] = loop.name
+		loopMap["parentloop"] = ctx.Get(forloopVarName)
+	}
+
+	ctx.Set(loopVarName, loopMap)
+
+	gctx := goContext(ctx)
+	done := gctx.Done()
+	signaler, _ := ctx.(loopSignaler)
+
+	for i := range l {
+		select {
+		case <-done:
+			return ctx.WrapError(gctx.Err())
+		default:
+		}
+
+		if err := countIteration(ctx); err != nil {
+			return err
+		}
 
-	for i, l := 0, iter.Len(); i < l; i++ {
 		ctx.Set(loop.Variable, iter.Index(i))
-		forloopMap["first"] = i == 0
-		forloopMap["last"] = i == l-1
-		forloopMap["index"] = i + 1
-		forloopMap["index0"] = i
-		forloopMap["rindex"] = l - i
-		forloopMap["rindex0"] = l - i - 1
-		forloopMap["length"] = l
+		loopMap["first"] = i == 0
+		loopMap["last"] = i == l-1
+		loopMap["index"] = i + 1
+		loopMap["index0"] = i
+		loopMap["rindex"] = l - i
+		loopMap["rindex0"] = l - i - 1
 		decorator.before(w, i)
+		decorator.update(loopMap, i)
 		err := ctx.RenderChildren(w)
 		decorator.after(w, i, l)
 
-		switch {
-		case err == nil:
-		// fall through
-		case err.Cause() == errLoopBreak:
-			break loop
-		case err.Cause() == errLoopContinueLoop:
-			continue loop
-		default:
+		if err != nil {
 			return err
 		}
+
+		if signaler != nil && signaler.TakeLoopSignal() == render.LoopBreak {
+			break
+		}
 	}
 
 	return nil
 }
 
-func makeLoopDecorator(loop loopRenderer, ctx render.Context) (loopDecorator, error) {
+func makeLoopDecorator(loop loopRenderer, ctx render.Context, length int) (loopDecorator, error) {
 	if loop.tagName == "tablerow" {
 		if loop.Cols != nil {
 			val, err := ctx.Evaluate(loop.Cols)
@@ -184,7 +297,8 @@ func makeLoopDecorator(loop loopRenderer, ctx render.Context) (loopDecorator, er
 			}
 		}
 
-		return tableRowDecorator(math.MaxInt32), nil
+		// Without cols, the table has a single row.
+		return tableRowDecorator(max(length, 1)), nil
 	}
 
 	return forLoopDecorator{}, nil
@@ -192,13 +306,16 @@ func makeLoopDecorator(loop loopRenderer, ctx render.Context) (loopDecorator, er
 
 type loopDecorator interface {
 	before(io.Writer, int)
+	// update sets the decorator's properties of the loop object
+	update(map[string]any, int)
 	after(io.Writer, int, int)
 }
 
 type forLoopDecorator struct{}
 
-func (d forLoopDecorator) before(io.Writer, int)     {}
-func (d forLoopDecorator) after(io.Writer, int, int) {}
+func (d forLoopDecorator) before(io.Writer, int)      {}
+func (d forLoopDecorator) update(map[string]any, int) {}
+func (d forLoopDecorator) after(io.Writer, int, int)  {}
 
 type tableRowDecorator int
 
@@ -217,6 +334,18 @@ func (c tableRowDecorator) before(w io.Writer, i int) {
 	}
 }
 
+// update sets the column and row properties of tablerowloop.
+func (c tableRowDecorator) update(loopMap map[string]any, i int) {
+	cols := int(c)
+
+	row, col := i/cols, i%cols
+	loopMap["col"] = col + 1
+	loopMap["col0"] = col
+	loopMap["col_first"] = col == 0
+	loopMap["col_last"] = col == cols-1
+	loopMap["row"] = row + 1
+}
+
 func (c tableRowDecorator) after(w io.Writer, i, l int) {
 	cols := int(c)
 
@@ -231,36 +360,47 @@ func (c tableRowDecorator) after(w io.Writer, i, l int) {
 	}
 }
 
-func applyLoopModifiers(loop expressions.Loop, ctx render.Context, iter iterable) (iterable, error) {
+// applyLoopModifiers applies the reversed, offset, and limit modifiers. It
+// returns the modified iterable, and the offset.
+func applyLoopModifiers(loop expressions.Loop, name string, ctx render.Context, iter iterable) (iterable, int, error) {
 	if loop.Reversed {
 		iter = reverseWrapper{iter}
 	}
 
-	if loop.Offset != nil {
+	offset := 0
+
+	switch {
+	case loop.OffsetContinue:
+		if store, ok := ctx.(loopOffsetStore); ok {
+			offset = store.LoopOffset(name)
+		}
+	case loop.Offset != nil:
 		val, err := ctx.Evaluate(loop.Offset)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
-		offset, ok := val.(int)
+		n, ok := val.(int)
 		if !ok {
-			return nil, ctx.Errorf("loop offset must be an integer")
+			return nil, 0, ctx.Errorf("loop offset must be an integer")
 		}
 
-		if offset > 0 {
-			iter = offsetWrapper{iter, offset}
-		}
+		offset = max(n, 0)
+	}
+
+	if offset > 0 {
+		iter = offsetWrapper{iter, offset}
 	}
 
 	if loop.Limit != nil {
 		val, err := ctx.Evaluate(loop.Limit)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		limit, ok := val.(int)
 		if !ok {
-			return nil, ctx.Errorf("loop limit must be an integer")
+			return nil, 0, ctx.Errorf("loop limit must be an integer")
 		}
 
 		if limit >= 0 {
@@ -268,7 +408,7 @@ func applyLoopModifiers(loop expressions.Loop, ctx render.Context, iter iterable
 		}
 	}
 
-	return iter, nil
+	return iter, offset, nil
 }
 
 func makeIterator(value any) iterable {
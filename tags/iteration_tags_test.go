@@ -2,6 +2,8 @@ package tags
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"regexp"
@@ -68,6 +70,18 @@ var iterationTests = []struct{ in, expected string }{
 		`{% for i in array %}{{ forloop.index }}[{% for j in array %}{{ forloop.index }}{% endfor %}]{{ forloop.index }}{% endfor %}`,
 		"1[123]12[123]23[123]3",
 	},
+	{
+		`{% for i in array %}{% for j in (1..2) %}{{ forloop.parentloop.index }}{{ forloop.index }}.{% endfor %}{% endfor %}`,
+		"11.12.21.22.31.32.",
+	},
+	{
+		`{% for i in (1..2) %}{% for j in (1..1) %}{% for k in (1..1) %}{{ forloop.parentloop.parentloop.index }}{% endfor %}{% endfor %}{% endfor %}`,
+		"12",
+	},
+	{`{% for a in array %}{{ forloop.parentloop }}.{% endfor %}`, "..."},
+	{`{% for a in array limit:1 %}{{ forloop.name }}{% endfor %}`, "a-array"},
+	{`{% for i in (1..limit) limit:1 %}{{ forloop.name }}{% endfor %}`, "i-(1..limit)"},
+	{`{% for p in loopmods.items limit:1 %}{{ forloop.name }}{% endfor %}`, "p-loopmods.items"},
 
 	{`{% for a in array reversed %}{{ forloop.first }}.{% endfor %}`, "true.false.false."},
 	{`{% for a in array reversed %}{{ forloop.last }}.{% endfor %}`, "false.false.true."},
@@ -87,6 +101,16 @@ var iterationTests = []struct{ in, expected string }{
 	{`{% for a in array offset:1 %}{{ forloop.last }}.{% endfor %}`, "false.true."},
 	{`{% for a in array offset:1 %}{{ forloop.length }}.{% endfor %}`, "2.2."},
 
+	// offset:continue
+	{
+		`{% for a in array limit:1 %}{{ a }}{% endfor %}.{% for a in array offset:continue limit:1 %}{{ a }}{% endfor %}.{% for a in array offset: continue %}{{ a }}{% endfor %}`,
+		"first.second.third",
+	},
+	{`{% for a in array offset:continue limit:2 %}{{ a }}.{% endfor %}`, "first.second."},
+	{`{% for a in array limit:1 %}{% endfor %}{% for b in array offset:continue %}{{ b }}.{% endfor %}`, "first.second.third."},
+	{`{% for a in array offset:1 limit:1 %}{% endfor %}{% for a in array offset:continue %}{{ a }}.{% endfor %}`, "third."},
+	{`{% for a in array %}{% break %}{% endfor %}{% for a in array offset:continue %}{{ a }}{% else %}empty{% endfor %}`, "empty"},
+
 	{`{% for a in array %}{% if a == 'second' %}{% break %}{% endif %}{{ a }}{% endfor %}`, "first"},
 	{`{% for a in array %}{% if a == 'second' %}{% continue %}{% endif %}{{ a }}.{% endfor %}`, "first.third."},
 
@@ -135,6 +159,23 @@ var iterationTests = []struct{ in, expected string }{
 		 <tr class="row2"><td class="col1">Batman Poster</td><td class="col2">Bullseye Shirt</td></tr>
 		 <tr class="row3"><td class="col1">Another Classic Vinyl</td><td class="col2">Awesome Jeans</td></tr>`,
 	},
+	{
+		`{% tablerow a in array cols:2 %}{{ tablerowloop.col }}{{ tablerowloop.col0 }}{{ tablerowloop.col_first }}{{ tablerowloop.col_last }}{{ tablerowloop.row }}{% endtablerow %}`,
+		`<tr class="row1"><td class="col1">10truefalse1</td><td class="col2">21falsetrue1</td></tr>
+		 <tr class="row2"><td class="col1">10truefalse2</td></tr>`,
+	},
+	{
+		`{% tablerow a in array %}{{ tablerowloop.index }}{{ tablerowloop.rindex0 }}{{ tablerowloop.first }}{{ tablerowloop.last }}{{ tablerowloop.length }}{{ tablerowloop.col_last }}{% endtablerow %}`,
+		`<tr class="row1"><td class="col1">12truefalse3false</td><td class="col2">21falsefalse3false</td><td class="col3">30falsetrue3true</td></tr>`,
+	},
+	{
+		`{% for i in (1..1) %}{% tablerow a in array limit:2 %}{{ forloop.index }}{{ forloop.length }}{% cycle 'x', 'y' %}{% endtablerow %}{% endfor %}`,
+		`<tr class="row1"><td class="col1">11x</td><td class="col2">11y</td></tr>`,
+	},
+	{
+		`{% tablerow a in array limit:2 %}{{ forloop }}{% cycle 'x', 'y' %}{% endtablerow %}`,
+		`<tr class="row1"><td class="col1">x</td><td class="col2">y</td></tr>`,
+	},
 }
 
 var iterationSyntaxErrorTests = []struct{ in, expected string }{
@@ -164,7 +205,7 @@ var iterationTestBindings = map[string]any{
 	"offset":   1,
 	"limit":    2,
 	"cols":     2,
-	"loopmods": map[string]any{"limit": 2, "offset": 1, "cols": 2},
+	"loopmods": map[string]any{"limit": 2, "offset": 1, "cols": 2, "items": []string{"x"}},
 }
 
 func TestIterationTags(t *testing.T) {
@@ -216,6 +257,43 @@ func TestIterationTags_errors(t *testing.T) {
 	}
 }
 
+func TestIterationTags_cancellation(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+
+	var cancel context.CancelFunc
+	cfg.AddTag("cancel", func(string) (func(io.Writer, render.Context) error, error) {
+		return func(io.Writer, render.Context) error {
+			cancel()
+			return nil
+		}, nil
+	})
+
+	count := 0
+	cfg.AddTag("count", func(string) (func(io.Writer, render.Context) error, error) {
+		return func(io.Writer, render.Context) error {
+			count++
+			return nil
+		}, nil
+	})
+
+	for _, tag := range []string{"for", "tablerow"} {
+		t.Run(tag, func(t *testing.T) {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+			count = 0
+			source := fmt.Sprintf(`{%% %s i in (1..10000000) %%}{%% count %%}{%% if i == 2 %%}{%% cancel %%}{%% endif %%}{%% end%s %%}`, tag, tag)
+			root, err := cfg.Compile(source, parser.SourceLoc{})
+			require.NoError(t, err)
+			err = render.RenderContext(ctx, root, io.Discard, iterationTestBindings, cfg)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, context.Canceled))
+			require.Equal(t, 2, count)
+		})
+	}
+}
+
 // loopControlStateTests models the for-loop control state machine:
 // (current iteration, control action) -> rendered output.
 var loopControlStateTests = []struct {
@@ -232,6 +310,12 @@ var loopControlStateTests = []struct {
 	{"break only affects inner loop", `{% for a in array %}{% for b in array %}{% if b == 'second' %}{% break %}{% endif %}{{ b }}{% endfor %}:{{ a }}.{% endfor %}`, "first:first.first:second.first:third."},
 	{"else on empty array", `{% for a in empty %}{{ a }}.{% else %}none{% endfor %}`, "none"},
 	{"else not used", `{% for a in array %}{{ a }}.{% else %}none{% endfor %}`, "first.second.third."},
+	{"break in case", `{% for a in array %}{% case a %}{% when 'second' %}{% break %}{% endcase %}{{ a }}.{% endfor %}`, "first."},
+	{"continue in unless", `{% for a in array %}{% unless a != 'second' %}{% continue %}{% endunless %}{{ a }}.{% endfor %}`, "first.third."},
+	{"break in capture keeps the partial capture", `{% for a in array %}{% capture c %}{{ a }}{% if a == 'second' %}{% break %}{% endif %}!{% endcapture %}{{ c }}.{% endfor %}{{ c }}`, "first!.second"},
+	{"continue in tablerow", `{% tablerow a in array %}{% if a == 'second' %}{% continue %}{% endif %}{{ a }}{% endtablerow %}`, `<tr class="row1"><td class="col1">first</td><td class="col2"></td><td class="col3">third</td></tr>`},
+	{"break in include", `{% for a in array %}{% include "break.html" %}{{ a }}.{% endfor %}`, "first."},
+	{"break in render stays in the partial", `{% for a in array %}{% render "loop.html", array: array %}{{ a }}.{% endfor %}`, "1first.1second.1third."},
 }
 
 func TestLoopControlState(t *testing.T) {
@@ -243,6 +327,9 @@ func TestLoopControlState(t *testing.T) {
 		"empty": []string{},
 	}
 
+	cfg.Cache["break.html"] = []byte(`{% if a == 'second' %}{% break %}{% endif %}`)
+	cfg.Cache["loop.html"] = []byte(`{% for b in array %}{{ forloop.index }}{% break %}{% endfor %}`)
+
 	for _, test := range loopControlStateTests {
 		t.Run(test.name, func(t *testing.T) {
 			root, err := cfg.Compile(test.template, parser.SourceLoc{})
@@ -256,6 +343,35 @@ func TestLoopControlState(t *testing.T) {
 	}
 }
 
+func TestLoopControlState_strayErrors(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddMacroTags(&cfg)
+	cfg.Cache["break.html"] = []byte("\n{% break %}")
+
+	tests := []struct {
+		in, message, path string
+		line              int
+	}{
+		{"\n{% if true %}{% continue %}{% endif %}", "continue outside a loop", "page.html", 2},
+		{`{% include "break.html" %}`, "break outside a loop", "break.html", 2},
+		{`{% for a in (1..2) %}{% render "break.html" %}{% endfor %}`, "break outside a loop", "break.html", 2},
+		{"{% macro f %}\n{% break %}{% endmacro %}{% for a in (1..2) %}{{ f() }}{% endfor %}", "break outside a loop", "page.html", 2},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, parser.SourceLoc{Pathname: "page.html", LineNo: 1})
+			require.NoError(t, err)
+
+			err = render.Render(root, io.Discard, map[string]any{}, cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.message)
+			require.Equal(t, test.path, err.Path())
+			require.Equal(t, test.line, err.LineNumber())
+		})
+	}
+}
+
 // forloopMapStateTests models the forloop variable state at each iteration.
 // Each row is (loop configuration, iteration index) -> expected forloop map.
 var forloopMapStateTests = []struct {
@@ -269,9 +385,9 @@ var forloopMapStateTests = []struct {
 		template: `{% for a in array %}{% record_forloop %}{% endfor %}`,
 		bindings: map[string]any{"array": []string{"x", "y", "z"}},
 		expected: []map[string]any{
-			{"first": true, "last": false, "index": 1, "index0": 0, "rindex": 3, "rindex0": 2, "length": 3},
-			{"first": false, "last": false, "index": 2, "index0": 1, "rindex": 2, "rindex0": 1, "length": 3},
-			{"first": false, "last": true, "index": 3, "index0": 2, "rindex": 1, "rindex0": 0, "length": 3},
+			{"first": true, "last": false, "index": 1, "index0": 0, "rindex": 3, "rindex0": 2, "length": 3, "name": "a-array", "parentloop": nil},
+			{"first": false, "last": false, "index": 2, "index0": 1, "rindex": 2, "rindex0": 1, "length": 3, "name": "a-array", "parentloop": nil},
+			{"first": false, "last": true, "index": 3, "index0": 2, "rindex": 1, "rindex0": 0, "length": 3, "name": "a-array", "parentloop": nil},
 		},
 	},
 	{
@@ -279,8 +395,8 @@ var forloopMapStateTests = []struct {
 		template: `{% for a in array limit:2 %}{% record_forloop %}{% endfor %}`,
 		bindings: map[string]any{"array": []string{"x", "y", "z"}},
 		expected: []map[string]any{
-			{"first": true, "last": false, "index": 1, "index0": 0, "rindex": 2, "rindex0": 1, "length": 2},
-			{"first": false, "last": true, "index": 2, "index0": 1, "rindex": 1, "rindex0": 0, "length": 2},
+			{"first": true, "last": false, "index": 1, "index0": 0, "rindex": 2, "rindex0": 1, "length": 2, "name": "a-array", "parentloop": nil},
+			{"first": false, "last": true, "index": 2, "index0": 1, "rindex": 1, "rindex0": 0, "length": 2, "name": "a-array", "parentloop": nil},
 		},
 	},
 	{
@@ -288,8 +404,8 @@ var forloopMapStateTests = []struct {
 		template: `{% for a in array offset:1 %}{% record_forloop %}{% endfor %}`,
 		bindings: map[string]any{"array": []string{"x", "y", "z"}},
 		expected: []map[string]any{
-			{"first": true, "last": false, "index": 1, "index0": 0, "rindex": 2, "rindex0": 1, "length": 2},
-			{"first": false, "last": true, "index": 2, "index0": 1, "rindex": 1, "rindex0": 0, "length": 2},
+			{"first": true, "last": false, "index": 1, "index0": 0, "rindex": 2, "rindex0": 1, "length": 2, "name": "a-array", "parentloop": nil},
+			{"first": false, "last": true, "index": 2, "index0": 1, "rindex": 1, "rindex0": 0, "length": 2, "name": "a-array", "parentloop": nil},
 		},
 	},
 	{
@@ -297,9 +413,9 @@ var forloopMapStateTests = []struct {
 		template: `{% for a in array reversed %}{% record_forloop %}{% endfor %}`,
 		bindings: map[string]any{"array": []string{"x", "y", "z"}},
 		expected: []map[string]any{
-			{"first": true, "last": false, "index": 1, "index0": 0, "rindex": 3, "rindex0": 2, "length": 3},
-			{"first": false, "last": false, "index": 2, "index0": 1, "rindex": 2, "rindex0": 1, "length": 3},
-			{"first": false, "last": true, "index": 3, "index0": 2, "rindex": 1, "rindex0": 0, "length": 3},
+			{"first": true, "last": false, "index": 1, "index0": 0, "rindex": 3, "rindex0": 2, "length": 3, "name": "a-array", "parentloop": nil},
+			{"first": false, "last": false, "index": 2, "index0": 1, "rindex": 2, "rindex0": 1, "length": 3, "name": "a-array", "parentloop": nil},
+			{"first": false, "last": true, "index": 3, "index0": 2, "rindex": 1, "rindex0": 0, "length": 3, "name": "a-array", "parentloop": nil},
 		},
 	},
 	{
@@ -307,7 +423,7 @@ var forloopMapStateTests = []struct {
 		template: `{% for a in one %}{% record_forloop %}{% endfor %}`,
 		bindings: map[string]any{"one": []string{"only"}},
 		expected: []map[string]any{
-			{"first": true, "last": true, "index": 1, "index0": 0, "rindex": 1, "rindex0": 0, "length": 1},
+			{"first": true, "last": true, "index": 1, "index0": 0, "rindex": 1, "rindex0": 0, "length": 1, "name": "a-one", "parentloop": nil},
 		},
 	},
 }
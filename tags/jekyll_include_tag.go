@@ -0,0 +1,250 @@
+package tags
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/render"
+	"github.com/osteele/liquid/values"
+)
+
+// jekyllInclude is a parsed Jekyll include or include_relative tag:
+// {% include name.html key=value key2="value" %}.
+type jekyllInclude struct {
+	name   []nameSegment
+	params []renderParam // nil if the tag has no parameters
+}
+
+// A nameSegment is text or, if expr isn't nil, a {{ expr }} in a file name.
+type nameSegment struct {
+	text string
+	expr expressions.Expression
+}
+
+// jekyllIncludeTag compiles an include tag with Jekyll's semantics. The file
+// name is literal text, with optional {{ expr }} substitutions, instead of an
+// expression. It is relative to the template store, or, for
+// include_relative, to the including template. The parameters are
+// available to the partial as include.key.
+func jekyllIncludeTag(cfg *render.Config, source string, relative bool) (func(io.Writer, render.Context) error, error) {
+	inc, err := parseJekyllInclude(cfg.ParseTable, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w io.Writer, ctx render.Context) error {
+		rel, err := inc.filename(ctx)
+		if err != nil {
+			return err
+		}
+
+		dir := ""
+		if relative {
+			dir = ctx.SourceFile()
+		}
+
+		filename, err := resolveTemplatePath(dir, rel)
+		if err != nil {
+			return ctx.WrapError(err)
+		}
+
+		b := map[string]any{}
+		if inc.params != nil {
+			params := make(map[string]any, len(inc.params))
+			for _, param := range inc.params {
+				value, err := ctx.Evaluate(param.value)
+				if err != nil {
+					return err
+				}
+
+				params[param.name] = value
+			}
+
+			b["include"] = params
+		}
+
+		return renderInclude(w, ctx, filename, b)
+	}, nil
+}
+
+// parseJekyllInclude parses the arguments of an include tag. Its expressions
+// are parsed with table, which can be nil.
+func parseJekyllInclude(table *expressions.ParseTable, source string) (*jekyllInclude, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("include tag requires a file name")
+	}
+
+	nameSource, rest, err := takeJekyllIncludeName(source)
+	if err != nil {
+		return nil, err
+	}
+
+	inc := &jekyllInclude{}
+	if inc.name, err = parseNameSegments(table, nameSource); err != nil {
+		return nil, err
+	}
+
+	if inc.params, err = parseJekyllIncludeParams(table, rest); err != nil {
+		return nil, err
+	}
+
+	return inc, nil
+}
+
+// takeJekyllIncludeName splits the file name from the parameters. The name is
+// quoted, or ends at whitespace outside of {{ }}.
+func takeJekyllIncludeName(source string) (string, string, error) {
+	if source[0] == '\'' || source[0] == '"' {
+		end := findClosingQuote(source, 0)
+		if end < 0 {
+			return "", "", fmt.Errorf("unclosed quote in file name")
+		}
+
+		return source[1:end], source[end+1:], nil
+	}
+
+	for i := 0; i < len(source); i++ {
+		switch {
+		case strings.HasPrefix(source[i:], "{{"):
+			end := strings.Index(source[i:], "}}")
+			if end < 0 {
+				return "", "", fmt.Errorf("unclosed {{ in file name")
+			}
+
+			i += end + 1
+		case unicode.IsSpace(rune(source[i])):
+			return source[:i], source[i:], nil
+		}
+	}
+
+	return source, "", nil
+}
+
+func parseNameSegments(table *expressions.ParseTable, source string) ([]nameSegment, error) {
+	var segments []nameSegment
+
+	for source != "" {
+		start := strings.Index(source, "{{")
+		if start < 0 {
+			segments = append(segments, nameSegment{text: source})
+			break
+		}
+
+		end := strings.Index(source[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed {{ in file name")
+		}
+
+		expr, err := table.Parse(source[start+2 : start+end])
+		if err != nil {
+			return nil, err
+		}
+
+		if start > 0 {
+			segments = append(segments, nameSegment{text: source[:start]})
+		}
+
+		segments = append(segments, nameSegment{expr: expr})
+		source = source[start+end+2:]
+	}
+
+	return segments, nil
+}
+
+// parseJekyllIncludeParams parses key=value parameters. Values are quoted
+// strings or variables. Like the render tag, it also accepts key: value, and
+// commas between parameters.
+func parseJekyllIncludeParams(table *expressions.ParseTable, source string) ([]renderParam, error) {
+	var params []renderParam
+
+	for {
+		source = strings.TrimLeftFunc(source, func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+		if source == "" {
+			return params, nil
+		}
+
+		name, nameEnd := readRenderIdentifier(source)
+		if name == "" {
+			return nil, fmt.Errorf("invalid include parameter (expected 'key=value'): %s", source)
+		}
+
+		rest := strings.TrimSpace(source[nameEnd:])
+		if rest == "" || (rest[0] != '=' && rest[0] != ':') {
+			return nil, fmt.Errorf("invalid include parameter (expected 'key=value'): %s", source)
+		}
+
+		rest = strings.TrimSpace(rest[1:])
+		if rest == "" {
+			return nil, fmt.Errorf("missing value for include parameter '%s'", name)
+		}
+
+		end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+		if rest[0] == '\'' || rest[0] == '"' {
+			end = findClosingQuote(rest, 0) + 1
+			if end == 0 {
+				return nil, fmt.Errorf("unclosed quote in include parameter '%s'", name)
+			}
+		} else if end < 0 {
+			end = len(rest)
+		}
+
+		value, err := table.Parse(rest[:end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for include parameter '%s': %w", name, err)
+		}
+
+		params = append(params, renderParam{name: name, value: value})
+		source = rest[end:]
+	}
+}
+
+// filename evaluates the file name.
+func (inc *jekyllInclude) filename(ctx render.Context) (string, error) {
+	var b strings.Builder
+
+	for _, segment := range inc.name {
+		if segment.expr == nil {
+			b.WriteString(segment.text)
+			continue
+		}
+
+		value, err := ctx.Evaluate(segment.expr)
+		if err != nil {
+			return "", err
+		}
+
+		if value = values.ToLiquid(value); value != nil {
+			fmt.Fprint(&b, value)
+		}
+	}
+
+	return b.String(), nil
+}
+
+func jekyllIncludeTagAnalyzer(source string) render.NodeAnalysis {
+	inc, err := parseJekyllInclude(nil, source)
+	if err != nil {
+		return render.NodeAnalysis{}
+	}
+
+	var analysis render.NodeAnalysis
+	if len(inc.name) == 1 && inc.name[0].expr == nil {
+		analysis.Partials = []string{inc.name[0].text}
+	}
+
+	for _, segment := range inc.name {
+		if segment.expr != nil {
+			analysis.Arguments = append(analysis.Arguments, segment.expr)
+		}
+	}
+
+	for _, param := range inc.params {
+		analysis.Arguments = append(analysis.Arguments, param.value)
+	}
+
+	return analysis
+}
@@ -0,0 +1,122 @@
+package tags
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+	"github.com/osteele/liquid/render"
+)
+
+func TestJekyllIncludeTag(t *testing.T) {
+	cfg := render.NewConfig()
+	cfg.JekyllExtensions = true
+	AddStandardTags(&cfg)
+	cfg.Cache["card.html"] = []byte(`[{{ page.title }}|{{ include.title }}]`)
+	cfg.Cache["outer.html"] = []byte(`{{ include.title }} {% include card.html title="inner" %} {{ include.title }}`)
+	cfg.Cache["pages/sibling.html"] = []byte(`sibling {{ include.x }}`)
+
+	loc := parser.SourceLoc{Pathname: "pages/page.html", LineNo: 1}
+	bindings := map[string]any{
+		"page":    map[string]any{"title": "Page"},
+		"name":    "card.html",
+		"kind":    "card",
+		"sibling": "sibling.html",
+	}
+
+	tests := []struct{ in, expected string }{
+		{`{% include card.html %}`, `[Page|]`},
+		{`{% include "card.html" %}`, `[Page|]`},
+		{`{% include card.html title=page.title %}`, `[Page|Page]`},
+		{`{% include card.html title = "A \"quoted\" title" %}`, `[Page|A "quoted" title]`},
+		{`{% include card.html title: 'x', other=1 %}`, `[Page|x]`},
+		{`{% include {{ name }} title="x" %}`, `[Page|x]`},
+		{`{% include {{ kind }}.html title=page.title %}`, `[Page|Page]`},
+		{`{% include outer.html title="outer" %}`, `outer [Page|inner] outer`},
+		{`{% include_relative sibling.html x=1 %}`, `sibling 1`},
+		{`{% include_relative {{ sibling }} %}`, `sibling `},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, loc)
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			err = render.Render(root, buf, bindings, cfg)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestJekyllIncludeTag_errors(t *testing.T) {
+	cfg := render.NewConfig()
+	cfg.JekyllExtensions = true
+	AddStandardTags(&cfg)
+	loc := parser.SourceLoc{Pathname: "pages/page.html", LineNo: 1}
+
+	for _, test := range []struct{ in, message string }{
+		{`{% include %}`, "requires a file name"},
+		{`{% include "card.html %}`, "unclosed quote"},
+		{`{% include {{ name %}`, "unclosed {{"},
+		{`{% include card.html title %}`, "expected 'key=value'"},
+		{`{% include card.html title= %}`, "missing value"},
+		{`{% include card.html title="x %}`, "unclosed quote"},
+	} {
+		_, err := cfg.Compile(test.in, loc)
+		require.Errorf(t, err, test.in)
+		require.Containsf(t, err.Error(), test.message, test.in)
+	}
+
+	root, err := cfg.Compile(`{% include ../secret.html %}`, loc)
+	require.NoError(t, err)
+	err = render.Render(root, new(bytes.Buffer), map[string]any{}, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "escapes its source directory")
+
+	// Without Jekyll extensions, include takes an expression, and
+	// include_relative isn't available.
+	cfg = render.NewConfig()
+	AddStandardTags(&cfg)
+
+	_, err = cfg.Compile(`{% include card.html title=x %}`, loc)
+	require.Error(t, err)
+
+	_, err = cfg.Compile(`{% include_relative sibling.html %}`, loc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `undefined tag "include_relative"`)
+
+	// AddJekyllTags defines it, but it still requires Jekyll extensions.
+	AddJekyllTags(&cfg)
+
+	_, err = cfg.Compile(`{% include_relative sibling.html %}`, loc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "requires Jekyll extensions")
+}
+
+func TestJekyllIncludeTagAnalyzer(t *testing.T) {
+	cfg := render.NewConfig()
+	cfg.JekyllExtensions = true
+	AddStandardTags(&cfg)
+
+	root, err := cfg.Compile(`{% include card.html title=page.title %}{% include_relative {{ name }} %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	analysis := cfg.Analyze(root)
+	require.Equal(t, []string{"card.html"}, analysis.Partials)
+	require.Equal(t, []expressions.VariablePath{{"page", "title"}, {"name"}}, analysis.Globals)
+
+	// Without Jekyll extensions, the argument is an expression.
+	cfg = render.NewConfig()
+	AddStandardTags(&cfg)
+
+	root, err = cfg.Compile(`{% include card %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	analysis = cfg.Analyze(root)
+	require.Empty(t, analysis.Partials)
+	require.Equal(t, []expressions.VariablePath{{"card"}}, analysis.Globals)
+}
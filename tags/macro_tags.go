@@ -0,0 +1,32 @@
+package tags
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/osteele/liquid/ast"
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/render"
+)
+
+// AddMacroTags defines the macro block, which defines a macro, and the call
+// tag, which renders one. An expression can also call a macro, so
+// {% call price(product.price) %} and {{ price(product.price) }} are the same.
+func AddMacroTags(c *render.Config) {
+	c.AddMacroBlock("macro")
+	c.AddConfigTag("call", callTag)
+	c.AddTagAnalyzer("call", echoTagAnalyzer)
+}
+
+func callTag(cfg *render.Config, source string) (func(io.Writer, render.Context) error, error) {
+	expr, err := cfg.ParseExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := expressions.SyntaxTree(expr).(*ast.Call); !ok {
+		return nil, fmt.Errorf("call tag requires a macro call, e.g. price(amount); got %q", source)
+	}
+
+	return echoRenderer(expr), nil
+}
@@ -0,0 +1,186 @@
+package tags
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+	"github.com/osteele/liquid/render"
+)
+
+const priceMacro = `{% macro price(amount, currency='USD') %}{{ amount }} {{ currency }}{% endmacro %}`
+
+func TestMacroTags(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddMacroTags(&cfg)
+	cfg.AddFilter("minus", func(a, b int) int { return a - b })
+	cfg.AddFilter("upcase", func(s string) string { return string(bytes.ToUpper([]byte(s))) })
+	bindings := map[string]any{"p": map[string]any{"amount": 12}}
+
+	tests := []struct{ in, expected string }{
+		{priceMacro + `{% call price(10) %}`, `10 USD`},
+		{priceMacro + `{{ price(p.amount) }}`, `12 USD`},
+		{priceMacro + `{{ price(1, 'EUR') }}, {{ price(currency: 'GBP', amount: 2) }}`, `1 EUR, 2 GBP`},
+		{priceMacro + `{% assign s = price(3) %}[{{ s | upcase }}]`, `[3 USD]`},
+		{`{% macro greet %}hi{% endmacro %}{% call greet() %}`, `hi`},
+		{`{% macro pair(a, b=a) %}{{ a }}{{ b }}{% endmacro %}{{ pair(1) }}{{ pair(1, 2) }}`, `1112`},
+
+		// the body is isolated from the caller's variables
+		{`{% macro show(x) %}{{ x }}{{ p }}{% endmacro %}{% assign y = 1 %}{% call show(y) %}`, `1`},
+		{`{% macro set %}{% assign y = 2 %}{% endmacro %}{% assign y = 1 %}{% call set() %}{{ y }}`, `1`},
+
+		// macros can call themselves and each other
+		{`{% macro count(n) %}{{ n }}{% if n > 1 %}{% call count((n | minus: 1)) %}{% endif %}{% endmacro %}{% call count(3) %}`, `321`},
+		{priceMacro + `{% macro total(n) %}Total: {{ price(n) }}{% endmacro %}{% call total(4) %}`, `Total: 4 USD`},
+		{`{% macro total(n) %}Total: {{ price(n) }}{% endmacro %}` + priceMacro + `{% call total(4) %}`, `Total: 4 USD`},
+
+		// the definition renders nothing, and the macro can be called anywhere
+		// in the template
+		{`a{% macro m %}b{% endmacro %}c`, `ac`},
+		{`{{ price(5) }}` + priceMacro, `5 USD`},
+		{`{% if false %}{% macro m() %}x{% endmacro %}{% endif %}{{ m() }}`, `x`},
+		{`{% for i in (1..2) %}{% macro m() %}x{% endmacro %}{% endfor %}{{ m() }}`, `x`},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, parser.SourceLoc{LineNo: 1})
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			err = render.Render(root, buf, bindings, cfg)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestMacroTags_compileErrors(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddMacroTags(&cfg)
+
+	tests := []struct {
+		in, message string
+		line        int
+	}{
+		{"\n{{ price(1) }}", `undefined macro "price"`, 2},
+		{priceMacro + "\n{% if a %}" + priceMacro + "{% endif %}", `macro "price" is defined more than once`, 2},
+		{priceMacro + "\n{% call price() %}", `macro "price" is missing argument "amount"`, 2},
+		{priceMacro + "\n{% call price(1, 'EUR', 3) %}", `macro "price" takes 2 arguments; got 3`, 2},
+		{priceMacro + "\n{{ price(1, tax: 2) }}", `macro "price" has no parameter "tax"`, 2},
+		{priceMacro + "\n{{ price(1, amount: 2) }}", `more than one value for "amount"`, 2},
+		{priceMacro + "\n{% if price(1, x: 1) %}{% endif %}", `has no parameter "x"`, 2},
+		{"{% macro %}{% endmacro %}", "macro requires a name", 1},
+		{"{% macro f(a, a) %}{% endmacro %}", `duplicate parameter "a"`, 1},
+		{"{% macro f(a b) %}{% endmacro %}", `invalid parameter "a b"`, 1},
+		{"{% macro f(a %}{% endmacro %}", "expected )", 1},
+		{"{% call x %}", "call tag requires a macro call", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			_, err := cfg.Compile(test.in, parser.SourceLoc{Pathname: "page.html", LineNo: 1})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.message)
+			require.Equal(t, test.line, err.LineNumber())
+		})
+	}
+
+	// Without the macro tags, a call is a compile error.
+	standard := render.NewConfig()
+	AddStandardTags(&standard)
+	_, err := standard.Compile(`{% if true %}{% assign x = price(1) %}{% endif %}`, parser.SourceLoc{LineNo: 1})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `undefined macro "price": macros are not enabled`)
+
+	// In lax mode, a bad call is a warning, and an error when it is rendered.
+	cfg.ErrorMode = parser.LaxMode
+	root, warnings, err := cfg.CompileWithWarnings(`{{ price(1) }}`, parser.SourceLoc{LineNo: 1})
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+
+	cfg.ErrorMode = parser.StrictMode
+	err = render.Render(root, new(bytes.Buffer), map[string]any{}, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `undefined macro "price"`)
+}
+
+func TestMacroTags_renderErrors(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddMacroTags(&cfg)
+	loc := parser.SourceLoc{Pathname: "page.html", LineNo: 1}
+
+	// An error in a macro body is reported at its location in the body.
+	root, err := cfg.Compile("{% macro f(x) %}\n{{ x | undefined_filter }}{% endmacro %}\n{{ f(1) }}", loc)
+	require.NoError(t, err)
+	err = render.Render(root, new(bytes.Buffer), map[string]any{}, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "undefined_filter")
+	require.Equal(t, 2, err.LineNumber())
+
+	root, err = cfg.Compile(`{% macro f(n) %}{% call f(n) %}{% endmacro %}{% call f(1) %}`, loc)
+	require.NoError(t, err)
+	err = render.Render(root, new(bytes.Buffer), map[string]any{}, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "include depth")
+}
+
+func TestMacroTags_autoescape(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddMacroTags(&cfg)
+	cfg.SetAutoEscapeReplacer(render.HtmlEscaper)
+
+	root, err := cfg.Compile(`{% macro b(s) %}<b>{{ s }}</b>{% endmacro %}{{ b(x) }}`, parser.SourceLoc{LineNo: 1})
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	err = render.Render(root, buf, map[string]any{"x": "<&>"}, cfg)
+	require.NoError(t, err)
+	require.Equal(t, `<b>&lt;&amp;&gt;</b>`, buf.String())
+}
+
+func TestMacroTags_contextualAutoescape(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddMacroTags(&cfg)
+	cfg.SetAutoEscapeReplacer(render.HtmlContextEscaper)
+	bindings := map[string]any{"u": "javascript:alert(1)", "x": "<&>"}
+
+	tests := []struct{ in, expected string }{
+		{`{% macro b(s) %}<b>{{ s }}</b>{% endmacro %}{{ b(x) }}`, `<b>&lt;&amp;&gt;</b>`},
+		// The output of a macro is escaped for the position of the object
+		// that calls it, as the output of a variable is.
+		{`{% macro link(x) %}{{ x }}{% endmacro %}<a href="{{ link(u) }}">`, `<a href="#ZgotmplZ">`},
+		{`<a href="{{ u }}">`, `<a href="#ZgotmplZ">`},
+		{`{% macro b(s) %}<b>{{ s }}</b>{% endmacro %}<a title="{{ b(x) }}">`, `<a title="&lt;b&gt;&amp;lt;&amp;amp;&amp;gt;&lt;/b&gt;">`},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, parser.SourceLoc{LineNo: 1})
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			err = render.Render(root, buf, bindings, cfg)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestMacroTags_analyze(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(&cfg)
+	AddMacroTags(&cfg)
+
+	root, err := cfg.Compile(`{% macro f(a, b=c) %}{{ a }}{{ b }}{{ d }}{% endmacro %}{% call f(e) %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	analysis := cfg.Analyze(root)
+	require.Equal(t, []expressions.VariablePath{{"c"}, {"d"}, {"e"}}, analysis.Globals)
+	require.Equal(t, []expressions.VariablePath{{"a"}, {"b"}}, analysis.Locals)
+}
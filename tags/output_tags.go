@@ -0,0 +1,98 @@
+package tags
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/render"
+	"github.com/osteele/liquid/values"
+)
+
+// valueWriter is implemented by render contexts that write values as objects
+// do, applying the engine's escaping.
+type valueWriter interface {
+	WriteValue(io.Writer, any) error
+}
+
+// counterStore is implemented by render contexts that hold the increment and
+// decrement counters.
+type counterStore interface {
+	AddToCounter(name string, delta int) int
+}
+
+// echoTag implements {% echo expr %}, which renders the same output as {{ expr }}.
+// It is mostly used inside {% liquid %}, where objects are not available.
+func echoTag(cfg *render.Config, source string) (func(io.Writer, render.Context) error, error) {
+	expr, err := cfg.ParseExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return echoRenderer(expr), nil
+}
+
+// echoRenderer renders the value of expr as an object does.
+func echoRenderer(expr expressions.Expression) func(io.Writer, render.Context) error {
+	return func(w io.Writer, ctx render.Context) error {
+		value, err := ctx.Evaluate(expr)
+		if err != nil {
+			return err
+		}
+
+		if vw, ok := ctx.(valueWriter); ok {
+			return vw.WriteValue(w, value)
+		}
+
+		if value = values.ToLiquid(value); value != nil {
+			_, err = io.WriteString(w, fmt.Sprint(value))
+		}
+
+		return err
+	}
+}
+
+func echoTagAnalyzer(source string) render.NodeAnalysis {
+	expr, err := expressions.Parse(source)
+	if err != nil {
+		return render.NodeAnalysis{}
+	}
+
+	return render.NodeAnalysis{Arguments: []expressions.Expression{expr}}
+}
+
+var counterNameRe = regexp.MustCompile(`^[a-zA-Z_][\w-]*$`)
+
+// makeCounterTag returns the compiler for {% increment name %}, if delta is 1,
+// or {% decrement name %}, if delta is -1. The increment tag renders the
+// counter and then increments it; the decrement tag decrements the counter and
+// then renders it. Counters start at zero, and are separate from the
+// variables that assign and capture set; a counter is the value of a variable
+// only where no binding or assignment defines it.
+func makeCounterTag(delta int) render.TagCompiler {
+	return func(source string) (func(io.Writer, render.Context) error, error) {
+		name := strings.TrimSpace(source)
+		if !counterNameRe.MatchString(name) {
+			return nil, fmt.Errorf("syntax error: expected a counter name, got %q", source)
+		}
+
+		return func(w io.Writer, ctx render.Context) error {
+			counters, ok := ctx.(counterStore)
+			if !ok {
+				return ctx.Errorf("this render context doesn't support counters")
+			}
+
+			value := counters.AddToCounter(name, delta)
+			if delta > 0 {
+				value -= delta
+			}
+
+			_, err := io.WriteString(w, strconv.Itoa(value))
+
+			return err
+		}, nil
+	}
+}
@@ -37,8 +37,9 @@ type isolatedFileWriter interface {
 	RenderFileIsolatedTo(io.Writer, string, map[string]any) error
 }
 
-// parseRenderArgs parses the arguments of a render tag.
-func parseRenderArgs(source string) (*renderArgs, error) {
+// parseRenderArgs parses the arguments of a render tag. Its expressions are
+// parsed with table, which can be nil.
+func parseRenderArgs(table *expressions.ParseTable, source string) (*renderArgs, error) {
 	source = strings.TrimSpace(source)
 	if source == "" {
 		return nil, fmt.Errorf("render tag requires a template name")
@@ -49,7 +50,7 @@ func parseRenderArgs(source string) (*renderArgs, error) {
 		return nil, err
 	}
 
-	templateExpr, err := expressions.Parse(templateSource)
+	templateExpr, err := table.Parse(templateSource)
 	if err != nil {
 		return nil, fmt.Errorf("invalid template name: %w", err)
 	}
@@ -66,7 +67,7 @@ func parseRenderArgs(source string) (*renderArgs, error) {
 		if err != nil {
 			return nil, fmt.Errorf("invalid 'with' argument: %w", err)
 		}
-		args.withValue, err = expressions.Parse(valueSource)
+		args.withValue, err = table.Parse(valueSource)
 		if err != nil {
 			return nil, fmt.Errorf("invalid 'with' value: %w", err)
 		}
@@ -77,7 +78,7 @@ func parseRenderArgs(source string) (*renderArgs, error) {
 		if err != nil {
 			return nil, fmt.Errorf("invalid 'for' argument: %w", err)
 		}
-		args.forValue, err = expressions.Parse(valueSource)
+		args.forValue, err = table.Parse(valueSource)
 		if err != nil {
 			return nil, fmt.Errorf("invalid 'for' value: %w", err)
 		}
@@ -85,7 +86,7 @@ func parseRenderArgs(source string) (*renderArgs, error) {
 		rest = paramSource
 	}
 
-	args.params, err = parseRenderParams(rest)
+	args.params, err = parseRenderParams(table, rest)
 	if err != nil {
 		return nil, err
 	}
@@ -258,7 +259,7 @@ func hasRenderParamPrefix(source string) bool {
 	return strings.HasPrefix(strings.TrimSpace(source[end:]), ":")
 }
 
-func parseRenderParams(source string) ([]renderParam, error) {
+func parseRenderParams(table *expressions.ParseTable, source string) ([]renderParam, error) {
 	var params []renderParam
 	source = strings.TrimSpace(source)
 	for source != "" {
@@ -285,7 +286,7 @@ func parseRenderParams(source string) ([]renderParam, error) {
 			source = ""
 		}
 
-		value, err := expressions.Parse(valueSource)
+		value, err := table.Parse(valueSource)
 		if err != nil {
 			return nil, fmt.Errorf("invalid parameter value for '%s': %w", name, err)
 		}
@@ -342,8 +343,34 @@ func isEscaped(source string, index int) bool {
 	return backslashes%2 == 1
 }
 
-func renderTag(source string) (func(io.Writer, render.Context) error, error) {
-	args, err := parseRenderArgs(source)
+func renderTagAnalyzer(source string) render.NodeAnalysis {
+	args, err := parseRenderArgs(nil, source)
+	if err != nil {
+		return render.NodeAnalysis{}
+	}
+
+	var analysis render.NodeAnalysis
+	if name, ok := literalString(args.templateName); ok {
+		analysis.Partials = []string{name}
+	} else {
+		analysis.Arguments = append(analysis.Arguments, args.templateName)
+	}
+
+	for _, expr := range []expressions.Expression{args.withValue, args.forValue} {
+		if expr != nil {
+			analysis.Arguments = append(analysis.Arguments, expr)
+		}
+	}
+
+	for _, param := range args.params {
+		analysis.Arguments = append(analysis.Arguments, param.value)
+	}
+
+	return analysis
+}
+
+func renderTag(cfg *render.Config, source string) (func(io.Writer, render.Context) error, error) {
+	args, err := parseRenderArgs(cfg.ParseTable, source)
 	if err != nil {
 		return nil, err
 	}
@@ -449,7 +476,20 @@ func renderFor(
 		return ctx.Errorf("'for' parameter must be an array; got %T", collection)
 	}
 
+	gctx := goContext(ctx)
+	done := gctx.Done()
+
 	for i := 0; i < items.Len(); i++ {
+		select {
+		case <-done:
+			return ctx.WrapError(gctx.Err())
+		default:
+		}
+
+		if err := countIteration(ctx); err != nil {
+			return err
+		}
+
 		scope := maps.Clone(params)
 		scope[alias] = items.Index(i)
 		scope["forloop"] = map[string]any{
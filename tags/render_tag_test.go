@@ -475,7 +475,7 @@ func TestParseRenderArgs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			args, err := parseRenderArgs(tt.input)
+			args, err := parseRenderArgs(nil, tt.input)
 			if tt.shouldError {
 				require.Error(t, err)
 			} else {
@@ -7,61 +7,102 @@ import (
 	"io"
 	"strings"
 
+	"github.com/osteele/liquid/ast"
 	"github.com/osteele/liquid/expressions"
 	"github.com/osteele/liquid/render"
 )
 
-// AddStandardTags defines the standard Liquid tags.
+// AddStandardTags defines the standard Liquid tags, and the Jekyll tags if
+// the configuration has Jekyll extensions enabled.
 func AddStandardTags(c *render.Config) {
-	c.AddTag("assign", makeAssignTag(c))
-	c.AddTag("include", includeTag)
-	c.AddTag("render", renderTag)
+	c.AddConfigTag("assign", assignTag)
+	c.AddTag("decrement", makeCounterTag(-1))
+	c.AddConfigTag("echo", echoTag)
+	c.AddConfigTag("include", includeTag)
+	c.AddTag("increment", makeCounterTag(1))
+	c.AddConfigTag("render", renderTag)
+	c.AddStatementsTag("liquid")
 
 	// blocks
 	// The parser only recognize the comment and raw tags if they've been defined,
 	// but it ignores any syntax specified here.
 	c.AddTag("break", breakTag)
 	c.AddTag("continue", continueTag)
-	c.AddTag("cycle", cycleTag)
+	c.AddConfigTag("cycle", cycleTag)
 	c.AddBlock("capture").Compiler(captureTagCompiler)
-	c.AddBlock("case").Clause("when").Clause("else").Compiler(caseTagCompiler)
+	c.AddBlock("case").Clause("when").Clause("else").ConfigCompiler(caseTagCompiler)
 	c.AddBlock("comment")
-	c.AddBlock("for").Clause("else").Compiler(loopTagCompiler)
-	c.AddBlock("if").Clause("else").Clause("elsif").Compiler(ifTagCompiler(true))
+	c.AddBlock("for").Clause("else").ConfigCompiler(loopTagCompiler)
+	c.AddBlock("if").Clause("else").Clause("elsif").ConfigCompiler(ifTagCompiler(true))
 	c.AddBlock("raw")
-	c.AddBlock("tablerow").Compiler(loopTagCompiler)
-	c.AddBlock("unless").Clause("else").Compiler(ifTagCompiler(false))
+	c.AddBlock("tablerow").ConfigCompiler(loopTagCompiler)
+	c.AddBlock("unless").Clause("else").ConfigCompiler(ifTagCompiler(false))
+
+	c.AddTagAnalyzer("assign", assignTagAnalyzer)
+	c.AddTagAnalyzer("echo", echoTagAnalyzer)
+	c.AddConfigTagAnalyzer("include", includeTagAnalyzer)
+	c.AddTagAnalyzer("render", renderTagAnalyzer)
+	c.AddBlockAnalyzer("capture", captureTagAnalyzer)
+	c.AddBlockAnalyzer("case", caseTagAnalyzer)
+	c.AddBlockAnalyzer("for", loopTagAnalyzer)
+	c.AddBlockAnalyzer("if", ifTagAnalyzer)
+	c.AddBlockAnalyzer("tablerow", loopTagAnalyzer)
+	c.AddBlockAnalyzer("unless", ifTagAnalyzer)
+
+	if c.JekyllExtensions {
+		AddJekyllTags(c)
+	}
+}
+
+// AddJekyllTags defines the tags that Jekyll adds to Liquid: include_relative.
+// They require Jekyll extensions to be enabled.
+func AddJekyllTags(c *render.Config) {
+	c.AddConfigTag("include_relative", includeRelativeTag)
+	c.AddTagAnalyzer("include_relative", jekyllIncludeTagAnalyzer)
 }
 
-func makeAssignTag(cfg *render.Config) func(string) (func(io.Writer, render.Context) error, error) {
-	return func(source string) (func(io.Writer, render.Context) error, error) {
-		stmt, err := expressions.ParseStatement(expressions.AssignStatementSelector, source)
+func assignTag(cfg *render.Config, source string) (func(io.Writer, render.Context) error, error) {
+	stmt, err := cfg.ParseStatement(expressions.AssignStatementSelector, source)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if dot notation is used without Jekyll extensions enabled
+	if len(stmt.Path) > 1 && !cfg.JekyllExtensions {
+		return nil, errors.New("syntax error: dot notation in assign tag (e.g., 'obj.property = value') requires Jekyll extensions to be enabled")
+	}
+
+	return func(w io.Writer, ctx render.Context) error {
+		value, err := ctx.Evaluate(stmt.ValueFn)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		// Check if dot notation is used without Jekyll extensions enabled
-		if len(stmt.Path) > 1 && !cfg.JekyllExtensions {
-			return nil, errors.New("syntax error: dot notation in assign tag (e.g., 'obj.property = value') requires Jekyll extensions to be enabled")
+		// Use Path if available (dot notation), otherwise fall back to Variable (simple assignment)
+		if len(stmt.Path) > 1 {
+			return ctx.SetPath(stmt.Path, value)
 		}
 
-		return func(w io.Writer, ctx render.Context) error {
-			value, err := ctx.Evaluate(stmt.ValueFn)
-			if err != nil {
-				return err
-			}
+		// Simple assignment (backward compatibility and standard mode)
+		assign(ctx, stmt.Assignment.Variable, value)
 
-			// Use Path if available (dot notation), otherwise fall back to Variable (simple assignment)
-			if len(stmt.Path) > 1 {
-				return ctx.SetPath(stmt.Path, value)
-			}
+		return nil
+	}, nil
+}
 
-			// Simple assignment (backward compatibility and standard mode)
-			ctx.Set(stmt.Assignment.Variable, value)
+func assignTagAnalyzer(source string) render.NodeAnalysis {
+	stmt, err := expressions.ParseStatement(expressions.AssignStatementSelector, source)
+	if err != nil {
+		return render.NodeAnalysis{}
+	}
 
-			return nil
-		}, nil
+	analysis := render.NodeAnalysis{Arguments: []expressions.Expression{stmt.ValueFn}}
+	// An assignment to a property modifies a variable; it doesn't define one.
+	if len(stmt.Path) == 1 {
+		analysis.Assigns = stmt.Path
 	}
+
+	return analysis
 }
 
 func captureTagCompiler(node render.BlockNode) (func(io.Writer, render.Context) error, error) {
@@ -70,14 +111,51 @@ func captureTagCompiler(node render.BlockNode) (func(io.Writer, render.Context)
 		return nil, fmt.Errorf("syntax error: capture tag requires exactly one variable name, got %q", node.Args)
 	}
 
+	return captureBody(func(ctx render.Context, s string) error {
+		assign(ctx, varname, s)
+		return nil
+	}), nil
+}
+
+// assigner is implemented by render contexts that record the variables that
+// a render assigns, for its result.
+type assigner interface {
+	Assign(name string, value any)
+}
+
+// assign sets a variable, and records it if the context supports it.
+func assign(ctx render.Context, name string, value any) {
+	if a, ok := ctx.(assigner); ok {
+		a.Assign(name, value)
+		return
+	}
+
+	ctx.Set(name, value)
+}
+
+// captureBody returns a renderer that renders a block's body to a string,
+// instead of to the output, and passes it to store.
+func captureBody(store func(render.Context, string) error) func(io.Writer, render.Context) error {
 	return func(w io.Writer, ctx render.Context) error {
 		s, err := ctx.InnerString()
 		if err != nil {
 			return err
 		}
 
-		ctx.Set(varname, s)
+		return store(ctx, s)
+	}
+}
 
-		return nil
-	}, nil
+func captureTagAnalyzer(node render.BlockNode) render.NodeAnalysis {
+	return render.NodeAnalysis{Assigns: []string{strings.TrimSpace(node.Args)}}
+}
+
+// literalString returns the value of an expression that is a string literal.
+func literalString(expr expressions.Expression) (string, bool) {
+	if lit, ok := expressions.SyntaxTree(expr).(*ast.Literal); ok {
+		s, ok := lit.Value.(string)
+		return s, ok
+	}
+
+	return "", false
 }
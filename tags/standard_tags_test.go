@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/osteele/liquid/expressions"
 	"github.com/osteele/liquid/parser"
 	"github.com/osteele/liquid/render"
 )
@@ -16,6 +17,10 @@ var parseErrorTests = []struct{ in, expected string }{
 	{"{% undefined_tag %}", "undefined tag"},
 	{"{% assign v x y z %}", "syntax error"},
 	{"{% if syntax error %}", `unterminated "if" block`},
+	{"{% echo %}", "syntax error"},
+	{"{% increment a b %}", "counter name"},
+	{"{% liquid if x %}{% endif %}", `unterminated "if" block`},
+	{"{% liquid\n  assign a = 1\n  undefined_tag x\n%}", `undefined tag "undefined_tag" in undefined_tag x`},
 	// TODO once expression parsing is moved to template parse stage
 	// {"{% if syntax error %}{% endif %}", "syntax error"},
 	// {"{% for a in ar undefined %}{{ a }} {% endfor %}", "TODO"},
@@ -27,6 +32,24 @@ var tagTests = []struct{ in, expected string }{
 	{`{% assign av = obj.a %}{{ av }}`, "1"},
 	{`{% assign av = (1..5) %}{{ av }}`, "{1 5}"},
 	{`{% capture x %}captured{% endcapture %}{{ x }}`, "captured"},
+	{`{% echo obj.a %}`, "1"},
+	{`{% echo page.missing %}`, ""},
+
+	// counters
+	{`{% increment c %}{% increment c %}{% increment d %}`, "010"},
+	{`{% decrement c %}{% decrement c %}{% increment c %}`, "-1-2-2"},
+	{`{% assign c = 10 %}{% increment c %}{% increment c %}{{ c }}`, "0110"},
+	{`{% increment x %}{{ x }}`, "0123"},
+	{`{% increment c %}{{ c }}{% assign c = 10 %}{% increment c %}{{ c }}`, "01110"},
+	{`{% decrement c %}{{ c }}{% if c < 0 %}negative{% endif %}`, "-1-1negative"},
+
+	// liquid
+	{"{% liquid assign a = 1 %}{{ a }}", "1"},
+	{"{% liquid\n  # a comment\n  for a in animals limit: 2\n    if a == 'zebra'\n      echo 'Z'\n    else\n      echo a\n    endif\n  endfor\n%}", "Zoctopus"},
+	{"{% liquid\n  case x\n  when 123\n    echo 'x'\n  endcase\n  comment\n    echo 'no'\n  endcomment\n%}", "x"},
+	{"{% liquid capture c\n echo 'a'\n endcapture %}{{ c }}", "a"},
+	{"a {%- liquid echo 1 -%} b", "a1b"},
+	{"{% liquid %}", ""},
 
 	// issue #76: assign with boolean expressions using 'and'/'or' operators
 	{`{% assign result = x == 123 and obj.a == 1 %}{{ result }}`, "true"},
@@ -198,3 +221,53 @@ func TestAssignTag_JekyllExtensions(t *testing.T) {
 		}
 	})
 }
+
+var analyzeTests = []struct {
+	in                        string
+	globals, locals, partials []string
+}{
+	{`{% assign a = x.y | upcase %}{{ a }}`, []string{"x.y"}, []string{"a"}, nil},
+	{`{{ a }}{% assign a = 1 %}`, []string{"a"}, nil, nil},
+	{`{% assign page.title = t %}{{ page.title }}`, []string{"t", "page.title"}, nil, nil},
+	{`{% capture c %}{{ x }}{{ c }}{% endcapture %}{{ c }}`, []string{"x", "c"}, []string{"c"}, nil},
+	{`{% for p in products limit: n %}{{ p.title }}{{ forloop.index }}{% else %}{{ p }}{% endfor %}{{ p }}`,
+		[]string{"products", "n", "p"}, []string{"p.title", "forloop.index"}, nil},
+	{`{% tablerow p in products cols: n %}{{ p.title }}{% endtablerow %}`,
+		[]string{"products", "n"}, []string{"p.title"}, nil},
+	{`{% tablerow p in products %}{{ tablerowloop.col }}{{ forloop.index }}{% endtablerow %}`,
+		[]string{"products", "forloop.index"}, []string{"tablerowloop.col"}, nil},
+	{`{% if a %}{{ b }}{% elsif c.d %}{% else %}{{ e }}{% endif %}`, []string{"a", "c.d", "b", "e"}, nil, nil},
+	{`{% unless a %}{{ b }}{% endunless %}`, []string{"a", "b"}, nil, nil},
+	{`{% case a %}{% when b, c %}{{ d }}{% else %}{% endcase %}`, []string{"a", "b", "c", "d"}, nil, nil},
+	{`{% include "header.html" %}{% include {{ name }}.html %}`, []string{"name"}, nil, []string{"header.html"}},
+	{`{% render "card", product: p, n: 1 %}{% render "card" for items as item %}{% render name with x %}`,
+		[]string{"p", "items", "name", "x"}, nil, []string{"card"}},
+	{`{% echo a.b | default: c %}`, []string{"a.b", "c"}, nil, nil},
+	{"{% liquid\n assign a = x\n echo a\n%}{% increment n %}", []string{"x"}, []string{"a"}, nil},
+}
+
+func TestStandardTags_analyze(t *testing.T) {
+	config := render.NewConfig()
+	AddStandardTags(&config)
+	config.JekyllExtensions = true
+
+	paths := func(paths []expressions.VariablePath) (out []string) {
+		for _, path := range paths {
+			out = append(out, path.String())
+		}
+
+		return
+	}
+
+	for i, test := range analyzeTests {
+		t.Run(fmt.Sprintf("%02d", i+1), func(t *testing.T) {
+			root, err := config.Compile(test.in, parser.SourceLoc{})
+			require.NoErrorf(t, err, test.in)
+
+			analysis := config.Analyze(root)
+			require.Equalf(t, test.globals, paths(analysis.Globals), test.in)
+			require.Equalf(t, test.locals, paths(analysis.Locals), test.in)
+			require.Equalf(t, test.partials, analysis.Partials, test.in)
+		})
+	}
+}
@@ -2,8 +2,11 @@ package liquid
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"sync/atomic"
 
+	"github.com/osteele/liquid/ast"
 	"github.com/osteele/liquid/parser"
 	"github.com/osteele/liquid/render"
 )
@@ -12,19 +15,34 @@ import (
 //
 // Use Engine.ParseTemplate to create a template.
 type Template struct {
-	root render.Node
-	cfg  *render.Config
+	root     render.Node
+	cfg      *atomic.Pointer[render.Config] // the engine's current configuration
+	warnings []SourceError
 }
 
-func newTemplate(cfg *render.Config, source []byte, path string, line int) (*Template, SourceError) {
+func newTemplate(engineCfg *atomic.Pointer[render.Config], source []byte, path string, line int) (*Template, SourceError) {
 	loc := parser.SourceLoc{Pathname: path, LineNo: line}
+	cfg := engineCfg.Load()
 
-	root, err := cfg.Compile(string(source), loc)
+	root, warnings, err := cfg.CompileWithWarnings(string(source), loc)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Template{root, cfg}, nil
+	return newTemplateFromRoot(engineCfg, cfg, root, warnings), nil
+}
+
+// newTemplateFromRoot creates a template that renders with the engine's
+// current configuration. cfg is the configuration that compiled it.
+func newTemplateFromRoot(engineCfg *atomic.Pointer[render.Config], cfg *render.Config, root render.Node, warnings []parser.Error) *Template {
+	t := Template{root: root, cfg: engineCfg}
+	if cfg.ErrorMode == parser.WarnMode {
+		for _, w := range warnings {
+			t.warnings = append(t.warnings, w)
+		}
+	}
+
+	return &t
 }
 
 // GetRoot returns the root node of the abstract syntax tree (AST) representing
@@ -33,11 +51,104 @@ func (t *Template) GetRoot() render.Node {
 	return t.root
 }
 
+// AST returns the syntax tree of the template. The tree is a copy: modify it,
+// for example with ast.Rewrite, and then use Engine.CompileAST to create a
+// new template from it. The expressions of objects, and the arguments of the
+// standard tags whose arguments are expressions or statements, are trees;
+// the arguments of other tags are source text.
+func (t *Template) AST() ast.Node {
+	return render.ToAST(t.root)
+}
+
+// Walk traverses the syntax tree of the template with ast.Walk.
+func (t *Template) Walk(v ast.Visitor) {
+	ast.Walk(v, t.AST())
+}
+
+// Warnings returns the syntax errors that were recovered from while the
+// template was parsed. It is only populated in the Warn error mode.
+func (t *Template) Warnings() []SourceError {
+	return t.warnings
+}
+
+// Analyze reports the variables that the template reads, and the filters and
+// partials that it uses, without rendering it. Variables that the template
+// reads from its bindings are reported separately from those that it defines
+// with assign, capture, or a loop.
+//
+// Partials are not analyzed. Tags registered with RegisterTag and
+// RegisterBlock contribute only the contents of their bodies.
+func (t *Template) Analyze() Analysis {
+	return t.cfg.Load().Analyze(t.root)
+}
+
 // Render executes the template with the specified variable bindings.
 func (t *Template) Render(vars Bindings) ([]byte, SourceError) {
+	return t.RenderContext(context.Background(), vars)
+}
+
+// RenderContext is the same as Render, except that rendering stops when ctx
+// is canceled or its deadline passes. The returned error then wraps the
+// context's error, so that errors.Is(err, context.DeadlineExceeded) works.
+func (t *Template) RenderContext(ctx context.Context, vars Bindings) ([]byte, SourceError) {
+	buf := new(bytes.Buffer)
+
+	err := render.RenderContext(ctx, t.root, buf, vars, *t.cfg.Load())
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// A RenderResult is the output of a render, and the state that it leaves
+// behind.
+type RenderResult struct {
+	Output []byte
+	// Regions are the named regions that the template's content_for tags
+	// filled, for the host to place. It is nil if there are none.
+	Regions map[string]string
+	// Assigns are the top-level variables that the template's assign and
+	// capture tags set, with their final values. A variable whose final
+	// value equals its value in the bindings isn't included. It is nil if
+	// there are none.
+	Assigns Bindings
+}
+
+// RenderWithResult is the same as Render, and also returns the state that
+// the render leaves behind.
+func (t *Template) RenderWithResult(vars Bindings) (*RenderResult, SourceError) {
+	return t.RenderWithResultContext(context.Background(), vars)
+}
+
+// RenderWithResultContext is the same as RenderWithResult, except that
+// rendering stops when ctx is canceled or its deadline passes.
+func (t *Template) RenderWithResultContext(ctx context.Context, vars Bindings) (*RenderResult, SourceError) {
+	buf := new(bytes.Buffer)
+
+	result, err := render.RenderWithResult(ctx, t.root, buf, vars, *t.cfg.Load())
+	if err != nil {
+		return nil, err
+	}
+
+	return &RenderResult{Output: buf.Bytes(), Regions: result.Regions, Assigns: result.Assigns}, nil
+}
+
+// RenderValue executes the template with the properties of scope as its
+// variables. Scope is a map, a struct or struct pointer, whose fields and
+// methods are looked up by their liquid tags or names as they are for
+// bindings, or a drop whose value is one of these. Variables that the
+// template assigns overlay the scope, and don't modify it.
+func (t *Template) RenderValue(scope any) ([]byte, SourceError) {
+	return t.RenderValueContext(context.Background(), scope)
+}
+
+// RenderValueContext is the same as RenderValue, except that rendering stops
+// when ctx is canceled or its deadline passes.
+func (t *Template) RenderValueContext(ctx context.Context, scope any) ([]byte, SourceError) {
 	buf := new(bytes.Buffer)
 
-	err := render.Render(t.root, buf, vars, *t.cfg)
+	_, err := render.RenderValue(ctx, t.root, buf, scope, *t.cfg.Load())
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +158,13 @@ func (t *Template) Render(vars Bindings) ([]byte, SourceError) {
 
 // FRender executes the template with the specified variable bindings and renders it into w.
 func (t *Template) FRender(w io.Writer, vars Bindings) SourceError {
-	err := render.Render(t.root, w, vars, *t.cfg)
+	return t.FRenderContext(context.Background(), w, vars)
+}
+
+// FRenderContext is the same as FRender, except that rendering stops when ctx
+// is canceled or its deadline passes.
+func (t *Template) FRenderContext(ctx context.Context, w io.Writer, vars Bindings) SourceError {
+	err := render.RenderContext(ctx, t.root, w, vars, *t.cfg.Load())
 	if err != nil {
 		return err
 	}
@@ -1,14 +1,19 @@
 package liquid
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/osteele/liquid/ast"
 	"github.com/osteele/liquid/render"
 )
 
@@ -27,6 +32,192 @@ func TestTemplate_RenderString(t *testing.T) {
 	require.Equal(t, "Hello world", out)
 }
 
+func TestTemplate_Analyze(t *testing.T) {
+	engine := NewEngine()
+	tpl, err := engine.ParseString(`{% for v in product.variants %}{{ v.title | upcase }}{% endfor %}` +
+		`{% assign name = customer.name %}{{ name }}{% render "footer" %}`)
+	require.NoError(t, err)
+
+	analysis := tpl.Analyze()
+	require.Equal(t, []VariablePath{{"product", "variants"}, {"customer", "name"}}, analysis.Globals)
+	require.Equal(t, []VariablePath{{"v", "title"}, {"name"}}, analysis.Locals)
+	require.Equal(t, []string{"upcase"}, analysis.Filters)
+	require.Equal(t, []string{"footer"}, analysis.Partials)
+}
+
+func TestTemplate_AST(t *testing.T) {
+	engine := NewEngine()
+	tpl, err := engine.ParseString(`{% for p in products %}{{ p.title | upcase }}{% endfor %}`)
+	require.NoError(t, err)
+
+	var filters []string
+	tpl.Walk(inspector(func(n ast.Node) {
+		if f, ok := n.(*ast.Filter); ok {
+			filters = append(filters, f.Name)
+		}
+	}))
+	require.Equal(t, []string{"upcase"}, filters)
+
+	root := ast.Rewrite(tpl.AST(), func(n ast.Node) ast.Node {
+		if f, ok := n.(*ast.Filter); ok && f.Name == "upcase" {
+			f.Name = "downcase"
+		}
+
+		return n
+	})
+	require.Equal(t, `{% for p in products %}{{ p.title | downcase }}{% endfor %}`, root.String())
+
+	rewritten, err := engine.CompileAST(root)
+	require.NoError(t, err)
+
+	out, err := rewritten.RenderString(Bindings{"products": []map[string]any{{"title": "A"}}})
+	require.NoError(t, err)
+	require.Equal(t, "a", out)
+
+	// the original template is unchanged
+	out, err = tpl.RenderString(Bindings{"products": []map[string]any{{"title": "a"}}})
+	require.NoError(t, err)
+	require.Equal(t, "A", out)
+
+	// rewriting a tree doesn't change the template's later trees or analysis
+	require.Equal(t, `{% for p in products %}{{ p.title | upcase }}{% endfor %}`, tpl.AST().String())
+	require.Equal(t, []string{"upcase"}, tpl.Analyze().Filters)
+
+	_, err = engine.CompileAST(&ast.Tag{Name: "undefined_tag"})
+	require.Error(t, err)
+}
+
+func TestTemplate_AST_tagArgs(t *testing.T) {
+	engine := NewEngine()
+	tpl, err := engine.ParseString(`{% assign y = x | plus: 1 %}` +
+		`{% if x > 1 %}{% for i in (1..x) reversed limit: x %}{{ i }}{% cycle 'a', 'b' %}{% endfor %}{% endif %}` +
+		`{% case x %}{% when 1, x %}w{% endcase %}{{ y }}`)
+	require.NoError(t, err)
+
+	root := ast.Rewrite(tpl.AST(), func(n ast.Node) ast.Node {
+		switch n := n.(type) {
+		case *ast.Variable:
+			if n.Name == "x" {
+				n.Name = "n"
+			}
+		case *ast.CycleStmt:
+			n.Values = []string{"c"}
+		}
+
+		return n
+	})
+	require.Equal(t, `{% assign y = n | plus: 1 %}`+
+		`{% if n > 1 %}{% for i in (1..n) reversed limit: n %}{{ i }}{% cycle "c" %}{% endfor %}{% endif %}`+
+		`{% case n %}{% when 1, n %}w{% endcase %}{{ y }}`, root.String())
+
+	rewritten, err := engine.CompileAST(root)
+	require.NoError(t, err)
+
+	out, err := rewritten.RenderString(Bindings{"n": 3, "x": 0})
+	require.NoError(t, err)
+	require.Equal(t, "3c2c1cw4", out)
+}
+
+type inspector func(ast.Node)
+
+func (f inspector) Visit(n ast.Node) ast.Visitor {
+	f(n)
+	return f
+}
+
+func TestTemplate_RenderContext(t *testing.T) {
+	engine := NewEngine()
+	tpl, err := engine.ParseString(`{% for i in (1..100000000) %}{% endfor %}`)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = tpl.RenderContext(ctx, emptyBindings)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	buf := new(bytes.Buffer)
+	err = tpl.FRenderContext(ctx, buf, emptyBindings)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Empty(t, buf.String())
+
+	// tags can read the context
+	type key struct{}
+	engine.RegisterTag("ctxvalue", func(c render.Context) (string, error) {
+		return c.(render.GoContextProvider).Context().Value(key{}).(string), nil
+	})
+	tpl, err = engine.ParseString(`{% ctxvalue %}`)
+	require.NoError(t, err)
+	out, err := tpl.RenderContext(context.WithValue(context.Background(), key{}, "value"), emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "value", string(out))
+}
+
+func TestTemplate_RenderWithResult(t *testing.T) {
+	engine := NewEngine()
+	engine.EnableTemplateInheritance()
+
+	tpl, err := engine.ParseString(`{% content_for "head" %}<meta name="{{ x }}">{% endcontent_for %}body`)
+	require.NoError(t, err)
+
+	result, err := tpl.RenderWithResult(Bindings{"x": "a"})
+	require.NoError(t, err)
+	require.Equal(t, "body", string(result.Output))
+	require.Equal(t, map[string]string{"head": `<meta name="a">`}, result.Regions)
+
+	require.Nil(t, result.Assigns)
+
+	tpl, err = engine.ParseString(`{% assign subject = "Hello " | append: name %}{% capture preheader %}Hi{% endcapture %}{% assign name = name %}{% for x in list %}{% endfor %}`)
+	require.NoError(t, err)
+
+	result, err = tpl.RenderWithResult(Bindings{"name": "Ann", "list": []int{1}})
+	require.NoError(t, err)
+	require.Equal(t, Bindings{"subject": "Hello Ann", "preheader": "Hi"}, result.Assigns)
+	require.Nil(t, result.Regions)
+
+	// A variable that is set to the value that it has in the bindings isn't
+	// reported.
+	result, err = tpl.RenderWithResult(Bindings{"name": "Ann", "subject": "Hello Ann", "preheader": "Hi"})
+	require.NoError(t, err)
+	require.Nil(t, result.Assigns)
+
+	tpl, err = engine.ParseString(`{% content_for "head" %}{{ x | nofilter }}{% endcontent_for %}`)
+	require.NoError(t, err)
+	_, err = tpl.RenderWithResult(Bindings{})
+	require.Error(t, err)
+}
+
+func TestTemplate_RenderValue(t *testing.T) {
+	type author struct {
+		Name string `liquid:"name"`
+	}
+
+	type page struct {
+		Title  string  `liquid:"title"`
+		Author *author `liquid:"author"`
+	}
+
+	engine := NewEngine()
+	tpl, err := engine.ParseString(`{% assign title = title | upcase %}{{ title }} by {{ author.name }}`)
+	require.NoError(t, err)
+
+	model := page{Title: "Home", Author: &author{Name: "Ann"}}
+	out, err := tpl.RenderValue(model)
+	require.NoError(t, err)
+	require.Equal(t, "HOME by Ann", string(out))
+	require.Equal(t, "Home", model.Title)
+
+	out, err = tpl.RenderValue(Bindings{"title": "map", "author": Bindings{"name": "Bo"}})
+	require.NoError(t, err)
+	require.Equal(t, "MAP by Bo", string(out))
+
+	_, err = tpl.RenderValue(3)
+	require.Error(t, err)
+}
+
 func TestTemplate_SetSourcePath(t *testing.T) {
 	engine := NewEngine()
 	engine.RegisterTag("sourcepath", func(c render.Context) (string, error) {
@@ -146,6 +337,27 @@ func BenchmarkTemplate_RenderStructProperty(b *testing.B) {
 	}
 }
 
+func BenchmarkTemplate_RenderExpression(b *testing.B) {
+	engine := NewEngine()
+	engine.RegisterFilter("money", func(cents int) string { return fmt.Sprintf("$%d.%02d", cents/100, cents%100) })
+	tpl, err := engine.ParseString(`{% for product in products %}{{ product.variants[0].price | money }}{% endfor %}`)
+	require.NoError(b, err)
+
+	products := make([]map[string]any, 1000)
+	for i := range products {
+		products[i] = map[string]any{"variants": []map[string]any{{"price": 1000 + i}}}
+	}
+
+	bindings := Bindings{"products": products}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		_, err := tpl.Render(bindings)
+		require.NoError(b, err)
+	}
+}
+
 type benchmarkTemplateStore struct {
 	source []byte
 }
@@ -168,3 +380,15 @@ func BenchmarkTemplate_RenderIncludes(b *testing.B) {
 		require.NoError(b, err)
 	}
 }
+
+func BenchmarkTemplate_RenderLoopControl(b *testing.B) {
+	tpl, err := NewEngine().ParseString(`{% for i in (1..1000) %}{% if i > 1 %}{% continue %}{% endif %}{{ i }}{% endfor %}`)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		_, err := tpl.Render(emptyBindings)
+		require.NoError(b, err)
+	}
+}
@@ -137,6 +137,23 @@ func IsUndefined(value Value) bool {
 	return ok
 }
 
+// Property returns the named property of a map or struct value, and whether
+// the value has that property. A struct's properties are its exported fields
+// and methods, named by their liquid tags if they have them.
+func Property(value Value, name string) (any, bool) {
+	switch value.(type) {
+	case mapValue, structValue:
+		key := ValueOf(name)
+		if !value.Contains(key) {
+			return nil, false
+		}
+
+		return value.PropertyValue(key).Interface(), true
+	default:
+		return nil, false
+	}
+}
+
 // container values
 type (
 	arrayValue  struct{ wrapperValue }
@@ -216,6 +233,10 @@ func (mv mapValue) Contains(iv Value) bool {
 }
 
 func (mv mapValue) IndexValue(iv Value) Value {
+	if v, ok := mv.lookupString(iv); ok {
+		return v
+	}
+
 	mr := reflect.ValueOf(mv.value)
 	ir := reflect.ValueOf(iv.Interface())
 
@@ -231,6 +252,14 @@ func (mv mapValue) IndexValue(iv Value) Value {
 }
 
 func (mv mapValue) PropertyValue(iv Value) Value {
+	if v, ok := mv.lookupString(iv); ok {
+		if IsUndefined(v) && iv.Interface() == sizeKey {
+			return ValueOf(len(mv.value.(map[string]any)))
+		}
+
+		return v
+	}
+
 	mr := reflect.ValueOf(mv.Interface())
 
 	ir := reflect.ValueOf(iv.Interface())
@@ -253,6 +282,27 @@ func (mv mapValue) PropertyValue(iv Value) Value {
 	return undefinedValue
 }
 
+// lookupString looks up a string key in a map[string]any without
+// reflection, since that is the map type of most bindings. It returns false
+// for other maps and keys.
+func (mv mapValue) lookupString(iv Value) (Value, bool) {
+	m, ok := mv.value.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	key, ok := iv.Interface().(string)
+	if !ok {
+		return nil, false
+	}
+
+	if v, ok := m[key]; ok {
+		return ValueOf(v), true
+	}
+
+	return undefinedValue, true
+}
+
 func (sv stringValue) Contains(substr Value) bool {
 	s, ok := substr.Interface().(string)
 	if !ok {
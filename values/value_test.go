@@ -162,6 +162,34 @@ func TestValue_Contains(t *testing.T) {
 	require.False(t, msv.Contains(ValueOf(nil)))
 }
 
+func TestProperty(t *testing.T) {
+	type model struct {
+		Name  string `liquid:"name"`
+		Count int
+	}
+
+	tests := []struct {
+		value    any
+		name     string
+		expected any
+		found    bool
+	}{
+		{map[string]any{"a": 1}, "a", 1, true},
+		{map[string]any{"a": nil}, "a", nil, true},
+		{map[string]any{"a": 1}, "b", nil, false},
+		{model{Name: "x", Count: 2}, "name", "x", true},
+		{&model{Count: 2}, "Count", 2, true},
+		{model{}, "Name", nil, false},
+		{"string", "size", nil, false},
+		{[]int{1}, "first", nil, false},
+	}
+	for i, test := range tests {
+		value, found := Property(ValueOf(test.value), test.name)
+		require.Equalf(t, test.found, found, "%d", i)
+		require.Equalf(t, test.expected, value, "%d", i)
+	}
+}
+
 func TestNilValue(t *testing.T) {
 	nv := ValueOf(nil)
 